@@ -0,0 +1,43 @@
+//go:build !race
+
+package bloomfilter
+
+import (
+	"testing"
+
+	. "gopkg.in/check.v1"
+)
+
+// sync.Pool drops items randomly under race detector, so this test is skipped there.
+func (s *filterTestSuite) TestZeroAllocs(c *C) {
+
+	keys := benchKeys()
+
+	for _, opts := range [][]Option{
+		{},
+		{WithErrorRate(0.0000001)},
+		{WithHashStrategy(HashFNV1a64), WithIndexMode(IndexDoubleHashing)},
+		{WithHashStrategy(HashMurmur3), WithIndexMode(IndexDoubleHashing)},
+		{WithHashStrategy(HashXXHash64), WithSeed(7)},
+		{WithConcurrency(ConcurrencyAtomic)},
+	} {
+		filter, err := NewWithOptions(100000, opts...)
+		c.Assert(err, IsNil)
+
+		i := 0
+		allocs := testing.AllocsPerRun(1000, func() {
+			filter.Add(keys[i%len(keys)])
+			filter.Check(keys[i%len(keys)])
+			filter.AddUint64(uint64(i))
+			filter.CheckString("key")
+
+			// key on stack does not escape
+			var key [8]byte
+			key[0] = byte(i)
+			filter.Add(key[:])
+			filter.Check(key[:])
+			i++
+		})
+		c.Assert(allocs, Equals, float64(0))
+	}
+}
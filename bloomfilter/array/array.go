@@ -21,9 +21,15 @@ func init() {
 
 // Array is main structure
 type Array struct {
-	mc sync.RWMutex
-
-	bArray      []byte `json:"array"`
+	mc          sync.RWMutex
+	unlocked    bool
+	mapping     *Mapping
+	sparse      *sparseBits
+	stripes     []stripe
+	stripeBytes int
+	cow         *cowState
+
+	bArray      []byte
 	Length      uint64 `json:"length"`
 	SizeOneByte uint64
 }
@@ -45,14 +51,68 @@ func New(length uint64) *Array {
 	return out
 }
 
-// Set adds new point to array
+// NewUnlocked is constructor for array without locking. It must be used from one goroutine only.
+func NewUnlocked(length uint64) *Array {
+	out := New(length)
+	out.unlocked = true
+	return out
+}
+
+// SetUnlocked disables locking. Array must be used from one goroutine only then.
+func (b *Array) SetUnlocked() {
+	b.unlocked = true
+}
+
+func (b *Array) lock() {
+	if b.stripes != nil {
+		b.lockStripes()
+	} else if !b.unlocked {
+		b.mc.Lock()
+	}
+}
+
+func (b *Array) unlock() {
+	if b.stripes != nil {
+		b.unlockStripes()
+	} else if !b.unlocked {
+		b.mc.Unlock()
+	}
+}
+
+func (b *Array) rLock() {
+	if b.stripes != nil {
+		b.rLockStripes()
+	} else if !b.unlocked {
+		b.mc.RLock()
+	}
+}
+
+func (b *Array) rUnlock() {
+	if b.stripes != nil {
+		b.rUnlockStripes()
+	} else if !b.unlocked {
+		b.mc.RUnlock()
+	}
+}
+
+// Set adds new point to array. It panics for array in read-only mapping.
 func (b *Array) Set(i uint64) {
 	j := int(i / sizeOneByte)
 	k := uint8(1 << (i % sizeOneByte))
 
-	b.mc.Lock()
-	defer b.mc.Unlock()
+	if b.ReadOnly() {
+		panic("array: Set of read-only mapped array")
+	}
+
+	b.lockBit(j)
+	defer b.unlockBit(j)
 
+	if b.sparse != nil {
+		b.setSparse(i)
+		return
+	}
+
+	b.preserve(j)
 	b.bArray[j] = b.bArray[j] | k
 }
 
@@ -61,17 +121,64 @@ func (b *Array) Get(i uint64) bool {
 	j := int(i / sizeOneByte)
 	k := uint8(1 << (i % sizeOneByte))
 
-	b.mc.RLock()
-	defer b.mc.RUnlock()
+	b.rLockBit(j)
+	defer b.rUnlockBit(j)
+
+	if b.sparse != nil {
+		return b.sparse.get(i)
+	}
 
 	return b.bArray[j]&k != 0
 }
 
+// Reset clears all bits. Memory of array is reused.
+// It panics for array in read-only mapping.
+func (b *Array) Reset() {
+
+	if b.ReadOnly() {
+		panic("array: Reset of read-only mapped array")
+	}
+
+	b.lock()
+	defer b.unlock()
+
+	b.preserveAll()
+
+	if b.sparse != nil {
+		b.sparse = newSparseBits()
+		return
+	}
+
+	for i := range b.bArray {
+		b.bArray[i] = 0
+	}
+}
+
 // Merge adds values from outside array into current
 func (b *Array) Merge(a *Array) error {
 
-	b.mc.Lock()
-	defer b.mc.Unlock()
+	if b.ReadOnly() {
+		return fmt.Errorf("array is read-only")
+	}
+
+	defer b.lockWith(a)()
+
+	b.preserveAll()
+
+	if a.sparse != nil {
+		a.sparse.forEach(func(i uint64) {
+			if b.sparse != nil {
+				b.setSparse(i)
+			} else {
+				b.bArray[i/sizeOneByte] |= 1 << (i % sizeOneByte)
+			}
+		})
+		return nil
+	}
+
+	if b.sparse != nil {
+		b.densify()
+	}
 
 	for i := range a.bArray {
 		b.bArray[i] |= a.bArray[i]
@@ -80,6 +187,105 @@ func (b *Array) Merge(a *Array) error {
 	return nil
 }
 
+// MergeBytes adds values from bytes in format of ToBytes
+func (b *Array) MergeBytes(data []byte) error {
+
+	if b.ReadOnly() {
+		return fmt.Errorf("array is read-only")
+	}
+
+	b.lock()
+	defer b.unlock()
+
+	b.preserveAll()
+
+	if b.sparse != nil {
+		b.densify()
+	}
+
+	for i := 0; i < len(data) && i < len(b.bArray); i++ {
+		b.bArray[i] |= data[i]
+	}
+
+	return nil
+}
+
+// AndBytes keeps only values which are found in bytes in format of ToBytes too. Returns number of set bits.
+func (b *Array) AndBytes(data []byte) uint64 {
+
+	b.lock()
+	defer b.unlock()
+
+	b.preserveAll()
+
+	if b.sparse != nil {
+		return b.andSparse(b.sparse, func(i uint64) bool {
+			j := i / sizeOneByte
+			return j < uint64(len(data)) && data[j]&(1<<(i%sizeOneByte)) != 0
+		})
+	}
+
+	for i := range b.bArray {
+		if i < len(data) {
+			b.bArray[i] &= data[i]
+		} else {
+			b.bArray[i] = 0
+		}
+	}
+
+	return popCount(b.bArray)
+}
+
+// And keeps only values which are found in outside array too. Returns number of set bits.
+func (b *Array) And(a *Array) uint64 {
+
+	defer b.lockWith(a)()
+
+	b.preserveAll()
+
+	// result has only bits of sparse array, so it stays sparse
+	if b.sparse != nil {
+		return b.andSparse(b.sparse, a.getUnlocked)
+	}
+	if a.sparse != nil {
+		return b.andSparse(a.sparse, b.getUnlocked)
+	}
+
+	for i := range a.bArray {
+		b.bArray[i] &= a.bArray[i]
+	}
+
+	return popCount(b.bArray)
+}
+
+// Clone returns independent copy of array
+func (b *Array) Clone() *Array {
+
+	b.rLock()
+	defer b.rUnlock()
+
+	out := &Array{
+		unlocked:    b.unlocked,
+		Length:      b.Length,
+		SizeOneByte: b.SizeOneByte,
+	}
+
+	if b.stripes != nil {
+		out.stripes = make([]stripe, len(b.stripes), len(b.stripes))
+		out.stripeBytes = b.stripeBytes
+	}
+
+	if b.sparse != nil {
+		out.sparse = b.sparse.clone()
+		return out
+	}
+
+	out.bArray = make([]byte, len(b.bArray), len(b.bArray))
+	copy(out.bArray, b.bArray)
+
+	return out
+}
+
 // Compare checks characteristics of arrays
 func (b *Array) Compare(a *Array) error {
 	if a.SizeOneByte != b.SizeOneByte {
@@ -99,25 +305,66 @@ func (b *Array) Compare(a *Array) error {
 
 // ToBytes save internal byte array to buffer
 func (b *Array) ToBytes(binBuf *bytes.Buffer) error {
-	b.mc.RLock()
-	defer b.mc.RUnlock()
+	_, err := b.WriteTo(binBuf)
+	return err
+}
 
-	_, err := binBuf.Write(b.bArray)
+// WriteTo writes bytes of ToBytes to w. Sparse array is written by small chunks.
+func (b *Array) WriteTo(w io.Writer) (int64, error) {
+	b.rLock()
+	defer b.rUnlock()
 
-	return err
+	if b.sparse != nil {
+		cw := &countingWriter{w: w}
+		err := b.sparse.toBytes(cw, int((b.Length+sizeOneByte-1)/sizeOneByte))
+		return cw.n, err
+	}
+
+	n, err := w.Write(b.bArray)
+
+	return int64(n), err
+}
+
+// countingWriter counts written bytes
+type countingWriter struct {
+	w io.Writer
+	n int64
+}
+
+func (cw *countingWriter) Write(p []byte) (int, error) {
+	n, err := cw.w.Write(p)
+	cw.n += int64(n)
+	return n, err
 }
 
 // Read read internal byte array from buffer
 func (b *Array) Read(reader *bufio.Reader, length int64) error {
 
-	b.mc.Lock()
-	defer b.mc.Unlock()
+	b.lock()
+	defer b.unlock()
+
+	b.preserveAll()
+
+	data, err := readBytes(reader, length, b.Length)
+	b.bArray = data
+	b.sparse = nil
+
+	return err
+}
+
+// Len is a "getter". Returns length of array in bits.
+func (b *Array) Len() uint64 {
+	return b.Length
+}
+
+// readBytes reads length bytes of array or bytes for bitLength bits if length is 0.
+func readBytes(reader *bufio.Reader, length int64, bitLength uint64) ([]byte, error) {
 
 	readByLength := false
 	if length == 0 {
 		readByLength = true
-		length = int64(b.Length / sizeOneByte)
-		if b.Length*sizeOneByte > uint64(length) {
+		length = int64(bitLength / sizeOneByte)
+		if bitLength*sizeOneByte > uint64(length) {
 			length++
 		}
 	}
@@ -126,7 +373,7 @@ func (b *Array) Read(reader *bufio.Reader, length int64) error {
 	array := make([]byte, maxLength, maxLength)
 
 	var err error
-	b.bArray = []byte{}
+	out := []byte{}
 
 	var n int
 	total := 0
@@ -138,14 +385,14 @@ func (b *Array) Read(reader *bufio.Reader, length int64) error {
 
 		n, err = reader.Read(array)
 		if err != nil && err != io.EOF {
-			return err
+			return out, err
 		}
 
 		if n == 0 {
-			return nil
+			return out, nil
 		}
 
-		b.bArray = append(b.bArray, array[:n]...)
+		out = append(out, array[:n]...)
 		if err == io.EOF {
 			break
 		}
@@ -156,5 +403,5 @@ func (b *Array) Read(reader *bufio.Reader, length int64) error {
 		}
 	}
 
-	return nil
+	return out, nil
 }
@@ -0,0 +1,525 @@
+package array
+
+import (
+	"bufio"
+	"bytes"
+	"io"
+	"sync"
+	"testing"
+
+	. "gopkg.in/check.v1"
+)
+
+type arrayTestSuite struct{}
+
+// Hook up gocheck into the "go test" runner.
+func Test(t *testing.T) { TestingT(t) }
+
+var _ = Suite(&arrayTestSuite{})
+
+func (s *arrayTestSuite) TestPopCount(c *C) {
+
+	a := New(1001)
+	c.Assert(a.PopCount(), Equals, uint64(0))
+
+	set := map[uint64]bool{}
+	for i := uint64(0); i < 1001; i += 3 {
+		a.Set(i)
+		set[i] = true
+	}
+	a.Set(1000)
+	set[1000] = true
+
+	c.Assert(a.PopCount(), Equals, uint64(len(set)))
+
+	for _, r := range [][2]uint64{{0, 1001}, {0, 1}, {1, 2}, {3, 4}, {5, 13}, {7, 200}, {64, 128}, {999, 1001}, {10, 10}, {20, 5000}} {
+		expected := uint64(0)
+		for i := r[0]; i < r[1] && i < 1001; i++ {
+			if set[i] {
+				expected++
+			}
+		}
+		c.Assert(a.PopCountRange(r[0], r[1]), Equals, expected, Commentf("range %v", r))
+	}
+}
+
+func (s *arrayTestSuite) TestPairPopCountRange(c *C) {
+
+	const length = 3001
+	ranges := [][2]uint64{{0, length}, {0, 1}, {3, 4}, {5, 13}, {7, 200}, {64, 1030}, {2999, length}, {10, 10}, {20, 5000}}
+
+	for _, sparse := range [][2]bool{{false, false}, {true, false}, {false, true}, {true, true}} {
+		x, y := New(length), New(length)
+		if sparse[0] {
+			x = NewSparse(length)
+		}
+		if sparse[1] {
+			y = NewSparse(length)
+		}
+		ax, ay := NewAtomic(length), NewAtomic(length)
+
+		for i := uint64(0); i < length; i += 37 {
+			x.Set(i)
+			ax.Set(i)
+		}
+		for i := uint64(0); i < length; i += 41 {
+			y.Set(i)
+			ay.Set(i)
+		}
+
+		for _, r := range ranges {
+			expected, expectedXor := uint64(0), uint64(0)
+			for i := r[0]; i < r[1] && i < length; i++ {
+				if i%37 == 0 || i%41 == 0 {
+					expected++
+				}
+				if (i%37 == 0) != (i%41 == 0) {
+					expectedXor++
+				}
+			}
+			comment := Commentf("sparse %v, range %v", sparse, r)
+			c.Assert(x.OrPopCountRange(y, r[0], r[1]), Equals, expected, comment)
+			c.Assert(y.OrPopCountRange(x, r[0], r[1]), Equals, expected, comment)
+			c.Assert(ax.OrPopCountRange(ay, r[0], r[1]), Equals, expected, comment)
+			c.Assert(x.XorPopCountRange(y, r[0], r[1]), Equals, expectedXor, comment)
+			c.Assert(y.XorPopCountRange(x, r[0], r[1]), Equals, expectedXor, comment)
+			c.Assert(ax.XorPopCountRange(ay, r[0], r[1]), Equals, expectedXor, comment)
+		}
+
+		// arrays are not changed, sparse one stays sparse
+		c.Assert(x.PopCount(), Equals, uint64((length+36)/37))
+		c.Assert(y.PopCount(), Equals, uint64((length+40)/41))
+		c.Assert(x.Sparse(), Equals, sparse[0])
+	}
+}
+
+func (s *arrayTestSuite) TestCloneAnd(c *C) {
+
+	a := New(100)
+	b := NewUnlocked(100)
+	for i := uint64(0); i < 100; i += 2 {
+		a.Set(i)
+	}
+	for i := uint64(0); i < 100; i += 3 {
+		b.Set(i)
+	}
+
+	clone := b.Clone()
+	c.Assert(clone.unlocked, Equals, true)
+	c.Assert(clone.PopCount(), Equals, b.PopCount())
+
+	clone.Set(1)
+	c.Assert(b.Get(1), Equals, false)
+
+	c.Assert(a.And(b), Equals, uint64(17))
+	for i := uint64(0); i < 100; i++ {
+		c.Assert(a.Get(i), Equals, i%6 == 0)
+	}
+}
+
+func (s *arrayTestSuite) TestAtomic(c *C) {
+
+	for _, length := range []uint64{1, 7, 64, 100, 1001} {
+		a := New(length)
+		b := NewAtomic(length)
+		c.Assert(b.Len(), Equals, length)
+
+		for i := uint64(0); i < length; i += 3 {
+			a.Set(i)
+			b.Set(i)
+		}
+		b.Set(length - 1)
+		a.Set(length - 1)
+
+		for i := uint64(0); i < length; i++ {
+			c.Assert(b.Get(i), Equals, a.Get(i))
+		}
+
+		bufA := bytes.NewBuffer([]byte{})
+		c.Assert(a.ToBytes(bufA), IsNil)
+		bufB := bytes.NewBuffer([]byte{})
+		c.Assert(b.ToBytes(bufB), IsNil)
+		c.Assert(bufB.Bytes(), DeepEquals, bufA.Bytes())
+
+		c.Assert(b.PopCount(), Equals, a.PopCount())
+		c.Assert(b.PopCountRange(1, length), Equals, a.PopCountRange(1, length))
+		c.Assert(b.PopCountRange(length/3, length/2+1), Equals, a.PopCountRange(length/3, length/2+1))
+
+		read := NewAtomic(length)
+		c.Assert(read.Read(bufio.NewReader(bytes.NewReader(bufA.Bytes())), int64(bufA.Len())), IsNil)
+		c.Assert(read.PopCount(), Equals, a.PopCount())
+		c.Assert(read.Compare(b), IsNil)
+
+		clone := b.Clone()
+		clone.Set(1 % length)
+		c.Assert(clone.Get(1%length), Equals, true)
+		c.Assert(b.Get(1%length), Equals, a.Get(1%length))
+	}
+
+	a := NewAtomic(100)
+	b := NewAtomic(100)
+	for i := uint64(0); i < 100; i += 2 {
+		a.Set(i)
+	}
+	for i := uint64(0); i < 100; i += 3 {
+		b.Set(i)
+	}
+
+	merged := a.Clone()
+	c.Assert(merged.Merge(b), IsNil)
+	c.Assert(merged.PopCount(), Equals, uint64(50+34-17))
+
+	bufB := bytes.NewBuffer([]byte{})
+	c.Assert(b.ToBytes(bufB), IsNil)
+	merged = a.Clone()
+	c.Assert(merged.MergeBytes(bufB.Bytes()), IsNil)
+	c.Assert(merged.PopCount(), Equals, uint64(50+34-17))
+
+	c.Assert(a.Clone().And(b), Equals, uint64(17))
+	c.Assert(a.AndBytes(bufB.Bytes()), Equals, uint64(17))
+	c.Assert(NewAtomic(10).Compare(a), NotNil)
+}
+
+func (s *arrayTestSuite) TestAtomicConcurrent(c *C) {
+
+	a := NewAtomic(10000)
+
+	var wg sync.WaitGroup
+	for g := uint64(0); g < 8; g++ {
+		wg.Add(1)
+		go func(g uint64) {
+			defer wg.Done()
+			for i := g; i < 10000; i += 8 {
+				a.Set(i)
+				a.Get(i)
+			}
+		}(g)
+	}
+	wg.Wait()
+
+	c.Assert(a.PopCount(), Equals, uint64(10000))
+}
+
+func (s *arrayTestSuite) TestIteratorReset(c *C) {
+
+	positions := []uint64{0, 1, 7, 8, 63, 64, 65, 100, 65535, 65536, 70000, 199999}
+
+	for _, a := range []interface {
+		Set(i uint64)
+		Iterator() *Iterator
+		PopCount() uint64
+		Reset()
+	}{New(200000), NewAtomic(200000), NewSparse(200000), NewUnlocked(200000)} {
+
+		it := a.Iterator()
+		_, find := it.Next()
+		c.Assert(find, Equals, false)
+
+		for _, i := range positions {
+			a.Set(i)
+		}
+
+		out := []uint64{}
+		it = a.Iterator()
+		for i, find := it.Next(); find; i, find = it.Next() {
+			out = append(out, i)
+		}
+		c.Assert(out, DeepEquals, positions)
+
+		a.Reset()
+		c.Assert(a.PopCount(), Equals, uint64(0))
+		_, find = a.Iterator().Next()
+		c.Assert(find, Equals, false)
+	}
+
+	// bitmap container
+	a := NewSparse(1000000)
+	for i := uint64(0); i < 5000; i++ {
+		a.Set(i*2 + 1)
+	}
+	c.Assert(a.Sparse(), Equals, true)
+
+	count := uint64(0)
+	it := a.Iterator()
+	for i, find := it.Next(); find; i, find = it.Next() {
+		c.Assert(i, Equals, count*2+1)
+		count++
+	}
+	c.Assert(count, Equals, uint64(5000))
+}
+
+func (s *arrayTestSuite) TestXorAndNot(c *C) {
+
+	// a has every 2nd bit, b has every 3rd bit
+	makeArrays := func() []*Array {
+		out := []*Array{New(1000000), NewSparse(1000000), New(1000000), NewSparse(1000000)}
+		for i := uint64(0); i < 3000; i += 2 {
+			out[0].Set(i)
+			out[1].Set(i)
+		}
+		for i := uint64(0); i < 3000; i += 3 {
+			out[2].Set(i)
+			out[3].Set(i)
+		}
+		return out
+	}
+
+	for _, pair := range [][2]int{{0, 2}, {0, 3}, {1, 2}, {1, 3}} {
+		arrays := makeArrays()
+		a, b := arrays[pair[0]], arrays[pair[1]]
+
+		bufB := bytes.NewBuffer([]byte{})
+		c.Assert(b.ToBytes(bufB), IsNil)
+
+		// 1500 + 1000 - 2 * 500
+		c.Assert(a.Clone().Xor(b), Equals, uint64(1500))
+		c.Assert(a.Clone().XorBytes(bufB.Bytes()), Equals, uint64(1500))
+
+		andNot := a.Clone()
+		c.Assert(andNot.AndNot(b), Equals, uint64(1000))
+		c.Assert(a.Clone().AndNotBytes(bufB.Bytes()), Equals, uint64(1000))
+		for i := uint64(0); i < 3000; i++ {
+			c.Assert(andNot.Get(i), Equals, i%2 == 0 && i%3 != 0)
+		}
+	}
+
+	a := NewAtomic(3000)
+	b := NewAtomic(3000)
+	for i := uint64(0); i < 3000; i += 2 {
+		a.Set(i)
+	}
+	for i := uint64(0); i < 3000; i += 3 {
+		b.Set(i)
+	}
+	bufB := bytes.NewBuffer([]byte{})
+	c.Assert(b.ToBytes(bufB), IsNil)
+
+	c.Assert(a.Clone().Xor(b), Equals, uint64(1500))
+	c.Assert(a.Clone().XorBytes(bufB.Bytes()), Equals, uint64(1500))
+	c.Assert(a.Clone().AndNot(b), Equals, uint64(1000))
+	c.Assert(a.Clone().AndNotBytes(bufB.Bytes()), Equals, uint64(1000))
+}
+
+func (s *arrayTestSuite) TestStriped(c *C) {
+
+	a := NewStriped(100000, 16)
+	c.Assert(a.Stripes(), Equals, 16)
+	c.Assert(NewStriped(10, 0).Stripes(), Equals, 2)
+	c.Assert(NewStriped(100000, 0).Stripes(), Equals, DefaultStripes)
+
+	other := New(100000)
+	for i := uint64(0); i < 100000; i += 5 {
+		other.Set(i)
+	}
+
+	var wg sync.WaitGroup
+	for g := uint64(0); g < 8; g++ {
+		wg.Add(1)
+		go func(g uint64) {
+			defer wg.Done()
+			for i := g; i < 100000; i += 8 {
+				a.Set(i)
+				a.Get(i)
+			}
+		}(g)
+	}
+
+	// source of Merge is changed while it is merged, arrays are merged into each other
+	sparse := NewSparse(100000)
+	wg.Add(5)
+	go func() {
+		defer wg.Done()
+		for i := 0; i < 10; i++ {
+			c.Assert(a.Merge(other), IsNil)
+			c.Assert(a.Merge(sparse), IsNil)
+		}
+	}()
+	go func() {
+		defer wg.Done()
+		for i := 0; i < 10; i++ {
+			c.Assert(other.Merge(a), IsNil)
+			a.XorPopCountRange(other, 0, 100000)
+			other.XorPositions(a, 10)
+		}
+	}()
+	go func() {
+		defer wg.Done()
+		for i := uint64(1); i < 100000; i += 5 {
+			other.Set(i)
+		}
+	}()
+	go func() {
+		defer wg.Done()
+		for i := uint64(3); i < 100000; i += 1000 {
+			sparse.Set(i)
+		}
+	}()
+	go func() {
+		defer wg.Done()
+		for i := 0; i < 10; i++ {
+			c.Assert(a.ToBytes(bytes.NewBuffer([]byte{})), IsNil)
+			a.PopCount()
+		}
+	}()
+	wg.Wait()
+
+	c.Assert(a.PopCount(), Equals, uint64(100000))
+
+	clone := a.Clone()
+	c.Assert(clone.Stripes(), Equals, 16)
+	c.Assert(clone.PopCount(), Equals, uint64(100000))
+}
+
+func (s *arrayTestSuite) TestSnapshot(c *C) {
+
+	type snapshotArray interface {
+		Set(i uint64)
+		Reset()
+		MergeBytes(data []byte) error
+		PopCountRange(begin, end uint64) uint64
+		ToBytes(binBuf *bytes.Buffer) error
+		Snapshot() *Snapshot
+	}
+
+	image := func(a interface{ ToBytes(*bytes.Buffer) error }) []byte {
+		binBuf := bytes.NewBuffer([]byte{})
+		c.Assert(a.ToBytes(binBuf), IsNil)
+		return binBuf.Bytes()
+	}
+
+	length := uint64(100003)
+	for _, a := range []snapshotArray{New(length), NewStriped(length, 8), NewAtomic(length), NewSparse(length)} {
+
+		for i := uint64(0); i < length; i += 3 {
+			a.Set(i)
+		}
+		first := a.Snapshot()
+		firstImage := image(a)
+		firstRange := a.PopCountRange(1000, 70000)
+
+		for i := uint64(1); i < length; i += 7 {
+			a.Set(i)
+		}
+		second := a.Snapshot()
+		secondImage := image(a)
+
+		a.Reset()
+		c.Assert(a.MergeBytes(firstImage[:100]), IsNil)
+
+		c.Assert(image(first), DeepEquals, firstImage)
+		c.Assert(first.Len(), Equals, length)
+		c.Assert(first.PopCountRange(1000, 70000), Equals, firstRange)
+		c.Assert(first.Get(3), Equals, true)
+		c.Assert(first.Get(1), Equals, false)
+		c.Assert(second.Get(1), Equals, true)
+		c.Assert(image(second), DeepEquals, secondImage)
+
+		first.Release()
+		a.Set(length - 1)
+		c.Assert(image(second), DeepEquals, secondImage)
+		c.Assert(second.PopCount(), Equals, popCount(secondImage))
+
+		c.Assert(second.MergeBytes(firstImage), NotNil)
+		c.Assert(func() { second.Set(1) }, PanicMatches, ".*snapshot.*")
+		second.Release()
+	}
+}
+
+func (s *arrayTestSuite) TestEqual(c *C) {
+
+	a, b, sparse := New(1000), NewAtomic(1000), NewSparse(1000)
+	other := NewAtomic(1000)
+	for _, i := range []uint64{1, 100, 999} {
+		a.Set(i)
+		b.Set(i)
+		sparse.Set(i)
+		other.Set(i)
+	}
+
+	c.Assert(a.Equal(sparse), Equals, true)
+	c.Assert(a.Equal(New(1001)), Equals, false)
+	c.Assert(b.Equal(other), Equals, true)
+
+	other.Set(500)
+	c.Assert(b.Equal(other), Equals, false)
+	sparse.Set(500)
+	c.Assert(a.Equal(sparse), Equals, false)
+}
+
+func (s *arrayTestSuite) TestXorPositions(c *C) {
+
+	for _, length := range []uint64{63, 64, 1001, 3001} {
+		x, y := New(length), NewSparse(length)
+		ax, ay, striped := NewAtomic(length), NewAtomic(length), NewStriped(length, 4)
+		for i := uint64(0); i < length; i += 37 {
+			x.Set(i)
+			ax.Set(i)
+			striped.Set(i)
+		}
+		for i := uint64(0); i < length; i += 41 {
+			y.Set(i)
+			ay.Set(i)
+		}
+		c.Assert(y.Sparse(), Equals, length > 1000, Commentf("length %d", length))
+
+		expected := []uint64{}
+		for i := uint64(0); i < length; i++ {
+			if x.Get(i) != y.Get(i) {
+				expected = append(expected, i)
+			}
+		}
+
+		for _, limit := range []int{0, 1, 5, len(expected), len(expected) + 10} {
+			want := expected[:min(limit, len(expected))]
+			comment := Commentf("length %d, limit %d", length, limit)
+			c.Assert(x.XorPositions(y, limit), DeepEquals, want, comment)
+			c.Assert(y.XorPositions(x, limit), DeepEquals, want, comment)
+			c.Assert(striped.XorPositions(y, limit), DeepEquals, want, comment)
+			c.Assert(ax.XorPositions(ay, limit), DeepEquals, want, comment)
+			c.Assert(y.XorPositions(y, limit), DeepEquals, []uint64{}, comment)
+		}
+
+		c.Assert(x.Equal(striped), Equals, true)
+		c.Assert(x.Equal(y), Equals, false)
+		c.Assert(ax.Equal(ay), Equals, false)
+	}
+}
+
+func (s *arrayTestSuite) TestWriteTo(c *C) {
+
+	type writerArray interface {
+		Set(i uint64)
+		ToBytes(binBuf *bytes.Buffer) error
+		WriteTo(w io.Writer) (int64, error)
+		Snapshot() *Snapshot
+	}
+
+	for _, length := range []uint64{63, 64, 1001, 300007} {
+		for _, a := range []writerArray{New(length), NewStriped(length, 4), NewAtomic(length), NewSparse(length)} {
+
+			for i := uint64(0); i < length; i += 5 {
+				a.Set(i)
+			}
+			a.Set(length - 1)
+
+			binBuf := bytes.NewBuffer([]byte{})
+			c.Assert(a.ToBytes(binBuf), IsNil)
+			c.Assert(binBuf.Len(), Equals, int((length+7)/8))
+
+			written := bytes.NewBuffer([]byte{})
+			n, err := a.WriteTo(written)
+			c.Assert(err, IsNil)
+			c.Assert(n, Equals, int64(binBuf.Len()))
+			c.Assert(written.Bytes(), DeepEquals, binBuf.Bytes())
+
+			snapshot := a.Snapshot()
+			a.Set(1)
+			fromSnapshot := bytes.NewBuffer([]byte{})
+			n, err = snapshot.WriteTo(fromSnapshot)
+			c.Assert(err, IsNil)
+			c.Assert(n, Equals, int64(binBuf.Len()))
+			c.Assert(fromSnapshot.Bytes(), DeepEquals, binBuf.Bytes())
+			snapshot.Release()
+		}
+	}
+}
@@ -0,0 +1,290 @@
+package array
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/binary"
+	"fmt"
+	"io"
+	"math/bits"
+	"sync/atomic"
+)
+
+const wordSize = uint64(64)
+
+// atomicChunk is number of bytes which Atomic.WriteTo writes at once
+const atomicChunk = 32 * 1024
+
+// Atomic is a bit array without locks. Bits are stored in []uint64 and changed by atomic operations,
+// so Set and Get may be called from many goroutines. Byte image is the same as for Array.
+type Atomic struct {
+	words  []uint64
+	Length uint64
+
+	cow atomic.Pointer[cowState]
+}
+
+// NewAtomic is constructor
+func NewAtomic(length uint64) *Atomic {
+
+	l := (length + wordSize - 1) / wordSize
+
+	return &Atomic{
+		words:  make([]uint64, l, l),
+		Length: length,
+	}
+}
+
+// Set adds new point to array
+func (b *Atomic) Set(i uint64) {
+	b.preserve(int(i / wordSize))
+
+	addr := &b.words[i/wordSize]
+	mask := uint64(1) << (i % wordSize)
+
+	for {
+		old := atomic.LoadUint64(addr)
+		if old&mask != 0 || atomic.CompareAndSwapUint64(addr, old, old|mask) {
+			return
+		}
+	}
+}
+
+// Get return true if point is found in array
+func (b *Atomic) Get(i uint64) bool {
+	return atomic.LoadUint64(&b.words[i/wordSize])&(uint64(1)<<(i%wordSize)) != 0
+}
+
+// Reset clears all bits. Memory of array is reused.
+func (b *Atomic) Reset() {
+	b.preserveAll()
+	for j := range b.words {
+		atomic.StoreUint64(&b.words[j], 0)
+	}
+}
+
+// Len is a "getter". Returns length of array in bits.
+func (b *Atomic) Len() uint64 {
+	return b.Length
+}
+
+// or sets bits of word by atomic operation
+func (b *Atomic) or(j int, value uint64) {
+	b.preserve(j)
+
+	addr := &b.words[j]
+	for {
+		old := atomic.LoadUint64(addr)
+		if old|value == old || atomic.CompareAndSwapUint64(addr, old, old|value) {
+			return
+		}
+	}
+}
+
+// and clears bits of word by atomic operation. Returns new value.
+func (b *Atomic) and(j int, value uint64) uint64 {
+	b.preserve(j)
+
+	addr := &b.words[j]
+	for {
+		old := atomic.LoadUint64(addr)
+		if atomic.CompareAndSwapUint64(addr, old, old&value) {
+			return old & value
+		}
+	}
+}
+
+// Merge adds values from outside array into current
+func (b *Atomic) Merge(a *Atomic) error {
+	for j := range a.words {
+		b.or(j, atomic.LoadUint64(&a.words[j]))
+	}
+	return nil
+}
+
+// MergeBytes adds values from bytes in format of ToBytes
+func (b *Atomic) MergeBytes(data []byte) error {
+	for j := range b.words {
+		b.or(j, wordFromBytes(data, j))
+	}
+	return nil
+}
+
+// And keeps only values which are found in outside array too. Returns number of set bits.
+func (b *Atomic) And(a *Atomic) uint64 {
+	res := 0
+	for j := range b.words {
+		res += bits.OnesCount64(b.and(j, atomic.LoadUint64(&a.words[j])))
+	}
+	return uint64(res)
+}
+
+// AndBytes keeps only values which are found in bytes in format of ToBytes too. Returns number of set bits.
+func (b *Atomic) AndBytes(data []byte) uint64 {
+	res := 0
+	for j := range b.words {
+		res += bits.OnesCount64(b.and(j, wordFromBytes(data, j)))
+	}
+	return uint64(res)
+}
+
+// Clone returns independent copy of array
+func (b *Atomic) Clone() *Atomic {
+	out := &Atomic{
+		words:  make([]uint64, len(b.words), len(b.words)),
+		Length: b.Length,
+	}
+	for j := range b.words {
+		out.words[j] = atomic.LoadUint64(&b.words[j])
+	}
+	return out
+}
+
+// Compare checks characteristics of arrays
+func (b *Atomic) Compare(a *Atomic) error {
+	if a.Length != b.Length {
+		return fmt.Errorf("Wrong length for Array: %d != %d", a.Length, b.Length)
+	}
+	return nil
+}
+
+// PopCount returns number of set bits in array
+func (b *Atomic) PopCount() uint64 {
+	res := 0
+	for j := range b.words {
+		res += bits.OnesCount64(atomic.LoadUint64(&b.words[j]))
+	}
+	return uint64(res)
+}
+
+// PopCountRange returns number of set bits in positions [begin, end)
+func (b *Atomic) PopCountRange(begin, end uint64) uint64 {
+	if end > b.Length {
+		end = b.Length
+	}
+	if begin >= end {
+		return 0
+	}
+
+	first := begin / wordSize
+	last := (end - 1) / wordSize
+
+	// bits before begin and after end - 1 are cleared by masks
+	headMask := ^uint64(0) << (begin % wordSize)
+	tailMask := ^uint64(0) >> (wordSize - 1 - (end-1)%wordSize)
+
+	if first == last {
+		return uint64(bits.OnesCount64(atomic.LoadUint64(&b.words[first]) & headMask & tailMask))
+	}
+
+	res := bits.OnesCount64(atomic.LoadUint64(&b.words[first])&headMask) +
+		bits.OnesCount64(atomic.LoadUint64(&b.words[last])&tailMask)
+	for j := first + 1; j < last; j++ {
+		res += bits.OnesCount64(atomic.LoadUint64(&b.words[j]))
+	}
+	return uint64(res)
+}
+
+// OrPopCountRange returns number of positions in [begin, end) which are set in b or in a.
+// Arrays are not changed, so union of big arrays is counted without a copy.
+func (b *Atomic) OrPopCountRange(a *Atomic, begin, end uint64) uint64 {
+	return b.popCountRangeOf(a, begin, end, orWord)
+}
+
+// XorPopCountRange returns number of positions in [begin, end) which are set in one of arrays only.
+// Arrays are not changed.
+func (b *Atomic) XorPopCountRange(a *Atomic, begin, end uint64) uint64 {
+	return b.popCountRangeOf(a, begin, end, xorWord)
+}
+
+// popCountRangeOf returns number of set bits of op(b, a) in positions [begin, end).
+func (b *Atomic) popCountRangeOf(a *Atomic, begin, end uint64, op func(x, y uint64) uint64) uint64 {
+	end = min(end, b.Length, a.Length)
+	if begin >= end {
+		return 0
+	}
+
+	wordOf := func(j uint64) uint64 {
+		return op(atomic.LoadUint64(&b.words[j]), atomic.LoadUint64(&a.words[j]))
+	}
+
+	first := begin / wordSize
+	last := (end - 1) / wordSize
+
+	// bits before begin and after end - 1 are cleared by masks
+	headMask := ^uint64(0) << (begin % wordSize)
+	tailMask := ^uint64(0) >> (wordSize - 1 - (end-1)%wordSize)
+
+	if first == last {
+		return uint64(bits.OnesCount64(wordOf(first) & headMask & tailMask))
+	}
+
+	res := bits.OnesCount64(wordOf(first)&headMask) + bits.OnesCount64(wordOf(last)&tailMask)
+	for j := first + 1; j < last; j++ {
+		res += bits.OnesCount64(wordOf(j))
+	}
+	return uint64(res)
+}
+
+// ToBytes save internal array to buffer. Bytes are the same as Array.ToBytes writes.
+func (b *Atomic) ToBytes(binBuf *bytes.Buffer) error {
+	binBuf.Grow(int((b.Length + sizeOneByte - 1) / sizeOneByte))
+	_, err := b.WriteTo(binBuf)
+	return err
+}
+
+// WriteTo writes bytes of ToBytes to w by small chunks.
+func (b *Atomic) WriteTo(w io.Writer) (int64, error) {
+
+	l := int((b.Length + sizeOneByte - 1) / sizeOneByte)
+	chunk := make([]byte, 0, atomicChunk)
+
+	total := int64(0)
+	for j := range b.words {
+		chunk = binary.LittleEndian.AppendUint64(chunk, atomic.LoadUint64(&b.words[j]))
+		if len(chunk) < atomicChunk && j < len(b.words)-1 {
+			continue
+		}
+
+		// the last word may have extra bytes
+		if n := l - int(total); len(chunk) > n {
+			chunk = chunk[:n]
+		}
+
+		n, err := w.Write(chunk)
+		total += int64(n)
+		if err != nil {
+			return total, err
+		}
+		chunk = chunk[:0]
+	}
+
+	return total, nil
+}
+
+// Read read internal array from buffer
+func (b *Atomic) Read(reader *bufio.Reader, length int64) error {
+
+	data, err := readBytes(reader, length, b.Length)
+
+	b.preserveAll()
+	for j := range b.words {
+		atomic.StoreUint64(&b.words[j], wordFromBytes(data, j))
+	}
+
+	return err
+}
+
+// wordFromBytes returns j-th little-endian word of data. Missing bytes are zeros.
+func wordFromBytes(data []byte, j int) uint64 {
+	begin := j * 8
+	if begin+8 <= len(data) {
+		return binary.LittleEndian.Uint64(data[begin:])
+	}
+
+	var word [8]byte
+	if begin < len(data) {
+		copy(word[:], data[begin:])
+	}
+	return binary.LittleEndian.Uint64(word[:])
+}
@@ -0,0 +1,228 @@
+package array
+
+import (
+	"encoding/binary"
+	"math/bits"
+	"sync/atomic"
+)
+
+// Xor keeps values which are set in one of arrays only. Returns number of set bits.
+func (b *Array) Xor(a *Array) uint64 {
+
+	defer b.lockWith(a)()
+
+	b.preserveAll()
+
+	if b.sparse != nil {
+		b.densify()
+	}
+
+	if a.sparse != nil {
+		a.sparse.forEach(func(i uint64) {
+			b.bArray[i/sizeOneByte] ^= 1 << (i % sizeOneByte)
+		})
+		return popCount(b.bArray)
+	}
+
+	for i := range a.bArray {
+		b.bArray[i] ^= a.bArray[i]
+	}
+
+	return popCount(b.bArray)
+}
+
+// AndNot keeps only values which are not found in outside array. Returns number of set bits.
+func (b *Array) AndNot(a *Array) uint64 {
+
+	defer b.lockWith(a)()
+
+	b.preserveAll()
+
+	// result has only bits of sparse array, so it stays sparse
+	if b.sparse != nil {
+		return b.andSparse(b.sparse, func(i uint64) bool { return !a.getUnlocked(i) })
+	}
+
+	if a.sparse != nil {
+		a.sparse.forEach(func(i uint64) {
+			b.bArray[i/sizeOneByte] &^= 1 << (i % sizeOneByte)
+		})
+		return popCount(b.bArray)
+	}
+
+	for i := range a.bArray {
+		b.bArray[i] &^= a.bArray[i]
+	}
+
+	return popCount(b.bArray)
+}
+
+// XorBytes is Xor with bytes in format of ToBytes. Returns number of set bits.
+func (b *Array) XorBytes(data []byte) uint64 {
+
+	b.lock()
+	defer b.unlock()
+
+	b.preserveAll()
+
+	if b.sparse != nil {
+		b.densify()
+	}
+
+	for i := 0; i < len(data) && i < len(b.bArray); i++ {
+		b.bArray[i] ^= data[i]
+	}
+
+	return popCount(b.bArray)
+}
+
+// AndNotBytes is AndNot with bytes in format of ToBytes. Returns number of set bits.
+func (b *Array) AndNotBytes(data []byte) uint64 {
+
+	b.lock()
+	defer b.unlock()
+
+	b.preserveAll()
+
+	if b.sparse != nil {
+		return b.andSparse(b.sparse, func(i uint64) bool {
+			j := i / sizeOneByte
+			return j >= uint64(len(data)) || data[j]&(1<<(i%sizeOneByte)) == 0
+		})
+	}
+
+	for i := 0; i < len(data) && i < len(b.bArray); i++ {
+		b.bArray[i] &^= data[i]
+	}
+
+	return popCount(b.bArray)
+}
+
+// Xor keeps values which are set in one of arrays only. Returns number of set bits.
+func (b *Atomic) Xor(a *Atomic) uint64 {
+	res := 0
+	for j := range b.words {
+		res += bits.OnesCount64(b.xor(j, atomic.LoadUint64(&a.words[j])))
+	}
+	return uint64(res)
+}
+
+// AndNot keeps only values which are not found in outside array. Returns number of set bits.
+func (b *Atomic) AndNot(a *Atomic) uint64 {
+	res := 0
+	for j := range b.words {
+		res += bits.OnesCount64(b.and(j, ^atomic.LoadUint64(&a.words[j])))
+	}
+	return uint64(res)
+}
+
+// XorBytes is Xor with bytes in format of ToBytes. Returns number of set bits.
+func (b *Atomic) XorBytes(data []byte) uint64 {
+	res := 0
+	for j := range b.words {
+		res += bits.OnesCount64(b.xor(j, wordFromBytes(data, j)))
+	}
+	return uint64(res)
+}
+
+// AndNotBytes is AndNot with bytes in format of ToBytes. Returns number of set bits.
+func (b *Atomic) AndNotBytes(data []byte) uint64 {
+	res := 0
+	for j := range b.words {
+		res += bits.OnesCount64(b.and(j, ^wordFromBytes(data, j)))
+	}
+	return uint64(res)
+}
+
+// xor changes bits of word by atomic operation. Returns new value.
+func (b *Atomic) xor(j int, value uint64) uint64 {
+	b.preserve(j)
+
+	addr := &b.words[j]
+	for {
+		old := atomic.LoadUint64(addr)
+		if atomic.CompareAndSwapUint64(addr, old, old^value) {
+			return old ^ value
+		}
+	}
+}
+
+// Equal returns true if arrays have the same length and the same bits. Arrays are not copied.
+func (b *Array) Equal(a *Array) bool {
+	return b.Length == a.Length && b.XorPopCountRange(a, 0, b.Length) == 0
+}
+
+// Equal returns true if arrays have the same length and the same bits. Arrays are not copied.
+func (b *Atomic) Equal(a *Atomic) bool {
+	return b.Length == a.Length && b.XorPopCountRange(a, 0, b.Length) == 0
+}
+
+// XorPositions returns not more than limit first positions which are set in one of arrays only.
+// Words of arrays are compared one by one, so arrays are not copied.
+func (b *Array) XorPositions(a *Array, limit int) []uint64 {
+
+	defer b.rLockWith(a)()
+
+	return xorPositions(b.wordReader(), a.wordReader(), min(b.Length, a.Length), limit)
+}
+
+// XorPositions returns not more than limit first positions which are set in one of arrays only.
+// Arrays are not copied.
+func (b *Atomic) XorPositions(a *Atomic, limit int) []uint64 {
+
+	x := func(j uint64) uint64 { return atomic.LoadUint64(&b.words[j]) }
+	y := func(j uint64) uint64 { return atomic.LoadUint64(&a.words[j]) }
+
+	return xorPositions(x, y, min(b.Length, a.Length), limit)
+}
+
+// xorPositions scans words of x ^ y in ascending order and returns positions of set bits below length.
+func xorPositions(x, y func(j uint64) uint64, length uint64, limit int) []uint64 {
+
+	out := []uint64{}
+	for j := uint64(0); j*wordSize < length && len(out) < limit; j++ {
+		for w := x(j) ^ y(j); w != 0 && len(out) < limit; w &= w - 1 {
+			i := j*wordSize + uint64(bits.TrailingZeros64(w))
+			if i >= length {
+				break
+			}
+			out = append(out, i)
+		}
+	}
+
+	return out
+}
+
+// wordReader returns function which returns word j of locked array. Words must be read
+// in ascending order: set bits of sparse array are walked once.
+func (b *Array) wordReader() func(j uint64) uint64 {
+
+	if b.sparse == nil {
+		data := b.bArray
+		return func(j uint64) uint64 {
+			begin := j * wordSize / sizeOneByte
+			if begin+8 <= uint64(len(data)) {
+				return binary.LittleEndian.Uint64(data[begin:])
+			}
+			w := uint64(0)
+			for k := begin; k < uint64(len(data)); k++ {
+				w |= uint64(data[k]) << ((k - begin) * sizeOneByte)
+			}
+			return w
+		}
+	}
+
+	positions := make([]uint64, 0, b.sparse.count)
+	b.sparse.forEach(func(i uint64) { positions = append(positions, i) })
+
+	return func(j uint64) uint64 {
+		w := uint64(0)
+		for len(positions) > 0 && positions[0]/wordSize <= j {
+			if positions[0]/wordSize == j {
+				w |= 1 << (positions[0] % wordSize)
+			}
+			positions = positions[1:]
+		}
+		return w
+	}
+}
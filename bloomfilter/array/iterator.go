@@ -0,0 +1,134 @@
+package array
+
+import (
+	"math/bits"
+	"sort"
+	"sync/atomic"
+)
+
+// Iterator returns positions of set bits in ascending order:
+//
+//	it := b.Iterator()
+//	for i, find := it.Next(); find; i, find = it.Next() {
+//		...
+//	}
+//
+// Bits which are set during iteration may be skipped.
+type Iterator struct {
+	bits interface {
+		NextSet(i uint64) (uint64, bool)
+	}
+	pos uint64
+}
+
+// Next returns next set bit position and true or false if there are no more set bits.
+func (it *Iterator) Next() (uint64, bool) {
+	i, find := it.bits.NextSet(it.pos)
+	if !find {
+		return 0, false
+	}
+	it.pos = i + 1
+	return i, true
+}
+
+// Iterator returns iterator over set bits
+func (b *Array) Iterator() *Iterator {
+	return &Iterator{bits: b}
+}
+
+// Iterator returns iterator over set bits
+func (b *Atomic) Iterator() *Iterator {
+	return &Iterator{bits: b}
+}
+
+// NextSet returns the first set bit at position >= i.
+func (b *Array) NextSet(i uint64) (uint64, bool) {
+	if i >= b.Length {
+		return 0, false
+	}
+
+	b.rLock()
+	defer b.rUnlock()
+
+	if b.sparse != nil {
+		return b.sparse.nextSet(i, b.Length)
+	}
+
+	j := i / sizeOneByte
+	v := b.bArray[j] & uint8(0xff<<(i%sizeOneByte))
+	for {
+		if v != 0 {
+			return j*sizeOneByte + uint64(bits.TrailingZeros8(v)), true
+		}
+		j++
+		if j >= uint64(len(b.bArray)) {
+			return 0, false
+		}
+		v = b.bArray[j]
+	}
+}
+
+// NextSet returns the first set bit at position >= i.
+func (b *Atomic) NextSet(i uint64) (uint64, bool) {
+	if i >= b.Length {
+		return 0, false
+	}
+
+	j := i / wordSize
+	v := atomic.LoadUint64(&b.words[j]) & (^uint64(0) << (i % wordSize))
+	for {
+		if v != 0 {
+			return j*wordSize + uint64(bits.TrailingZeros64(v)), true
+		}
+		j++
+		if j >= uint64(len(b.words)) {
+			return 0, false
+		}
+		v = atomic.LoadUint64(&b.words[j])
+	}
+}
+
+// nextSet returns the first set bit at position >= i and < length.
+func (s *sparseBits) nextSet(i, length uint64) (uint64, bool) {
+	last := (length - 1) >> containerBits
+	for key := i >> containerBits; key <= last; key++ {
+		c, find := s.containers[key]
+		if !find {
+			continue
+		}
+
+		low := uint64(0)
+		if key == i>>containerBits {
+			low = i & (containerSize - 1)
+		}
+
+		if v, find := c.nextSet(low); find {
+			return key<<containerBits + v, true
+		}
+	}
+	return 0, false
+}
+
+// nextSet returns the first set bit at position >= low.
+func (c *container) nextSet(low uint64) (uint64, bool) {
+	if c.bitmap == nil {
+		j := sort.Search(len(c.values), func(j int) bool { return uint64(c.values[j]) >= low })
+		if j < len(c.values) {
+			return uint64(c.values[j]), true
+		}
+		return 0, false
+	}
+
+	j := low / 64
+	v := c.bitmap[j] & (^uint64(0) << (low % 64))
+	for {
+		if v != 0 {
+			return j*64 + uint64(bits.TrailingZeros64(v)), true
+		}
+		j++
+		if j >= uint64(len(c.bitmap)) {
+			return 0, false
+		}
+		v = c.bitmap[j]
+	}
+}
@@ -0,0 +1,106 @@
+package array
+
+import (
+	"fmt"
+	"os"
+)
+
+// Mapping is a file which is mapped into memory. Arrays which are created by NewMapped
+// use its bytes in place, so file is not read into heap.
+type Mapping struct {
+	data     []byte
+	writable bool
+}
+
+// Map maps whole file into memory. Changes of writable mapping are saved into file by Sync.
+func Map(file *os.File, writable bool) (*Mapping, error) {
+
+	info, err := file.Stat()
+	if err != nil {
+		return nil, err
+	}
+
+	if info.Size() == 0 {
+		return nil, fmt.Errorf("file %s is empty", file.Name())
+	}
+
+	data, err := mmap(file, int(info.Size()), writable)
+	if err != nil {
+		return nil, err
+	}
+
+	return &Mapping{data: data, writable: writable}, nil
+}
+
+// Bytes returns mapped bytes. Bytes of read-only mapping must not be changed.
+func (m *Mapping) Bytes() []byte {
+	return m.data
+}
+
+// Writable is a "getter". Returns true for read-write mapping.
+func (m *Mapping) Writable() bool {
+	return m.writable
+}
+
+// Sync flushes changes into file (msync).
+func (m *Mapping) Sync() error {
+	if !m.writable || m.data == nil {
+		return nil
+	}
+	return msync(m.data)
+}
+
+// Close unmaps file. Arrays of mapping must not be used after Close.
+func (m *Mapping) Close() error {
+	if m.data == nil {
+		return nil
+	}
+
+	if err := m.Sync(); err != nil {
+		return err
+	}
+
+	err := munmap(m.data)
+	m.data = nil
+	return err
+}
+
+// NewMapped creates array in bytes of mapping from offset.
+func NewMapped(m *Mapping, offset int64, length uint64) (*Array, error) {
+
+	l := int64((length + sizeOneByte - 1) / sizeOneByte)
+	if offset < 0 || offset+l > int64(len(m.data)) {
+		return nil, fmt.Errorf("mapped file is too short for array: %d < %d", len(m.data), offset+l)
+	}
+
+	return &Array{
+		SizeOneByte: sizeOneByte,
+		bArray:      m.data[offset : offset+l : offset+l],
+		Length:      length,
+		mapping:     m,
+	}, nil
+}
+
+// Sync flushes changes of mapped array into file. It does nothing for arrays in heap.
+func (b *Array) Sync() error {
+	return b.SyncWith(func() {})
+}
+
+// SyncWith calls fn while bits are not changed, then flushes changes of mapped array into file.
+// fn may update bytes of mapping which depend on bits, e.g. checksum. It does nothing for arrays in heap.
+func (b *Array) SyncWith(fn func()) error {
+	if b.mapping == nil {
+		return nil
+	}
+
+	b.rLock()
+	defer b.rUnlock()
+
+	fn()
+	return b.mapping.Sync()
+}
+
+// ReadOnly returns true if array is placed in read-only mapping.
+func (b *Array) ReadOnly() bool {
+	return b.mapping != nil && !b.mapping.writable
+}
@@ -0,0 +1,21 @@
+//go:build !unix
+
+package array
+
+import (
+	"fmt"
+	"os"
+	"runtime"
+)
+
+func mmap(file *os.File, size int, writable bool) ([]byte, error) {
+	return nil, fmt.Errorf("mmap is not supported on %s", runtime.GOOS)
+}
+
+func msync(data []byte) error {
+	return nil
+}
+
+func munmap(data []byte) error {
+	return nil
+}
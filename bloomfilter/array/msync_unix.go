@@ -0,0 +1,16 @@
+//go:build unix && !netbsd && !solaris && !illumos && !aix
+
+package array
+
+import (
+	"syscall"
+	"unsafe"
+)
+
+func msync(data []byte) error {
+	_, _, errno := syscall.Syscall(syscall.SYS_MSYNC, uintptr(unsafe.Pointer(&data[0])), uintptr(len(data)), syscall.MS_SYNC)
+	if errno != 0 {
+		return errno
+	}
+	return nil
+}
@@ -0,0 +1,147 @@
+package array
+
+import (
+	"encoding/binary"
+	"math/bits"
+)
+
+// PopCount returns number of set bits in array
+func (b *Array) PopCount() uint64 {
+	b.rLock()
+	defer b.rUnlock()
+
+	if b.sparse != nil {
+		return b.sparse.count
+	}
+
+	return popCount(b.bArray)
+}
+
+// PopCountRange returns number of set bits in positions [begin, end)
+func (b *Array) PopCountRange(begin, end uint64) uint64 {
+	if end > b.Length {
+		end = b.Length
+	}
+	if begin >= end {
+		return 0
+	}
+
+	b.rLock()
+	defer b.rUnlock()
+
+	return b.popCountRangeUnlocked(begin, end)
+}
+
+// OrPopCountRange returns number of positions in [begin, end) which are set in b or in a.
+// Arrays are not changed, so union of big arrays is counted without a copy.
+func (b *Array) OrPopCountRange(a *Array, begin, end uint64) uint64 {
+	return b.popCountRangeOf(a, begin, end, orWord)
+}
+
+// XorPopCountRange returns number of positions in [begin, end) which are set in one of arrays only.
+// Arrays are not changed.
+func (b *Array) XorPopCountRange(a *Array, begin, end uint64) uint64 {
+	return b.popCountRangeOf(a, begin, end, xorWord)
+}
+
+// popCountRangeOf returns number of set bits of op(b, a) in positions [begin, end).
+func (b *Array) popCountRangeOf(a *Array, begin, end uint64, op func(x, y uint64) uint64) uint64 {
+	end = min(end, b.Length, a.Length)
+	if begin >= end {
+		return 0
+	}
+
+	defer b.rLockWith(a)()
+
+	if b.sparse == nil && a.sparse == nil {
+		return popCountRangeOf(b.bArray, a.bArray, begin, end, op)
+	}
+
+	// sparse array has few set bits, so common bits are found by them
+	both := uint64(0)
+	s, other := b.sparse, a
+	if s == nil {
+		s, other = a.sparse, b
+	}
+	s.forEach(func(i uint64) {
+		if i >= begin && i < end && other.getUnlocked(i) {
+			both++
+		}
+	})
+
+	onlyB := b.popCountRangeUnlocked(begin, end) - both
+	onlyA := a.popCountRangeUnlocked(begin, end) - both
+	return onlyB*(op(1, 0)&1) + onlyA*(op(0, 1)&1) + both*(op(1, 1)&1)
+}
+
+// popCountRangeUnlocked is PopCountRange of locked array, begin < end <= Length.
+func (b *Array) popCountRangeUnlocked(begin, end uint64) uint64 {
+	if b.sparse != nil {
+		return b.sparse.popCountRange(begin, end)
+	}
+	return popCountRange(b.bArray, begin, end)
+}
+
+func popCount(data []byte) uint64 {
+	res := 0
+	for len(data) >= 8 {
+		res += bits.OnesCount64(binary.LittleEndian.Uint64(data))
+		data = data[8:]
+	}
+	for _, v := range data {
+		res += bits.OnesCount8(v)
+	}
+	return uint64(res)
+}
+
+func popCountRange(data []byte, begin, end uint64) uint64 {
+	first := begin / sizeOneByte
+	last := (end - 1) / sizeOneByte
+
+	// bits before begin and after end - 1 are cleared by masks
+	headMask := uint8(0xff << (begin % sizeOneByte))
+	tailMask := uint8(0xff >> (sizeOneByte - 1 - (end-1)%sizeOneByte))
+
+	if first == last {
+		return uint64(bits.OnesCount8(data[first] & headMask & tailMask))
+	}
+
+	res := uint64(bits.OnesCount8(data[first]&headMask)) + uint64(bits.OnesCount8(data[last]&tailMask))
+	return res + popCount(data[first+1:last])
+}
+
+// popCountRangeOf returns number of set bits of op(x, y) in positions [begin, end).
+func popCountRangeOf(x, y []byte, begin, end uint64, op func(x, y uint64) uint64) uint64 {
+	first := begin / sizeOneByte
+	last := (end - 1) / sizeOneByte
+
+	// bits before begin and after end - 1 are cleared by masks
+	headMask := uint64(uint8(0xff << (begin % sizeOneByte)))
+	tailMask := uint64(uint8(0xff >> (sizeOneByte - 1 - (end-1)%sizeOneByte)))
+
+	byteOf := func(j uint64) uint64 {
+		return op(uint64(x[j]), uint64(y[j])) & 0xff
+	}
+
+	if first == last {
+		return uint64(bits.OnesCount64(byteOf(first) & headMask & tailMask))
+	}
+
+	res := bits.OnesCount64(byteOf(first)&headMask) + bits.OnesCount64(byteOf(last)&tailMask)
+	j := first + 1
+	for ; j+8 <= last; j += 8 {
+		res += bits.OnesCount64(op(binary.LittleEndian.Uint64(x[j:]), binary.LittleEndian.Uint64(y[j:])))
+	}
+	for ; j < last; j++ {
+		res += bits.OnesCount64(byteOf(j))
+	}
+	return uint64(res)
+}
+
+func orWord(x, y uint64) uint64 {
+	return x | y
+}
+
+func xorWord(x, y uint64) uint64 {
+	return x ^ y
+}
@@ -0,0 +1,354 @@
+package array
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/binary"
+	"fmt"
+	"io"
+	"sync"
+	"sync/atomic"
+)
+
+/*
+	Snapshot is a read-only view of array at the moment of its creation.
+	Bytes of array are not copied by Snapshot. Array is split into pages and writer
+	copies page into all snapshots which still share it before the first change of page.
+	So snapshot costs nothing until array is changed and reading of snapshot does not block writers.
+*/
+
+// PageSize is number of bytes which are copied together for snapshots.
+const PageSize = 4096
+
+// Snapshot is an immutable point-in-time view of Array or Atomic.
+type Snapshot struct {
+	cow    *cowState
+	pages  [][]byte
+	frozen *Array
+	Length uint64
+}
+
+// cowState keeps snapshots of array which are not released yet.
+type cowState struct {
+	mu        sync.RWMutex
+	shared    []uint32 // 1 if page is not copied by some snapshot yet, accessed atomically
+	snapshots []*Snapshot
+	size      int // number of bytes in array
+
+	// readPage copies bytes of page from array, getLive returns bit of array
+	readPage func(p int, dst []byte)
+	getLive  func(i uint64) bool
+}
+
+func newCowState(size int) *cowState {
+	return &cowState{
+		shared: make([]uint32, (size+PageSize-1)/PageSize),
+		size:   size,
+	}
+}
+
+// pageLen returns number of bytes in p-th page, the last page may be short.
+func (c *cowState) pageLen(p int) int {
+	if (p+1)*PageSize > c.size {
+		return c.size - p*PageSize
+	}
+	return PageSize
+}
+
+// add registers new snapshot which shares all pages.
+func (c *cowState) add(s *Snapshot) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	s.cow = c
+	s.pages = make([][]byte, len(c.shared), len(c.shared))
+	c.snapshots = append(c.snapshots, s)
+	for p := range c.shared {
+		atomic.StoreUint32(&c.shared[p], 1)
+	}
+}
+
+// remove unregisters snapshot. Pages are not copied any more if there is no snapshot.
+func (c *cowState) remove(s *Snapshot) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	for i, item := range c.snapshots {
+		if item == s {
+			c.snapshots = append(c.snapshots[:i], c.snapshots[i+1:]...)
+			break
+		}
+	}
+
+	if len(c.snapshots) == 0 {
+		for p := range c.shared {
+			atomic.StoreUint32(&c.shared[p], 0)
+		}
+	}
+}
+
+// preserve copies p-th page into snapshots which share it. It must be called before change of page.
+func (c *cowState) preserve(p int) {
+	if atomic.LoadUint32(&c.shared[p]) == 0 {
+		return
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if atomic.LoadUint32(&c.shared[p]) == 0 {
+		return
+	}
+
+	// all snapshots which share page see the same bytes, so one copy is enough
+	page := make([]byte, c.pageLen(p), c.pageLen(p))
+	c.readPage(p, page)
+	for _, s := range c.snapshots {
+		if s.pages[p] == nil {
+			s.pages[p] = page
+		}
+	}
+
+	atomic.StoreUint32(&c.shared[p], 0)
+}
+
+// preserveAll copies all shared pages. It is called before bulk changes of array.
+func (c *cowState) preserveAll() {
+	for p := range c.shared {
+		c.preserve(p)
+	}
+}
+
+// Snapshot returns read-only view of array at this moment. Bytes are copied lazily
+// when array is changed later. Release must be called when snapshot is not needed.
+// Sparse and mapped arrays are copied at once.
+func (b *Array) Snapshot() *Snapshot {
+
+	if b.sparse != nil || b.mapping != nil {
+		return &Snapshot{frozen: b.Clone(), Length: b.Length}
+	}
+
+	b.lock()
+	defer b.unlock()
+
+	if b.cow == nil {
+		b.cow = newCowState(len(b.bArray))
+		b.cow.readPage = func(p int, dst []byte) {
+			copy(dst, b.bArray[p*PageSize:])
+		}
+		b.cow.getLive = func(i uint64) bool {
+			return b.bArray[i/sizeOneByte]&(1<<(i%sizeOneByte)) != 0
+		}
+	}
+
+	out := &Snapshot{Length: b.Length}
+	b.cow.add(out)
+	return out
+}
+
+// preserve copies page of j-th byte into snapshots before change.
+func (b *Array) preserve(j int) {
+	if b.cow != nil {
+		b.cow.preserve(j / PageSize)
+	}
+}
+
+// preserveAll copies all pages into snapshots before bulk change.
+func (b *Array) preserveAll() {
+	if b.cow != nil {
+		b.cow.preserveAll()
+	}
+}
+
+// Snapshot returns read-only view of array at this moment. Bytes are copied lazily
+// when array is changed later. Release must be called when snapshot is not needed.
+// Set calls which run together with Snapshot may be found in snapshot or not.
+func (b *Atomic) Snapshot() *Snapshot {
+
+	c := b.cow.Load()
+	if c == nil {
+		c = newCowState(int((b.Length + sizeOneByte - 1) / sizeOneByte))
+		c.readPage = func(p int, dst []byte) {
+			var word [8]byte
+			for n := 0; n < len(dst); n += 8 {
+				binary.LittleEndian.PutUint64(word[:], atomic.LoadUint64(&b.words[(p*PageSize+n)/8]))
+				copy(dst[n:], word[:])
+			}
+		}
+		c.getLive = b.Get
+		if !b.cow.CompareAndSwap(nil, c) {
+			c = b.cow.Load()
+		}
+	}
+
+	out := &Snapshot{Length: b.Length}
+	c.add(out)
+	return out
+}
+
+// preserve copies page of j-th word into snapshots before change.
+func (b *Atomic) preserve(j int) {
+	if c := b.cow.Load(); c != nil {
+		c.preserve(j * 8 / PageSize)
+	}
+}
+
+// preserveAll copies all pages into snapshots before bulk change.
+func (b *Atomic) preserveAll() {
+	if c := b.cow.Load(); c != nil {
+		c.preserveAll()
+	}
+}
+
+// Release frees pages of snapshot. Snapshot must not be used after Release.
+func (s *Snapshot) Release() {
+	if s.cow != nil {
+		s.cow.remove(s)
+	}
+	s.cow = nil
+	s.pages = nil
+	s.frozen = nil
+}
+
+// Get return true if point is found in snapshot
+func (s *Snapshot) Get(i uint64) bool {
+
+	if s.frozen != nil {
+		return s.frozen.Get(i)
+	}
+
+	j := int(i / sizeOneByte)
+	p := j / PageSize
+
+	s.cow.mu.RLock()
+	defer s.cow.mu.RUnlock()
+
+	if page := s.pages[p]; page != nil {
+		return page[j-p*PageSize]&(1<<(i%sizeOneByte)) != 0
+	}
+
+	// page is not changed after snapshot, preserve waits for unlock
+	return s.cow.getLive(i)
+}
+
+// Len is a "getter". Returns length of snapshot in bits.
+func (s *Snapshot) Len() uint64 {
+	return s.Length
+}
+
+// forPages calls fn for pages [from, to) of snapshot. Only one page is locked at once,
+// so writers of array are not blocked for long time.
+func (s *Snapshot) forPages(from, to int, fn func(offset int, data []byte) error) error {
+
+	buf := make([]byte, PageSize, PageSize)
+	for p := from; p < to; p++ {
+		s.cow.mu.RLock()
+		data := s.pages[p]
+		if data == nil {
+			data = buf[:s.cow.pageLen(p)]
+			s.cow.readPage(p, data)
+		}
+		s.cow.mu.RUnlock()
+
+		if err := fn(p*PageSize, data); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// PopCount returns number of set bits in snapshot
+func (s *Snapshot) PopCount() uint64 {
+
+	if s.frozen != nil {
+		return s.frozen.PopCount()
+	}
+
+	res := uint64(0)
+	s.forPages(0, len(s.pages), func(offset int, data []byte) error {
+		res += popCount(data)
+		return nil
+	})
+	return res
+}
+
+// PopCountRange returns number of set bits in positions [begin, end)
+func (s *Snapshot) PopCountRange(begin, end uint64) uint64 {
+
+	if s.frozen != nil {
+		return s.frozen.PopCountRange(begin, end)
+	}
+
+	if end > s.Length {
+		end = s.Length
+	}
+	if begin >= end {
+		return 0
+	}
+
+	pageBits := uint64(PageSize) * sizeOneByte
+	res := uint64(0)
+	s.forPages(int(begin/pageBits), int((end-1)/pageBits)+1, func(offset int, data []byte) error {
+		first := uint64(offset) * sizeOneByte
+		last := first + uint64(len(data))*sizeOneByte
+		res += popCountRange(data, max(begin, first)-first, min(end, last)-first)
+		return nil
+	})
+	return res
+}
+
+// ToBytes save bytes of snapshot to buffer. Bytes are the same as Array.ToBytes writes.
+func (s *Snapshot) ToBytes(binBuf *bytes.Buffer) error {
+	if s.frozen == nil {
+		binBuf.Grow(s.cow.size)
+	}
+	_, err := s.WriteTo(binBuf)
+	return err
+}
+
+// WriteTo writes bytes of ToBytes to w page by page.
+func (s *Snapshot) WriteTo(w io.Writer) (int64, error) {
+
+	if s.frozen != nil {
+		return s.frozen.WriteTo(w)
+	}
+
+	total := int64(0)
+	err := s.forPages(0, len(s.pages), func(offset int, data []byte) error {
+		n, err := w.Write(data)
+		total += int64(n)
+		return err
+	})
+	return total, err
+}
+
+// Set panics, snapshot is read-only.
+func (s *Snapshot) Set(i uint64) {
+	panic("array: Set of snapshot")
+}
+
+// Reset panics, snapshot is read-only.
+func (s *Snapshot) Reset() {
+	panic("array: Reset of snapshot")
+}
+
+// MergeBytes returns error, snapshot is read-only.
+func (s *Snapshot) MergeBytes(data []byte) error {
+	return fmt.Errorf("snapshot is read-only")
+}
+
+// AndBytes panics, snapshot is read-only.
+func (s *Snapshot) AndBytes(data []byte) uint64 {
+	panic("array: AndBytes of snapshot")
+}
+
+// AndNotBytes panics, snapshot is read-only.
+func (s *Snapshot) AndNotBytes(data []byte) uint64 {
+	panic("array: AndNotBytes of snapshot")
+}
+
+// Read returns error, snapshot is read-only.
+func (s *Snapshot) Read(reader *bufio.Reader, length int64) error {
+	return fmt.Errorf("snapshot is read-only")
+}
@@ -0,0 +1,263 @@
+package array
+
+import (
+	"io"
+	"math/bits"
+	"sort"
+)
+
+/*
+	Sparse array keeps set bits in containers like roaring bitmaps. Every container
+	has 2^16 bits. It is a sorted list of set positions while it is small
+	and it is changed to bitmap when it has more than arrayContainerMax positions.
+
+	Sparse array is changed to dense []byte when part of set bits is more than 1/sparseDensity.
+*/
+
+const (
+	containerBits     = 16
+	containerSize     = uint64(1) << containerBits
+	containerBytes    = int(containerSize / sizeOneByte)
+	arrayContainerMax = 4096
+
+	// sparseDensity defines when sparse array is changed to dense one
+	sparseDensity = 32
+)
+
+// NewSparse is constructor for array which does not allocate memory for bits
+// until they are set. It is changed to usual dense array automatically.
+func NewSparse(length uint64) *Array {
+	return &Array{
+		SizeOneByte: sizeOneByte,
+		Length:      length,
+		sparse:      newSparseBits(),
+	}
+}
+
+// Sparse returns true if array still keeps bits in sparse containers.
+func (b *Array) Sparse() bool {
+	b.rLock()
+	defer b.rUnlock()
+
+	return b.sparse != nil
+}
+
+// densify changes sparse array to dense. Array must be locked.
+func (b *Array) densify() {
+	l := (b.Length + sizeOneByte - 1) / sizeOneByte
+	b.bArray = make([]byte, l, l)
+	b.sparse.forEach(func(i uint64) {
+		b.bArray[i/sizeOneByte] |= 1 << (i % sizeOneByte)
+	})
+	b.sparse = nil
+}
+
+// setSparse sets bit of sparse array and changes it to dense when it is needed. Array must be locked.
+func (b *Array) setSparse(i uint64) {
+	if b.sparse.set(i) && b.sparse.count*sparseDensity >= b.Length {
+		b.densify()
+	}
+}
+
+// getUnlocked returns bit for dense and sparse array without locking.
+func (b *Array) getUnlocked(i uint64) bool {
+	if b.sparse != nil {
+		return b.sparse.get(i)
+	}
+	return b.bArray[i/sizeOneByte]&(1<<(i%sizeOneByte)) != 0
+}
+
+// andSparse keeps bits of src which are found by get. Result is sparse. Array must be locked.
+func (b *Array) andSparse(src *sparseBits, get func(i uint64) bool) uint64 {
+	out := newSparseBits()
+	src.forEach(func(i uint64) {
+		if get(i) {
+			out.set(i)
+		}
+	})
+
+	b.sparse = out
+	b.bArray = nil
+	return out.count
+}
+
+type container struct {
+	values []uint16 // sorted positions, if bitmap is nil
+	bitmap []uint64
+	count  int
+}
+
+type sparseBits struct {
+	containers map[uint64]*container
+	count      uint64
+}
+
+func newSparseBits() *sparseBits {
+	return &sparseBits{containers: map[uint64]*container{}}
+}
+
+func (s *sparseBits) get(i uint64) bool {
+	c, find := s.containers[i>>containerBits]
+	if !find {
+		return false
+	}
+	return c.get(uint16(i))
+}
+
+// set returns true if bit is new
+func (s *sparseBits) set(i uint64) bool {
+	key := i >> containerBits
+	c, find := s.containers[key]
+	if !find {
+		c = &container{}
+		s.containers[key] = c
+	}
+
+	if !c.set(uint16(i)) {
+		return false
+	}
+
+	s.count++
+	return true
+}
+
+// keys returns sorted keys of containers
+func (s *sparseBits) keys() []uint64 {
+	keys := make([]uint64, 0, len(s.containers))
+	for key := range s.containers {
+		keys = append(keys, key)
+	}
+	sort.Slice(keys, func(i, j int) bool { return keys[i] < keys[j] })
+	return keys
+}
+
+// forEach calls fn for every set bit in ascending order
+func (s *sparseBits) forEach(fn func(i uint64)) {
+	for _, key := range s.keys() {
+		base := key << containerBits
+		s.containers[key].forEach(func(low uint16) {
+			fn(base + uint64(low))
+		})
+	}
+}
+
+func (s *sparseBits) clone() *sparseBits {
+	out := &sparseBits{
+		containers: make(map[uint64]*container, len(s.containers)),
+		count:      s.count,
+	}
+	for key, c := range s.containers {
+		out.containers[key] = &container{
+			values: append([]uint16(nil), c.values...),
+			bitmap: append([]uint64(nil), c.bitmap...),
+			count:  c.count,
+		}
+	}
+	return out
+}
+
+// popCountRange returns number of set bits in positions [begin, end)
+func (s *sparseBits) popCountRange(begin, end uint64) uint64 {
+	res := uint64(0)
+	for key, c := range s.containers {
+		base := key << containerBits
+		if base >= end || base+containerSize <= begin {
+			continue
+		}
+		if base >= begin && base+containerSize <= end {
+			res += uint64(c.count)
+			continue
+		}
+		c.forEach(func(low uint16) {
+			if i := base + uint64(low); i >= begin && i < end {
+				res++
+			}
+		})
+	}
+	return res
+}
+
+// toBytes writes l bytes of dense image.
+func (s *sparseBits) toBytes(w io.Writer, l int) error {
+	chunk := make([]byte, containerBytes, containerBytes)
+	for begin, key := 0, uint64(0); begin < l; begin, key = begin+containerBytes, key+1 {
+
+		for i := range chunk {
+			chunk[i] = 0
+		}
+
+		if c, find := s.containers[key]; find {
+			c.forEach(func(low uint16) {
+				chunk[low/8] |= 1 << (low % 8)
+			})
+		}
+
+		n := l - begin
+		if n > containerBytes {
+			n = containerBytes
+		}
+		if _, err := w.Write(chunk[:n]); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (c *container) get(low uint16) bool {
+	if c.bitmap != nil {
+		return c.bitmap[low/64]&(1<<(low%64)) != 0
+	}
+	j := sort.Search(len(c.values), func(j int) bool { return c.values[j] >= low })
+	return j < len(c.values) && c.values[j] == low
+}
+
+// set returns true if bit is new
+func (c *container) set(low uint16) bool {
+	if c.bitmap != nil {
+		if c.bitmap[low/64]&(1<<(low%64)) != 0 {
+			return false
+		}
+		c.bitmap[low/64] |= 1 << (low % 64)
+		c.count++
+		return true
+	}
+
+	j := sort.Search(len(c.values), func(j int) bool { return c.values[j] >= low })
+	if j < len(c.values) && c.values[j] == low {
+		return false
+	}
+
+	c.values = append(c.values, 0)
+	copy(c.values[j+1:], c.values[j:])
+	c.values[j] = low
+	c.count++
+
+	if c.count > arrayContainerMax {
+		c.toBitmap()
+	}
+	return true
+}
+
+func (c *container) toBitmap() {
+	c.bitmap = make([]uint64, containerSize/64, containerSize/64)
+	for _, low := range c.values {
+		c.bitmap[low/64] |= 1 << (low % 64)
+	}
+	c.values = nil
+}
+
+func (c *container) forEach(fn func(low uint16)) {
+	if c.bitmap == nil {
+		for _, low := range c.values {
+			fn(low)
+		}
+		return
+	}
+
+	for j, word := range c.bitmap {
+		for word != 0 {
+			fn(uint16(j*64 + bits.TrailingZeros64(word)))
+			word &= word - 1
+		}
+	}
+}
@@ -0,0 +1,99 @@
+package array
+
+import (
+	"bytes"
+	"math/rand"
+
+	. "gopkg.in/check.v1"
+)
+
+func (s *arrayTestSuite) TestSparse(c *C) {
+
+	length := uint64(1000 * 1000)
+	rnd := rand.New(rand.NewSource(1))
+
+	sparse := NewSparse(length)
+	dense := New(length)
+	c.Assert(sparse.Sparse(), Equals, true)
+	c.Assert(len(sparse.bArray), Equals, 0)
+
+	// one container becomes bitmap
+	for i := uint64(0); i < 5000; i++ {
+		sparse.Set(i * 3)
+		dense.Set(i * 3)
+	}
+	for i := 0; i < 10000; i++ {
+		n := uint64(rnd.Int63n(int64(length)))
+		sparse.Set(n)
+		dense.Set(n)
+	}
+	sparse.Set(length - 1)
+	dense.Set(length - 1)
+
+	c.Assert(sparse.Sparse(), Equals, true)
+	c.Assert(sparse.PopCount(), Equals, dense.PopCount())
+
+	for i := uint64(0); i < length; i += 7 {
+		c.Assert(sparse.Get(i), Equals, dense.Get(i))
+	}
+
+	for _, r := range [][2]uint64{{0, length}, {5, 70000}, {65536, 131072}, {100, 200}, {length - 10, length}} {
+		c.Assert(sparse.PopCountRange(r[0], r[1]), Equals, dense.PopCountRange(r[0], r[1]), Commentf("range %v", r))
+	}
+
+	bufS := bytes.NewBuffer([]byte{})
+	c.Assert(sparse.ToBytes(bufS), IsNil)
+	bufD := bytes.NewBuffer([]byte{})
+	c.Assert(dense.ToBytes(bufD), IsNil)
+	c.Assert(bufS.Bytes(), DeepEquals, bufD.Bytes())
+
+	clone := sparse.Clone()
+	c.Assert(clone.Sparse(), Equals, true)
+	c.Assert(sparse.Get(2), Equals, false)
+	clone.Set(2)
+	c.Assert(clone.Get(2), Equals, true)
+	c.Assert(sparse.Get(2), Equals, false)
+
+	// and of sparse array keeps it sparse
+	other := New(length)
+	for i := uint64(0); i < length; i += 2 {
+		other.Set(i)
+	}
+	expected := dense.Clone().And(other)
+	c.Assert(sparse.Clone().And(other), Equals, expected)
+	c.Assert(other.Clone().And(sparse), Equals, expected)
+	c.Assert(sparse.Clone().AndBytes(bufD.Bytes()), Equals, dense.PopCount())
+
+	andSparse := other.Clone()
+	andSparse.And(sparse)
+	c.Assert(andSparse.Sparse(), Equals, true)
+
+	// merge of two sparse arrays
+	merged := NewSparse(length)
+	c.Assert(merged.Merge(sparse), IsNil)
+	c.Assert(merged.Sparse(), Equals, true)
+	c.Assert(merged.PopCount(), Equals, sparse.PopCount())
+
+	merged = New(length)
+	c.Assert(merged.Merge(sparse), IsNil)
+	c.Assert(merged.PopCount(), Equals, sparse.PopCount())
+
+	merged = NewSparse(length)
+	c.Assert(merged.Merge(dense), IsNil)
+	c.Assert(merged.Sparse(), Equals, false)
+	c.Assert(merged.PopCount(), Equals, dense.PopCount())
+
+	// dense after threshold
+	for i := uint64(0); i < length/sparseDensity; i++ {
+		sparse.Set(i * 16)
+		dense.Set(i * 16)
+	}
+	c.Assert(sparse.Sparse(), Equals, false)
+	c.Assert(sparse.PopCount(), Equals, dense.PopCount())
+
+	bufS.Reset()
+	c.Assert(sparse.ToBytes(bufS), IsNil)
+	bufD.Reset()
+	c.Assert(dense.ToBytes(bufD), IsNil)
+	c.Assert(bufS.Bytes(), DeepEquals, bufD.Bytes())
+}
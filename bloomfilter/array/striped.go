@@ -0,0 +1,154 @@
+package array
+
+import (
+	"sync"
+	"unsafe"
+)
+
+/*
+	Striped array splits bytes into stripes, every stripe has own lock.
+	Set and Get lock one stripe only, so writers of different stripes do not wait each other.
+	Bulk operations (Merge, ToBytes, Read and others) lock all stripes in ascending order,
+	so they can not deadlock with each other. Operations of two arrays (Merge, And, Xor and others)
+	lock the source array for reading too, arrays are locked in order of their addresses.
+*/
+
+// DefaultStripes is number of stripes which is used by NewStriped if it gets 0.
+const DefaultStripes = 64
+
+// stripe is a lock which takes whole cache line, so neighbour locks do not share it
+type stripe struct {
+	sync.RWMutex
+	_ [40]byte
+}
+
+// NewStriped is constructor for dense array with stripes number of locks.
+func NewStriped(length uint64, stripes int) *Array {
+
+	out := New(length)
+
+	if stripes <= 0 {
+		stripes = DefaultStripes
+	}
+	if stripes > len(out.bArray) {
+		stripes = len(out.bArray)
+	}
+	if stripes < 1 {
+		stripes = 1
+	}
+
+	out.stripes = make([]stripe, stripes, stripes)
+	out.stripeBytes = (len(out.bArray) + stripes - 1) / stripes
+	if out.stripeBytes < 1 {
+		out.stripeBytes = 1
+	}
+
+	return out
+}
+
+// Stripes is a "getter". Returns number of stripe locks, 0 for not striped array.
+func (b *Array) Stripes() int {
+	return len(b.stripes)
+}
+
+// lockBit locks stripe of j-th byte or whole array
+func (b *Array) lockBit(j int) {
+	if b.stripes != nil {
+		b.stripes[j/b.stripeBytes].Lock()
+	} else if !b.unlocked {
+		b.mc.Lock()
+	}
+}
+
+func (b *Array) unlockBit(j int) {
+	if b.stripes != nil {
+		b.stripes[j/b.stripeBytes].Unlock()
+	} else if !b.unlocked {
+		b.mc.Unlock()
+	}
+}
+
+func (b *Array) rLockBit(j int) {
+	if b.stripes != nil {
+		b.stripes[j/b.stripeBytes].RLock()
+	} else if !b.unlocked {
+		b.mc.RLock()
+	}
+}
+
+func (b *Array) rUnlockBit(j int) {
+	if b.stripes != nil {
+		b.stripes[j/b.stripeBytes].RUnlock()
+	} else if !b.unlocked {
+		b.mc.RUnlock()
+	}
+}
+
+// lockStripes locks all stripes in fixed order
+func (b *Array) lockStripes() {
+	for i := range b.stripes {
+		b.stripes[i].Lock()
+	}
+}
+
+func (b *Array) unlockStripes() {
+	for i := len(b.stripes) - 1; i >= 0; i-- {
+		b.stripes[i].Unlock()
+	}
+}
+
+func (b *Array) rLockStripes() {
+	for i := range b.stripes {
+		b.stripes[i].RLock()
+	}
+}
+
+func (b *Array) rUnlockStripes() {
+	for i := len(b.stripes) - 1; i >= 0; i-- {
+		b.stripes[i].RUnlock()
+	}
+}
+
+// lockWith locks b for writing and a for reading in order of their addresses,
+// so a.Merge(b) and b.Merge(a) can not deadlock. Returns function which unlocks both arrays.
+func (b *Array) lockWith(a *Array) func() {
+
+	if a == b {
+		b.lock()
+		return b.unlock
+	}
+
+	if uintptr(unsafe.Pointer(a)) < uintptr(unsafe.Pointer(b)) {
+		a.rLock()
+		b.lock()
+	} else {
+		b.lock()
+		a.rLock()
+	}
+
+	return func() {
+		b.unlock()
+		a.rUnlock()
+	}
+}
+
+// rLockWith locks b and a for reading in order of their addresses. Returns function which unlocks both arrays.
+func (b *Array) rLockWith(a *Array) func() {
+
+	if a == b {
+		b.rLock()
+		return b.rUnlock
+	}
+
+	first, second := b, a
+	if uintptr(unsafe.Pointer(a)) < uintptr(unsafe.Pointer(b)) {
+		first, second = a, b
+	}
+	first.rLock()
+	second.rLock()
+
+	return func() {
+		second.rUnlock()
+		first.rUnlock()
+	}
+}
@@ -1,30 +1,92 @@
 package bloomfilter
 
 import (
-// "testing"
+	"testing"
+
+	"github.com/iostrovok/go-bloom-filter/bloomfilter/fortesting"
 )
 
-var bloomBanch *BloomFilter
-
-// func init() {
-// 	var err error
-// 	bloomBanch, err = New(10000*10000*1000, 0.001)
-// 	if err != nil {
-// 		panic(err)
-// 	}
-// }
-
-// func BenchmarkAdd(b *testing.B) {
-// 	for _, s := range testData {
-// 		bloomBanch.Add([]byte(s))
-// 	}
-// }
-
-// func BenchmarkCheck(b *testing.B) {
-// 	for _, s := range testData {
-// 		bloomBanch.Check([]byte(s))
-// 		if !bloomBanch.Check([]byte(s)) {
-// 			panic("wring banch test!")
-// 		}
-// 	}
-// }
+func benchKeys() [][]byte {
+	testArray := fortesting.ArrayForTesting()
+	keys := make([][]byte, len(testArray), len(testArray))
+	for i, s := range testArray {
+		keys[i] = []byte(s)
+	}
+	return keys
+}
+
+func benchAdd(b *testing.B, opts ...Option) {
+	keys := benchKeys()
+	filter, err := NewWithOptions(10000*1000, append(opts, WithOverflowPolicy(OverflowIgnore))...)
+	if err != nil {
+		b.Fatal(err)
+	}
+
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		filter.Add(keys[i%len(keys)])
+	}
+}
+
+func benchCheck(b *testing.B, opts ...Option) {
+	keys := benchKeys()
+	filter, err := NewWithOptions(10000*1000, opts...)
+	if err != nil {
+		b.Fatal(err)
+	}
+	for _, key := range keys {
+		filter.Add(key)
+	}
+
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		if !filter.Check(keys[i%len(keys)]) {
+			b.Fatal("wrong bench test!")
+		}
+	}
+}
+
+func BenchmarkAdd(b *testing.B) {
+	benchAdd(b)
+}
+
+func BenchmarkCheck(b *testing.B) {
+	benchCheck(b)
+}
+
+func BenchmarkAddXXHashDouble(b *testing.B) {
+	benchAdd(b, WithHashStrategy(HashXXHash64), WithIndexMode(IndexDoubleHashing))
+}
+
+func BenchmarkCheckXXHashDouble(b *testing.B) {
+	benchCheck(b, WithHashStrategy(HashXXHash64), WithIndexMode(IndexDoubleHashing))
+}
+
+func benchAddParallel(b *testing.B, opts ...Option) {
+	keys := benchKeys()
+	filter, err := NewWithOptions(10000*1000, append(opts, WithHashStrategy(HashXXHash64),
+		WithIndexMode(IndexDoubleHashing), WithOverflowPolicy(OverflowIgnore))...)
+	if err != nil {
+		b.Fatal(err)
+	}
+
+	b.ReportAllocs()
+	b.ResetTimer()
+	b.RunParallel(func(pb *testing.PB) {
+		i := 0
+		for pb.Next() {
+			filter.Add(keys[i%len(keys)])
+			i++
+		}
+	})
+}
+
+func BenchmarkAddParallelLocked(b *testing.B) {
+	benchAddParallel(b)
+}
+
+func BenchmarkAddParallelAtomic(b *testing.B) {
+	benchAddParallel(b, WithConcurrency(ConcurrencyAtomic))
+}
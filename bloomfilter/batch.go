@@ -0,0 +1,183 @@
+package bloomfilter
+
+import (
+	"fmt"
+	"sync"
+	"sync/atomic"
+)
+
+// batchSize is number of keys which are hashed by workers before bits are set.
+const batchSize = 4096
+
+// AddMany adds keys in input order. Returns number of keys which were not found in filter.
+// Keys are hashed by worker pool if filter is created with WithWorkers.
+// Bits are set by one goroutine, so count and capacity check are the same as for Add.
+func (bf *BloomFilter) AddMany(keys [][]byte) (added int, err error) {
+
+	if bf.opts.workers < 2 {
+		for _, key := range keys {
+			found, err := bf.Add(key)
+			if err != nil {
+				return added, err
+			}
+			if !found {
+				added++
+			}
+		}
+		return added, nil
+	}
+
+	locations := make([]uint64, batchSize*bf.numSlices, batchSize*bf.numSlices)
+	counts := make([]int, batchSize, batchSize)
+	for begin := 0; begin < len(keys); begin += batchSize {
+		end := begin + batchSize
+		if end > len(keys) {
+			end = len(keys)
+		}
+
+		bf.hashMany(keys[begin:end], locations, counts)
+
+		for i := 0; i < end-begin; i++ {
+			found, err := bf.addLocations(locations[i*bf.numSlices : i*bf.numSlices+counts[i]])
+			if err != nil {
+				return added, err
+			}
+			if !found {
+				added++
+			}
+		}
+	}
+
+	return added, nil
+}
+
+// CheckMany checks keys. Result has the same order as keys.
+// Keys are checked by worker pool if filter is created with WithWorkers.
+func (bf *BloomFilter) CheckMany(keys [][]byte) []bool {
+
+	out := make([]bool, len(keys), len(keys))
+
+	bf.parallel(len(keys), func(begin, end int) {
+		for i := begin; i < end; i++ {
+			out[i] = bf.Check(keys[i])
+		}
+	})
+
+	return out
+}
+
+// hashMany writes absolute bit positions for every key into locations
+// and number of positions into counts.
+func (bf *BloomFilter) hashMany(keys [][]byte, locations []uint64, counts []int) {
+	bf.parallel(len(keys), func(begin, end int) {
+		sc := scratchPool.Get().(*scratch)
+		defer scratchPool.Put(sc)
+
+		for i := begin; i < end; i++ {
+			counts[i] = len(bf.locations(locations[i*bf.numSlices:i*bf.numSlices], keys[i], sc))
+		}
+	})
+}
+
+// parallel splits [0, count) into ranges and runs fn for every range by own goroutine.
+func (bf *BloomFilter) parallel(count int, fn func(begin, end int)) {
+
+	workers := bf.opts.workers
+	if workers > count {
+		workers = count
+	}
+
+	if workers < 2 {
+		fn(0, count)
+		return
+	}
+
+	step := (count + workers - 1) / workers
+
+	var wg sync.WaitGroup
+	for begin := 0; begin < count; begin += step {
+		end := begin + step
+		if end > count {
+			end = count
+		}
+
+		wg.Add(1)
+		go func(begin, end int) {
+			defer wg.Done()
+			fn(begin, end)
+		}(begin, end)
+	}
+	wg.Wait()
+}
+
+// Locations appends absolute bit positions of key to dst.
+// Positions of slice i are in range [i*BitsPerSlice(), (i+1)*BitsPerSlice()).
+func (bf *BloomFilter) Locations(dst []uint64, key []byte) []uint64 {
+	sc := scratchPool.Get().(*scratch)
+	defer scratchPool.Put(sc)
+
+	return bf.locations(dst, key, sc)
+}
+
+// SetLocations sets bits by absolute positions and adds count to number of keys.
+// It is used by structures which use the same hashing but keep own data, e.g. counting filter.
+// Error is returned for read-only filter or position out of filter, nothing is changed then.
+func (bf *BloomFilter) SetLocations(locations []uint64, count int64) error {
+
+	if bf.readOnly() {
+		return readOnlyError
+	}
+
+	for _, i := range locations {
+		if i >= bf.numBits {
+			return fmt.Errorf("location %d is out of filter with %d bits", i, bf.numBits)
+		}
+	}
+
+	for _, i := range locations {
+		bf.bitarray.Set(i)
+	}
+	atomic.AddInt64(&bf.count, count)
+
+	return nil
+}
+
+// locations appends absolute bit positions of key to dst.
+func (bf *BloomFilter) locations(dst []uint64, key []byte, sc *scratch) []uint64 {
+	offset := uint64(0)
+	hashes := bf.makeSaltIterator(key, sc)
+	k, find := hashes.next()
+	for find {
+		dst = append(dst, offset+k)
+		offset += bf.bitsPerSlice
+		k, find = hashes.next()
+	}
+	return dst
+}
+
+// addLocations sets precalculated bits. It works as Add without skipCheck.
+func (bf *BloomFilter) addLocations(locations []uint64) (bool, error) {
+
+	if bf.readOnly() {
+		return false, readOnlyError
+	}
+
+	if atomic.LoadInt64(&bf.count) > bf.capacity && bf.opts.overflow == OverflowError {
+		return false, capacityError
+	}
+
+	foundAllBits := true
+	for _, i := range locations {
+		if foundAllBits && !bf.bitarray.Get(i) {
+			foundAllBits = false
+		}
+		bf.bitarray.Set(i)
+	}
+
+	if !foundAllBits {
+		atomic.AddInt64(&bf.count, 1)
+		return false, nil
+	}
+
+	return true, nil
+}
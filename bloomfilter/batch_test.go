@@ -0,0 +1,69 @@
+package bloomfilter
+
+import (
+	"github.com/iostrovok/go-bloom-filter/bloomfilter/fortesting"
+	. "gopkg.in/check.v1"
+)
+
+func (s *filterTestSuite) TestAddManyCheckMany(c *C) {
+
+	keys := benchKeys()
+	keys = append(keys, keys[:100]...)
+
+	for _, workers := range []int{0, 1, 4} {
+		filter, err := NewWithOptions(int64(len(keys)), WithErrorRate(0.0001), WithWorkers(workers))
+		c.Assert(err, IsNil)
+
+		added, err := filter.AddMany(keys)
+		c.Assert(err, IsNil)
+		c.Assert(added, Equals, len(keys)-100)
+		c.Assert(filter.Count(), Equals, int64(len(keys)-100))
+
+		missed := make([][]byte, 0, 2*len(keys))
+		for _, s := range fortesting.ArrayForTesting() {
+			missed = append(missed, []byte(s+"eee-m"), []byte(s))
+		}
+
+		res := filter.CheckMany(missed)
+		c.Assert(len(res), Equals, len(missed))
+		for i := range missed {
+			c.Assert(res[i], Equals, i%2 == 1)
+		}
+	}
+}
+
+func (s *filterTestSuite) TestSetLocations(c *C) {
+
+	filter, err := New(1000, 0.01)
+	c.Assert(err, IsNil)
+
+	locations := filter.Locations(nil, []byte("key"))
+	c.Assert(len(locations), Equals, filter.NumSlices())
+	c.Assert(filter.SetLocations(locations, 1), IsNil)
+	c.Assert(filter.Check([]byte("key")), Equals, true)
+	c.Assert(filter.Count(), Equals, int64(1))
+
+	// nothing is set if some position is out of filter
+	c.Assert(filter.SetLocations([]uint64{1, filter.numBits}, 1), NotNil)
+	c.Assert(filter.bitarray.Get(1), Equals, false)
+	c.Assert(filter.Count(), Equals, int64(1))
+}
+
+func (s *filterTestSuite) TestAddManyCapacity(c *C) {
+
+	keys := benchKeys()
+
+	for _, workers := range []int{1, 3} {
+		filter, err := NewWithOptions(100, WithErrorRate(0.0001), WithWorkers(workers))
+		c.Assert(err, IsNil)
+
+		added, err := filter.AddMany(keys)
+		c.Assert(err, NotNil)
+		c.Assert(added, Equals, 101)
+		c.Assert(filter.Count(), Equals, int64(101))
+	}
+
+	filter, err := NewWithOptions(100, WithWorkers(-1))
+	c.Assert(err, NotNil)
+	c.Assert(filter, IsNil)
+}
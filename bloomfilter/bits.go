@@ -0,0 +1,251 @@
+package bloomfilter
+
+import (
+	"bufio"
+	"bytes"
+	"fmt"
+
+	"github.com/iostrovok/go-bloom-filter/bloomfilter/array"
+)
+
+// BitStore keeps bits of bloom filter. array.Array is the default store, array.Atomic is used
+// by ConcurrencyAtomic. Other stores are set by WithStore, e.g. a store of remote process.
+// Set has no error result, so store which may fail keeps error itself.
+type BitStore interface {
+	// Set sets i-th bit
+	Set(i uint64)
+	// Get returns true if i-th bit is set
+	Get(i uint64) bool
+	// Len returns number of bits
+	Len() uint64
+	// MergeBytes sets bits which are set in data. Bit i is bit i%8 of byte i/8
+	MergeBytes(data []byte) error
+	// ToBytes writes all bits in format of MergeBytes
+	ToBytes(binBuf *bytes.Buffer) error
+	// Read replaces all bits by bytes in format of MergeBytes. All bytes are read if length is 0
+	Read(reader *bufio.Reader, length int64) error
+}
+
+// StoreFactory creates store for bits of new filter. See WithStore.
+// Every call must return new store with own bits, e.g. inner filters of scalable filter call it one by one.
+type StoreFactory func(length uint64) (BitStore, error)
+
+// bitCounter is a store which counts set bits itself.
+type bitCounter interface {
+	PopCount() uint64
+	PopCountRange(begin, end uint64) uint64
+}
+
+// bitResetter is a store which clears bits itself.
+type bitResetter interface {
+	Reset()
+}
+
+// bitAnder is a store which clears bits by outside bytes itself.
+type bitAnder interface {
+	AndBytes(data []byte) uint64
+	AndNotBytes(data []byte) uint64
+}
+
+// newBitArray creates store for concurrency and storage modes or by factory of options.
+func newBitArray(o options, length uint64) (BitStore, error) {
+
+	if o.store != nil {
+		b, err := o.store(length)
+		if err != nil {
+			return nil, err
+		}
+		if b.Len() != length {
+			return nil, fmt.Errorf("wrong length of store: %d != %d", b.Len(), length)
+		}
+		return b, nil
+	}
+
+	switch o.concurrency {
+	case ConcurrencyAtomic:
+		return array.NewAtomic(length), nil
+	case ConcurrencyStriped:
+		return array.NewStriped(length, o.stripes), nil
+	}
+
+	var out *array.Array
+	if o.storage == StorageSparse {
+		out = array.NewSparse(length)
+	} else {
+		out = array.New(length)
+	}
+
+	if o.concurrency == ConcurrencyNone {
+		out.SetUnlocked()
+	}
+	return out, nil
+}
+
+// arrayBytes returns byte image of array.
+func arrayBytes(b BitStore) []byte {
+	binBuf := bytes.NewBuffer([]byte{})
+	b.ToBytes(binBuf)
+	return binBuf.Bytes()
+}
+
+// mergeBits adds bits of src into dst.
+func mergeBits(dst, src BitStore) error {
+	switch d := dst.(type) {
+	case *array.Array:
+		if s, ok := src.(*array.Array); ok {
+			return d.Merge(s)
+		}
+	case *array.Atomic:
+		if s, ok := src.(*array.Atomic); ok {
+			return d.Merge(s)
+		}
+	}
+	return dst.MergeBytes(arrayBytes(src))
+}
+
+// andBits keeps in dst only bits which are set in src. Returns number of set bits.
+func andBits(dst, src BitStore) uint64 {
+	switch d := dst.(type) {
+	case *array.Array:
+		if s, ok := src.(*array.Array); ok {
+			return d.And(s)
+		}
+	case *array.Atomic:
+		if s, ok := src.(*array.Atomic); ok {
+			return d.And(s)
+		}
+	}
+	if d, ok := dst.(bitAnder); ok {
+		return d.AndBytes(arrayBytes(src))
+	}
+	out := denseCopy(dst)
+	out.AndBytes(arrayBytes(src))
+	return replaceBits(dst, out)
+}
+
+// andNotBits keeps in dst only bits which are not set in src. Returns number of set bits.
+func andNotBits(dst, src BitStore) uint64 {
+	switch d := dst.(type) {
+	case *array.Array:
+		if s, ok := src.(*array.Array); ok {
+			return d.AndNot(s)
+		}
+	case *array.Atomic:
+		if s, ok := src.(*array.Atomic); ok {
+			return d.AndNot(s)
+		}
+	}
+	if d, ok := dst.(bitAnder); ok {
+		return d.AndNotBytes(arrayBytes(src))
+	}
+	out := denseCopy(dst)
+	out.AndNotBytes(arrayBytes(src))
+	return replaceBits(dst, out)
+}
+
+// cloneBits returns independent copy of array.
+func cloneBits(b BitStore) BitStore {
+	switch a := b.(type) {
+	case *array.Array:
+		return a.Clone()
+	case *array.Atomic:
+		return a.Clone()
+	}
+
+	return denseCopy(b)
+}
+
+// denseCopy returns copy of store in array.Array.
+func denseCopy(b BitStore) *array.Array {
+	out := array.New(b.Len())
+	out.MergeBytes(arrayBytes(b))
+	return out
+}
+
+// replaceBits sets bits of src into dst by Read. Returns number of set bits.
+func replaceBits(dst BitStore, src *array.Array) uint64 {
+	dst.Read(bufio.NewReader(bytes.NewReader(arrayBytes(src))), 0)
+	return src.PopCount()
+}
+
+// orPopCounter returns function which counts set bits of a | b in range.
+// Arrays of the same type are not copied.
+func orPopCounter(a, b BitStore) func(begin, end uint64) uint64 {
+	switch x := a.(type) {
+	case *array.Array:
+		if y, ok := b.(*array.Array); ok {
+			return func(begin, end uint64) uint64 { return x.OrPopCountRange(y, begin, end) }
+		}
+	case *array.Atomic:
+		if y, ok := b.(*array.Atomic); ok {
+			return func(begin, end uint64) uint64 { return x.OrPopCountRange(y, begin, end) }
+		}
+	}
+
+	union := denseCopy(a)
+	union.MergeBytes(arrayBytes(b))
+	return union.PopCountRange
+}
+
+// xorPopCounter returns function which counts set bits of a ^ b in range.
+// Arrays of the same type are not copied.
+func xorPopCounter(a, b BitStore) func(begin, end uint64) uint64 {
+	switch x := a.(type) {
+	case *array.Array:
+		if y, ok := b.(*array.Array); ok {
+			return func(begin, end uint64) uint64 { return x.XorPopCountRange(y, begin, end) }
+		}
+	case *array.Atomic:
+		if y, ok := b.(*array.Atomic); ok {
+			return func(begin, end uint64) uint64 { return x.XorPopCountRange(y, begin, end) }
+		}
+	}
+
+	diff := denseCopy(a)
+	diff.XorBytes(arrayBytes(b))
+	return diff.PopCountRange
+}
+
+// xorPositions returns not more than limit first positions which are set in one of stores only.
+// Arrays of the same type are not copied.
+func xorPositions(a, b BitStore, limit int) []uint64 {
+	switch x := a.(type) {
+	case *array.Array:
+		if y, ok := b.(*array.Array); ok {
+			return x.XorPositions(y, limit)
+		}
+	case *array.Atomic:
+		if y, ok := b.(*array.Atomic); ok {
+			return x.XorPositions(y, limit)
+		}
+	}
+
+	diff := denseCopy(a)
+	diff.XorBytes(arrayBytes(b))
+	return diff.XorPositions(array.New(diff.Len()), limit)
+}
+
+// counterOf returns store which counts set bits. It is a copy for store without PopCount.
+func counterOf(b BitStore) bitCounter {
+	if c, ok := b.(bitCounter); ok {
+		return c
+	}
+	return denseCopy(b)
+}
+
+// resetBits clears all bits of store.
+func resetBits(b BitStore) {
+	if r, ok := b.(bitResetter); ok {
+		r.Reset()
+		return
+	}
+	replaceBits(b, array.New(b.Len()))
+}
+
+// compareBits checks characteristics of arrays
+func compareBits(a, b BitStore) error {
+	if a.Len() != b.Len() {
+		return fmt.Errorf("Wrong length for Array: %d != %d", a.Len(), b.Len())
+	}
+	return nil
+}
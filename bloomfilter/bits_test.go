@@ -0,0 +1,254 @@
+package bloomfilter
+
+import (
+	"bufio"
+	"bytes"
+	"fmt"
+	"io"
+	"sync"
+
+	"github.com/iostrovok/go-bloom-filter/bloomfilter/array"
+	"github.com/iostrovok/go-bloom-filter/bloomfilter/fortesting"
+	. "gopkg.in/check.v1"
+)
+
+func (s *filterTestSuite) TestConcurrencyAtomic(c *C) {
+
+	testArray := fortesting.ArrayForTesting()
+
+	filterA, err := NewWithOptions(int64(len(testArray)), WithErrorRate(0.001), WithConcurrency(ConcurrencyAtomic))
+	c.Assert(err, IsNil)
+
+	filterB, err := New(int64(len(testArray)), 0.001)
+	c.Assert(err, IsNil)
+
+	var wg sync.WaitGroup
+	for g := 0; g < 4; g++ {
+		wg.Add(1)
+		go func(g int) {
+			defer wg.Done()
+			for i := g; i < len(testArray); i += 4 {
+				filterA.Add([]byte(testArray[i]))
+				filterA.Check([]byte(testArray[i]))
+			}
+		}(g)
+	}
+	wg.Wait()
+
+	for _, s := range testArray {
+		_, err := filterB.Add([]byte(s))
+		c.Assert(err, IsNil)
+		c.Assert(filterA.Check([]byte(s)), Equals, true)
+	}
+
+	// the same bytes as locked array
+	bufA := bytes.NewBuffer([]byte{})
+	c.Assert(filterA.ToBytes(bufA), IsNil)
+	bufB := bytes.NewBuffer([]byte{})
+	c.Assert(filterB.ToBytes(bufB), IsNil)
+	c.Assert(bufA.Len(), Equals, bufB.Len())
+	c.Assert(bufA.Bytes()[40:], DeepEquals, bufB.Bytes()[40:])
+
+	filterNew, err := FromReader(bufio.NewReader(bufA), 0)
+	c.Assert(err, IsNil)
+	c.Assert(filterNew.EstimateCardinality(), Equals, filterA.EstimateCardinality())
+
+	// filters with different arrays are merged
+	filterC, err := NewWithOptions(int64(len(testArray)), WithErrorRate(0.001), WithConcurrency(ConcurrencyAtomic))
+	c.Assert(err, IsNil)
+	c.Assert(filterC.Merge(filterB), IsNil)
+	c.Assert(filterB.Merge(filterA), IsNil)
+
+	union, err := Union(filterC, filterA)
+	c.Assert(err, IsNil)
+	intersect, err := Intersect(filterB, filterC)
+	c.Assert(err, IsNil)
+
+	for _, s := range testArray {
+		c.Assert(filterC.Check([]byte(s)), Equals, true)
+		c.Assert(union.Check([]byte(s)), Equals, true)
+		c.Assert(intersect.Check([]byte(s)), Equals, true)
+	}
+}
+
+func (s *filterTestSuite) TestStorageSparse(c *C) {
+
+	testArray := fortesting.ArrayForTesting()
+
+	sparse, err := NewWithOptions(100*1000*1000, WithStorage(StorageSparse))
+	c.Assert(err, IsNil)
+	dense, err := NewWithOptions(100 * 1000 * 1000)
+	c.Assert(err, IsNil)
+
+	for _, s := range testArray {
+		_, err := sparse.Add([]byte(s))
+		c.Assert(err, IsNil)
+		_, err = dense.Add([]byte(s))
+		c.Assert(err, IsNil)
+	}
+
+	c.Assert(sparse.bitarray.(*array.Array).Sparse(), Equals, true)
+	c.Assert(sparse.EstimateCardinality(), Equals, dense.EstimateCardinality())
+
+	for _, s := range testArray {
+		c.Assert(sparse.Check([]byte(s)), Equals, true)
+	}
+
+	bufS := bytes.NewBuffer([]byte{})
+	c.Assert(sparse.ToBytes(bufS), IsNil)
+	bufD := bytes.NewBuffer([]byte{})
+	c.Assert(dense.ToBytes(bufD), IsNil)
+	c.Assert(bytes.Equal(bufS.Bytes(), bufD.Bytes()), Equals, true)
+
+	_, err = NewWithOptions(100, WithStorage(StorageMode(100)))
+	c.Assert(err, NotNil)
+}
+
+func (s *filterTestSuite) TestClear(c *C) {
+
+	for _, opts := range [][]Option{{}, {WithConcurrency(ConcurrencyAtomic)}, {WithStorage(StorageSparse)}} {
+		filter, err := NewWithOptions(1000, opts...)
+		c.Assert(err, IsNil)
+
+		for _, s := range fortesting.ArrayForTesting()[:1000] {
+			_, err := filter.Add([]byte(s))
+			c.Assert(err, IsNil)
+		}
+		c.Assert(filter.FillRatio() > 0, Equals, true)
+
+		c.Assert(filter.Clear(), IsNil)
+		c.Assert(filter.Count(), Equals, int64(0))
+		c.Assert(filter.FillRatio(), Equals, float64(0))
+
+		for _, s := range fortesting.ArrayForTesting()[:1000] {
+			c.Assert(filter.Check([]byte(s)), Equals, false)
+		}
+	}
+}
+
+// mapStore is a BitStore which keeps only set bits
+type mapStore struct {
+	mc     sync.RWMutex
+	bits   map[uint64]bool
+	length uint64
+}
+
+func newMapStore(length uint64) (BitStore, error) {
+	return &mapStore{bits: map[uint64]bool{}, length: length}, nil
+}
+
+func (m *mapStore) Set(i uint64) {
+	m.mc.Lock()
+	defer m.mc.Unlock()
+	m.bits[i] = true
+}
+
+func (m *mapStore) Get(i uint64) bool {
+	m.mc.RLock()
+	defer m.mc.RUnlock()
+	return m.bits[i]
+}
+
+func (m *mapStore) Len() uint64 {
+	return m.length
+}
+
+func (m *mapStore) MergeBytes(data []byte) error {
+	for j, v := range data {
+		for k := uint64(0); k < 8; k++ {
+			if v&(1<<k) != 0 {
+				m.Set(uint64(j)*8 + k)
+			}
+		}
+	}
+	return nil
+}
+
+func (m *mapStore) ToBytes(binBuf *bytes.Buffer) error {
+	m.mc.RLock()
+	defer m.mc.RUnlock()
+
+	data := make([]byte, (m.length+7)/8)
+	for i := range m.bits {
+		data[i/8] |= 1 << (i % 8)
+	}
+	_, err := binBuf.Write(data)
+	return err
+}
+
+func (m *mapStore) Read(reader *bufio.Reader, length int64) error {
+	data := make([]byte, (m.length+7)/8)
+	io.ReadFull(reader, data)
+
+	m.mc.Lock()
+	m.bits = map[uint64]bool{}
+	m.mc.Unlock()
+
+	return m.MergeBytes(data)
+}
+
+func (s *filterTestSuite) TestWithStore(c *C) {
+
+	testArray := fortesting.ArrayForTesting()
+	half := len(testArray) / 2
+
+	filter, err := NewWithOptions(int64(len(testArray)), WithStore(newMapStore))
+	c.Assert(err, IsNil)
+	_, ok := filter.bitarray.(*mapStore)
+	c.Assert(ok, Equals, true)
+
+	plain, err := New(int64(len(testArray)))
+	c.Assert(err, IsNil)
+
+	for _, s := range testArray[:half] {
+		_, err := filter.Add([]byte(s))
+		c.Assert(err, IsNil)
+		_, err = plain.Add([]byte(s))
+		c.Assert(err, IsNil)
+	}
+	for _, s := range testArray[:half] {
+		c.Assert(filter.Check([]byte(s)), Equals, true)
+	}
+
+	bufA := bytes.NewBuffer([]byte{})
+	c.Assert(filter.ToBytes(bufA), IsNil)
+	bufB := bytes.NewBuffer([]byte{})
+	c.Assert(plain.ToBytes(bufB), IsNil)
+	c.Assert(bufA.Bytes(), DeepEquals, bufB.Bytes())
+
+	c.Assert(filter.FillRatio(), Equals, plain.FillRatio())
+	c.Assert(filter.EstimateCardinality(), Equals, plain.EstimateCardinality())
+
+	other, err := New(int64(len(testArray)))
+	c.Assert(err, IsNil)
+	for _, s := range testArray[half-100:] {
+		_, err := other.Add([]byte(s))
+		c.Assert(err, IsNil)
+	}
+
+	c.Assert(filter.Merge(other), IsNil)
+	c.Assert(plain.Merge(other), IsNil)
+	c.Assert(filter.FillRatio(), Equals, plain.FillRatio())
+
+	intersection, err := Intersect(other, filter)
+	c.Assert(err, IsNil)
+	c.Assert(intersection.Check([]byte(testArray[half])), Equals, true)
+
+	// bits of store are changed by Read
+	c.Assert(andBits(filter.bitarray, other.bitarray), Equals, counterOf(other.bitarray).PopCount())
+	c.Assert(filter.FillRatio(), Equals, other.FillRatio())
+
+	c.Assert(filter.Clear(), IsNil)
+	c.Assert(filter.FillRatio(), Equals, float64(0))
+	c.Assert(filter.Count(), Equals, int64(0))
+
+	_, err = NewWithOptions(100, WithStore(func(length uint64) (BitStore, error) {
+		return nil, fmt.Errorf("no store")
+	}))
+	c.Assert(err, ErrorMatches, "no store")
+
+	_, err = NewWithOptions(100, WithStore(func(length uint64) (BitStore, error) {
+		return newMapStore(length + 1)
+	}))
+	c.Assert(err, NotNil)
+}
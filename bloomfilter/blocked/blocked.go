@@ -0,0 +1,255 @@
+package blocked
+
+import (
+	"encoding/binary"
+	"fmt"
+	"math"
+	"math/bits"
+	"sync/atomic"
+
+	"github.com/iostrovok/go-bloom-filter/bloomfilter"
+	"github.com/iostrovok/go-bloom-filter/bloomfilter/array"
+)
+
+/*
+	Blocked bloom filter maps every key to one block of 512 bits (one cache line)
+	and sets all bits of key inside this block. So Add and Check cost one cache miss
+	instead of numSlices misses of bloomfilter.BloomFilter.
+
+	The price is a higher false positive rate for the same memory: keys are not spread
+	evenly between blocks, so some blocks are overfilled. For example (m/n is bits per key,
+	k is number of hash functions):
+
+		m/n   k   BloomFilter   blocked
+		8     6   2.2%          2.3%
+		10    7   0.82%         0.96%
+		16    11  0.046%        0.086%
+		20    14  0.0067%       0.022%
+
+	The gap grows with k. New keeps the requested error rate, so it takes more memory than BloomFilter:
+	about 5% for 0.01, 10% for 0.001 and 16% for 0.0001. FalsePositiveRate calculates the rate for any sizes.
+*/
+
+// BlockBits is number of bits in one block.
+const BlockBits = 512
+
+// MaxHashes is the maximum number of bits which are set for one key.
+const MaxHashes = 16
+
+var capacityError error
+
+func init() {
+	capacityError = fmt.Errorf("BloomFilter is at capacity")
+}
+
+// Filter is a structure for blocked bloom filter.
+type Filter struct {
+	errorRate float64
+	numHashes int
+	numBlocks uint64
+	capacity  int64
+	count     int64
+	hasher    bloomfilter.Hasher
+
+	bits *array.Atomic
+}
+
+// New is constructor. It checks parameters and creates new blocked bloom filter with Murmur3 hasher.
+func New(capacity int64, errorRates ...float64) (*Filter, error) {
+
+	errorRate := 0.001
+	if len(errorRates) > 0 {
+		errorRate = errorRates[0]
+	}
+
+	return NewWithHasher(capacity, errorRate, bloomfilter.Murmur3)
+}
+
+// NewWithHasher is constructor with hash function.
+// Register it by bloomfilter.RegisterHasher if filter will be loaded from file.
+func NewWithHasher(capacity int64, errorRate float64, hasher bloomfilter.Hasher) (*Filter, error) {
+
+	// error rate 1 gives no hash functions and no blocks
+	if errorRate <= 0 || 1.0 <= errorRate {
+		return nil, fmt.Errorf("error Rate must be between 0 and 1")
+	}
+
+	if capacity < 1 {
+		return nil, fmt.Errorf("capacity must be > 0")
+	}
+
+	if hasher.Size() < 8 {
+		return nil, fmt.Errorf("hasher %s: size must be >= 8", hasher.Name())
+	}
+
+	numHashes, numBlocks := sizes(capacity, errorRate)
+	return newFilter(errorRate, numHashes, numBlocks, capacity, hasher), nil
+}
+
+func newFilter(errorRate float64, numHashes int, numBlocks uint64, capacity int64, hasher bloomfilter.Hasher) *Filter {
+	return &Filter{
+		errorRate: errorRate,
+		numHashes: numHashes,
+		numBlocks: numBlocks,
+		capacity:  capacity,
+		hasher:    hasher,
+		bits:      array.NewAtomic(numBlocks * BlockBits),
+	}
+}
+
+// sizes returns number of hash functions and number of blocks which keep error rate for capacity.
+func sizes(capacity int64, errorRate float64) (int, uint64) {
+
+	numHashes := int(math.Ceil(math.Log2(1.0 / errorRate)))
+	if numHashes > MaxHashes {
+		numHashes = MaxHashes
+	}
+
+	// size of classic filter is the first try
+	numBits := math.Ceil(float64(capacity) * math.Abs(math.Log(errorRate)) / (math.Ln2 * math.Ln2))
+	numBlocks := uint64(math.Ceil(numBits / BlockBits))
+
+	for FalsePositiveRate(capacity, numBlocks, numHashes) > errorRate {
+		numBlocks += numBlocks/20 + 1
+	}
+
+	return numHashes, numBlocks
+}
+
+// FalsePositiveRate returns expected false positive rate of filter with numBlocks blocks
+// and numHashes hash functions after capacity keys are added.
+// Number of keys in block has Poisson distribution, so rate is a sum of rates of blocks with j keys.
+func FalsePositiveRate(capacity int64, numBlocks uint64, numHashes int) float64 {
+
+	lambda := float64(capacity) / float64(numBlocks)
+	last := int(lambda + 10*math.Sqrt(lambda) + 20)
+
+	res := 0.0
+	p := math.Exp(-lambda)
+	for j := 0; j <= last; j++ {
+		fill := 1.0 - math.Pow(1.0-1.0/BlockBits, float64(numHashes*j))
+		res += p * math.Pow(fill, float64(numHashes))
+		p *= lambda / float64(j+1)
+	}
+
+	return res
+}
+
+// locations returns first bit of block and two hashes for positions inside block.
+func (f *Filter) locations(key []byte) (uint64, uint64, uint64) {
+	var buf [64]byte
+	sum := f.hasher.Sum(buf[:0], key)
+
+	h1 := binary.LittleEndian.Uint64(sum)
+	h2 := mix64(h1)
+	if len(sum) >= 16 {
+		h2 = binary.LittleEndian.Uint64(sum[8:])
+	}
+
+	block, _ := bits.Mul64(h1, f.numBlocks)
+
+	// odd step makes all positions different
+	return block * BlockBits, h2 % BlockBits, (h2>>9)%BlockBits | 1
+}
+
+// mix64 is finalizer of SplitMix64, it makes second hash for 64 bits hashers
+func mix64(h uint64) uint64 {
+	h = (h ^ (h >> 30)) * 0xbf58476d1ce4e5b9
+	h = (h ^ (h >> 27)) * 0x94d049bb133111eb
+	return h ^ (h >> 31)
+}
+
+// Add new key. Returns true/false for key and error.
+func (f *Filter) Add(key []byte, skipChecks ...bool) (bool, error) {
+
+	if atomic.LoadInt64(&f.count) > f.capacity {
+		return false, capacityError
+	}
+
+	skipCheck := false
+	if len(skipChecks) > 0 {
+		skipCheck = skipChecks[0]
+	}
+
+	begin, pos, step := f.locations(key)
+
+	foundAllBits := true
+	for i := 0; i < f.numHashes; i++ {
+		if !skipCheck && foundAllBits && !f.bits.Get(begin+pos) {
+			foundAllBits = false
+		}
+		f.bits.Set(begin + pos)
+		pos = (pos + step) % BlockBits
+	}
+
+	if skipCheck || !foundAllBits {
+		atomic.AddInt64(&f.count, 1)
+		return false, nil
+	}
+
+	return true, nil
+}
+
+// Check key. Returns true/false
+func (f *Filter) Check(key []byte) bool {
+
+	begin, pos, step := f.locations(key)
+
+	for i := 0; i < f.numHashes; i++ {
+		if !f.bits.Get(begin + pos) {
+			return false
+		}
+		pos = (pos + step) % BlockBits
+	}
+
+	return true
+}
+
+// Merge adds keys of other filter. Filters must have the same parameters.
+// Count is not changed.
+func (f *Filter) Merge(other *Filter) error {
+
+	if f.numBlocks != other.numBlocks {
+		return fmt.Errorf("Wrong length for numBlocks: %d != %d", f.numBlocks, other.numBlocks)
+	}
+
+	if f.numHashes != other.numHashes {
+		return fmt.Errorf("Wrong length for numHashes: %d != %d", f.numHashes, other.numHashes)
+	}
+
+	if f.hasher.Name() != other.hasher.Name() {
+		return fmt.Errorf("Wrong hash function: %s != %s", f.hasher.Name(), other.hasher.Name())
+	}
+
+	return f.bits.Merge(other.bits)
+}
+
+// Count is a "getter". Returns number of added keys.
+func (f *Filter) Count() int64 {
+	return atomic.LoadInt64(&f.count)
+}
+
+// Capacity is a "getter". Returns capacity of filter.
+func (f *Filter) Capacity() int64 {
+	return f.capacity
+}
+
+// ErrorRate is a "getter". Returns expected false positive rate for full filter.
+func (f *Filter) ErrorRate() float64 {
+	return f.errorRate
+}
+
+// NumHashes is a "getter". Returns number of bits which are set for one key.
+func (f *Filter) NumHashes() int {
+	return f.numHashes
+}
+
+// NumBlocks is a "getter". Returns number of 512 bits blocks.
+func (f *Filter) NumBlocks() uint64 {
+	return f.numBlocks
+}
+
+// FillRatio returns part of set bits in filter.
+func (f *Filter) FillRatio() float64 {
+	return float64(f.bits.PopCount()) / float64(f.numBlocks*BlockBits)
+}
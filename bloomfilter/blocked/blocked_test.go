@@ -0,0 +1,193 @@
+package blocked
+
+import (
+	"bufio"
+	"bytes"
+	"fmt"
+	"path/filepath"
+	"testing"
+
+	"github.com/iostrovok/go-bloom-filter/bloomfilter"
+	"github.com/iostrovok/go-bloom-filter/bloomfilter/fortesting"
+	. "gopkg.in/check.v1"
+)
+
+type blockTestSuite struct{}
+
+// Hook up gocheck into the "go test" runner.
+func Test(t *testing.T) { TestingT(t) }
+
+var _ = Suite(&blockTestSuite{})
+
+func (s *blockTestSuite) TestNew(c *C) {
+
+	filter, err := New(0, .001)
+	c.Assert(err, NotNil)
+	c.Assert(filter, IsNil)
+
+	filter, err = New(100, 0)
+	c.Assert(err, NotNil)
+	c.Assert(filter, IsNil)
+
+	filter, err = New(100, 1)
+	c.Assert(err, NotNil)
+	c.Assert(filter, IsNil)
+
+	// the biggest error rate still makes filter which is saved and read back
+	filter, err = New(100, 0.99)
+	c.Assert(err, IsNil)
+	c.Assert(filter.NumHashes() >= 1, Equals, true)
+	c.Assert(filter.NumBlocks() >= 1, Equals, true)
+	c.Assert(filter.Check([]byte("key")), Equals, false)
+	binBuf := bytes.NewBuffer([]byte{})
+	c.Assert(filter.ToBytes(binBuf), IsNil)
+	_, err = FromReader(bufio.NewReader(bytes.NewReader(binBuf.Bytes())))
+	c.Assert(err, IsNil)
+
+	filter, err = New(100)
+	c.Assert(err, IsNil)
+	c.Assert(filter.ErrorRate(), Equals, 0.001)
+	c.Assert(filter.Capacity(), Equals, int64(100))
+	c.Assert(filter.NumHashes(), Equals, 10)
+	c.Assert(FalsePositiveRate(100, filter.NumBlocks(), filter.NumHashes()) <= 0.001, Equals, true)
+
+	filter, err = New(1000, 1e-12)
+	c.Assert(err, IsNil)
+	c.Assert(filter.NumHashes(), Equals, MaxHashes)
+}
+
+func (s *blockTestSuite) TestAddCheck(c *C) {
+
+	testArray := fortesting.ArrayForTesting()
+
+	for _, hasher := range []bloomfilter.Hasher{bloomfilter.Murmur3, bloomfilter.XXHash64, bloomfilter.SHA1} {
+		filter, err := NewWithHasher(int64(len(testArray)), 0.001, hasher)
+		c.Assert(err, IsNil)
+
+		for _, s := range testArray {
+			res, err := filter.Add([]byte(s))
+			c.Assert(err, IsNil)
+			c.Assert(res, Equals, false)
+		}
+		c.Assert(filter.Count(), Equals, int64(len(testArray)))
+
+		for _, s := range testArray {
+			c.Assert(filter.Check([]byte(s)), Equals, true)
+			res, err := filter.Add([]byte(s))
+			c.Assert(err, IsNil)
+			c.Assert(res, Equals, true)
+		}
+		c.Assert(filter.FillRatio() > 0, Equals, true)
+
+		// over capacity
+		for i := 0; i < 10; i++ {
+			filter.Add([]byte(fmt.Sprintf("over-%d", i)), true)
+		}
+		_, err = filter.Add([]byte("over"))
+		c.Assert(err, NotNil)
+	}
+}
+
+func (s *blockTestSuite) TestFalsePositiveRate(c *C) {
+
+	capacity := 100000
+	filter, err := New(int64(capacity), 0.01)
+	c.Assert(err, IsNil)
+
+	for i := 0; i < capacity; i++ {
+		filter.Add([]byte(fmt.Sprintf("key-%d", i)))
+	}
+
+	found := 0
+	for i := 0; i < 10*capacity; i++ {
+		if filter.Check([]byte(fmt.Sprintf("unknown-%d", i))) {
+			found++
+		}
+	}
+
+	rate := float64(found) / float64(10*capacity)
+	c.Assert(rate < 0.012, Equals, true, Commentf("rate: %f", rate))
+	c.Assert(rate > 0.005, Equals, true, Commentf("rate: %f", rate))
+}
+
+func (s *blockTestSuite) TestMergeToBytes(c *C) {
+
+	testArray := fortesting.ArrayForTesting()
+	half := len(testArray) / 2
+
+	filterA, err := New(int64(len(testArray)))
+	c.Assert(err, IsNil)
+	filterB, err := New(int64(len(testArray)))
+	c.Assert(err, IsNil)
+
+	for _, s := range testArray[:half] {
+		_, err := filterA.Add([]byte(s))
+		c.Assert(err, IsNil)
+	}
+	for _, s := range testArray[half:] {
+		_, err := filterB.Add([]byte(s))
+		c.Assert(err, IsNil)
+	}
+
+	c.Assert(filterA.Merge(filterB), IsNil)
+	c.Assert(filterA.Count(), Equals, int64(half))
+	for _, s := range testArray {
+		c.Assert(filterA.Check([]byte(s)), Equals, true)
+	}
+
+	other, err := New(int64(len(testArray)), 0.01)
+	c.Assert(err, IsNil)
+	c.Assert(filterA.Merge(other), NotNil)
+
+	other, err = NewWithHasher(int64(len(testArray)), 0.001, bloomfilter.XXHash64)
+	c.Assert(err, IsNil)
+	c.Assert(filterA.Merge(other), NotNil)
+
+	binBuf := bytes.NewBuffer([]byte{})
+	c.Assert(filterA.ToBytes(binBuf), IsNil)
+	c.Assert(binBuf.Len() > int(filterA.NumBlocks()*BlockBits/8), Equals, true)
+
+	loaded, err := FromReader(bufio.NewReader(bytes.NewReader(binBuf.Bytes())))
+	c.Assert(err, IsNil)
+	c.Assert(loaded.Count(), Equals, filterA.Count())
+	c.Assert(loaded.NumBlocks(), Equals, filterA.NumBlocks())
+	c.Assert(loaded.NumHashes(), Equals, filterA.NumHashes())
+	c.Assert(loaded.ErrorRate(), Equals, filterA.ErrorRate())
+	for _, s := range testArray {
+		c.Assert(loaded.Check([]byte(s)), Equals, true)
+	}
+
+	fileName := filepath.Join(c.MkDir(), "blocked.bf")
+	c.Assert(filterA.ToFile(fileName), IsNil)
+	loaded, err = FromFile(fileName)
+	c.Assert(err, IsNil)
+	for _, s := range testArray {
+		c.Assert(loaded.Check([]byte(s)), Equals, true)
+	}
+
+	_, err = FromReader(bufio.NewReader(bytes.NewReader(binBuf.Bytes()[1:])))
+	c.Assert(err, NotNil)
+}
+
+func benchmarkCheck(b *testing.B, filter interface{ Check([]byte) bool }) {
+	keys := make([][]byte, 1024)
+	for i := range keys {
+		keys[i] = []byte(fmt.Sprintf("key-%d", i))
+	}
+
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		filter.Check(keys[i%len(keys)])
+	}
+}
+
+func BenchmarkCheckBlocked(b *testing.B) {
+	filter, _ := New(10*1000*1000, 0.001)
+	benchmarkCheck(b, filter)
+}
+
+func BenchmarkCheckBloomFilter(b *testing.B) {
+	filter, _ := bloomfilter.NewWithOptions(10*1000*1000, bloomfilter.WithHashStrategy(bloomfilter.HashMurmur3),
+		bloomfilter.WithIndexMode(bloomfilter.IndexDoubleHashing))
+	benchmarkCheck(b, filter)
+}
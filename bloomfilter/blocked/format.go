@@ -0,0 +1,136 @@
+package blocked
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/binary"
+	"fmt"
+	"io"
+	"os"
+
+	"github.com/iostrovok/go-bloom-filter/bloomfilter"
+)
+
+/*
+	Binary format of blocked bloom filter:
+
+	magic        [8]byte  "GoBlock\xff"
+	version      uint32   formatVersion
+	num hashes   uint32
+	num blocks   uint64
+	capacity     int64
+	count        int64
+	error rate   float64
+	name length  uint32
+	hasher name  [name length]byte
+	bits         numBlocks * 64 bytes
+*/
+
+const formatVersion = uint32(1)
+
+var magic = []byte{'G', 'o', 'B', 'l', 'o', 'c', 'k', 0xff}
+
+type header struct {
+	Version    uint32
+	NumHashes  uint32
+	NumBlocks  uint64
+	Capacity   int64
+	Count      int64
+	ErrorRate  float64
+	NameLength uint32
+}
+
+// ToFile saves blocked bloom filter to file by file name.
+func (f *Filter) ToFile(fileName string) error {
+	file, err := os.Create(fileName)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+
+	binBuf := bytes.NewBuffer([]byte{})
+	if err := f.ToBytes(binBuf); err != nil {
+		return err
+	}
+
+	_, err = file.Write(binBuf.Bytes())
+	return err
+}
+
+// ToBytes returns binary image of blocked bloom filter.
+func (f *Filter) ToBytes(binBuf *bytes.Buffer) error {
+
+	name := f.hasher.Name()
+
+	binBuf.Write(magic)
+	binary.Write(binBuf, binary.LittleEndian, header{
+		Version:    formatVersion,
+		NumHashes:  uint32(f.numHashes),
+		NumBlocks:  f.numBlocks,
+		Capacity:   f.capacity,
+		Count:      f.Count(),
+		ErrorRate:  f.errorRate,
+		NameLength: uint32(len(name)),
+	})
+	binBuf.WriteString(name)
+
+	return f.bits.ToBytes(binBuf)
+}
+
+// FromFile creates new blocked bloom filter from file
+func FromFile(fileName string) (*Filter, error) {
+
+	file, err := os.Open(fileName)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+
+	return FromReader(bufio.NewReader(file))
+}
+
+// FromReader creates new blocked bloom filter from bufio.Reader
+func FromReader(reader *bufio.Reader) (*Filter, error) {
+
+	b := make([]byte, len(magic), len(magic))
+	if _, err := io.ReadFull(reader, b); err != nil {
+		return nil, err
+	}
+
+	if !bytes.Equal(b, magic) {
+		return nil, fmt.Errorf("wrong format of blocked bloom filter")
+	}
+
+	var h header
+	if err := binary.Read(reader, binary.LittleEndian, &h); err != nil {
+		return nil, err
+	}
+
+	if h.Version != formatVersion {
+		return nil, fmt.Errorf("unknown format version: %d", h.Version)
+	}
+
+	if h.NumHashes < 1 || h.NumHashes > MaxHashes || h.NumBlocks < 1 || h.NameLength > 1024 ||
+		h.ErrorRate <= 0 || 1.0 <= h.ErrorRate {
+		return nil, fmt.Errorf("wrong header of blocked bloom filter")
+	}
+
+	name := make([]byte, h.NameLength, h.NameLength)
+	if _, err := io.ReadFull(reader, name); err != nil {
+		return nil, err
+	}
+
+	hasher, find := bloomfilter.LookupHasher(string(name))
+	if !find {
+		return nil, fmt.Errorf("unknown hash function: %q", name)
+	}
+
+	f := newFilter(h.ErrorRate, int(h.NumHashes), h.NumBlocks, h.Capacity, hasher)
+	f.count = h.Count
+
+	if err := f.bits.Read(reader, int64(h.NumBlocks*BlockBits/8)); err != nil {
+		return nil, err
+	}
+
+	return f, nil
+}
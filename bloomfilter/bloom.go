@@ -3,14 +3,12 @@ package bloomfilter
 import (
 	"bufio"
 	"bytes"
-	"crypto/md5"
-	"crypto/sha1"
-	"crypto/sha256"
-	"crypto/sha512"
 	"encoding/binary"
 	"fmt"
+	"io"
 	"math"
 	"os"
+	"sync/atomic"
 	"unsafe"
 
 	"github.com/iostrovok/go-bloom-filter/bloomfilter/array"
@@ -18,37 +16,14 @@ import (
 
 var log2Const float64
 var capacityError error
+var readOnlyError error
+var checksumError error
 
 func init() {
 	log2Const = math.Log(2) * math.Log(2)
 	capacityError = fmt.Errorf("BloomFilter is at capacity")
-}
-
-// --------------------------------------------------------
-
-func sum(mark string, b []byte) []byte {
-
-	switch mark {
-	case "sha512":
-		t := sha512.Sum512(b)
-		return t[:]
-	case "sha384":
-		t := sha512.Sum384(b)
-		return t[:]
-	case "sha256":
-		t := sha256.Sum256(b)
-		return t[:]
-	case "sha1":
-		t := sha1.Sum(b)
-		return t[:]
-	case "md5":
-		t := md5.Sum(b)
-		return t[:]
-	default:
-		// nothing. We will never be here.
-	}
-
-	return []byte{}
+	readOnlyError = fmt.Errorf("BloomFilter is read-only")
+	checksumError = fmt.Errorf("wrong checksum of bloom filter")
 }
 
 // BloomFilter is a structure for scalable bloom filter.
@@ -60,34 +35,59 @@ type BloomFilter struct {
 	numBits      uint64
 	count        int64
 	chunkSize    int
-	hashfnname   string
+	hasher       Hasher
 
 	saltFunctions []*hashFN
 
-	bitarray *array.Array
+	opts options
+
+	bitarray BitStore
+
+	// mapped file, see FromMapping
+	mapping     *array.Mapping
+	ownMapping  bool
+	countOffset int64
+	// begin of image and offset of checksum for format v2, see Sync
+	imageOffset    int64
+	checksumOffset int64
 }
 
 // New is constructor. It checks parameters and creates new bloom filter.
 func New(capacity int64, errorRates ...float64) (*BloomFilter, error) {
 
-	errorRate := float64(0.001)
 	if len(errorRates) > 0 {
-		errorRate = errorRates[0]
+		return NewWithOptions(capacity, WithErrorRate(errorRates[0]))
+	}
+
+	return NewWithOptions(capacity)
+}
+
+// NewWithOptions is constructor with functional options. It checks parameters and creates new bloom filter.
+func NewWithOptions(capacity int64, opts ...Option) (*BloomFilter, error) {
+
+	o := defaultOptions()
+	for _, opt := range opts {
+		opt(&o)
 	}
 
-	if errorRate <= 0 || 1.0 < errorRate {
-		return nil, fmt.Errorf("error Rate must be between 0 and 1")
+	if err := o.check(); err != nil {
+		return nil, err
 	}
 
 	if capacity < 1 {
 		return nil, fmt.Errorf("capacity must be > 0")
 	}
 
-	numSlices := int(math.Ceil(math.Log2(float64(1.0) / errorRate)))
-	bitsPerSlice := uint64(math.Ceil((float64(capacity) * math.Abs(math.Log(errorRate))) / (float64(numSlices) * log2Const)))
+	errorRate, numSlices, bitsPerSlice := o.sizes(capacity)
 
-	bf := &BloomFilter{}
+	bf := &BloomFilter{opts: o}
 	bf.setup(errorRate, bitsPerSlice, numSlices, capacity, int64(0))
+	bits, err := newBitArray(bf.opts, bf.numBits)
+	if err != nil {
+		return nil, err
+	}
+
+	bf.bitarray = bits
 	return bf, nil
 }
 
@@ -100,14 +100,16 @@ func (bf *BloomFilter) setup(errorRates float64, bitsPerSlice uint64, numSlices
 	bf.count = count
 	bf.numBits = uint64(numSlices) * bitsPerSlice
 	bf.makeSalts()
-
-	bf.bitarray = array.New(bf.numBits)
 }
 
 // Add new key. Returns true/false for key and error.
 func (bf *BloomFilter) Add(key []byte, skipChecks ...bool) (bool, error) {
 
-	if bf.count > bf.capacity {
+	if bf.readOnly() {
+		return false, readOnlyError
+	}
+
+	if atomic.LoadInt64(&bf.count) > bf.capacity && bf.opts.overflow == OverflowError {
 		return false, capacityError
 	}
 
@@ -116,9 +118,12 @@ func (bf *BloomFilter) Add(key []byte, skipChecks ...bool) (bool, error) {
 		skipCheck = skipChecks[0]
 	}
 
+	sc := scratchPool.Get().(*scratch)
+	defer scratchPool.Put(sc)
+
 	foundAllBits := true
 	offset := uint64(0)
-	hashes := bf.makeSaltIterator(key)
+	hashes := bf.makeSaltIterator(key, sc)
 	k, find := hashes.next()
 	for find {
 		if !skipCheck && foundAllBits {
@@ -134,7 +139,7 @@ func (bf *BloomFilter) Add(key []byte, skipChecks ...bool) (bool, error) {
 	}
 
 	if skipCheck || !foundAllBits {
-		bf.count++
+		atomic.AddInt64(&bf.count, 1)
 		return false, nil
 	}
 
@@ -143,8 +148,11 @@ func (bf *BloomFilter) Add(key []byte, skipChecks ...bool) (bool, error) {
 
 // Check key. Returns true/false
 func (bf *BloomFilter) Check(key []byte) bool {
+	sc := scratchPool.Get().(*scratch)
+	defer scratchPool.Put(sc)
+
 	offset := uint64(0)
-	hashes := bf.makeSaltIterator(key)
+	hashes := bf.makeSaltIterator(key, sc)
 	k, find := hashes.next()
 	for find {
 		if !bf.bitarray.Get(offset + k) {
@@ -156,18 +164,19 @@ func (bf *BloomFilter) Check(key []byte) bool {
 	return true
 }
 
-func (bf *BloomFilter) makeSaltIterator(key []byte) *saltIterator {
-	iterator := &saltIterator{
+// makeSaltIterator returns iterator by value: a struct stored through a pointer
+// moves key to heap, so keys on stack (AddUint64) would be allocated.
+func (bf *BloomFilter) makeSaltIterator(key []byte, sc *scratch) saltIterator {
+	return saltIterator{
 		numSlices:        bf.numSlices,
 		saltFunctions:    bf.saltFunctions,
 		numSaltFunctions: len(bf.saltFunctions),
 		key:              key,
-		tmpBody:          []uint64{},
 		bitsPerSlice:     bf.bitsPerSlice,
 		chunkSize:        bf.chunkSize,
+		sc:               sc,
+		doubleHashing:    bf.opts.indexMode == IndexDoubleHashing,
 	}
-
-	return iterator
 }
 
 func (bf *BloomFilter) makeSalts() {
@@ -181,21 +190,16 @@ func (bf *BloomFilter) makeSalts() {
 	}
 
 	totalHashBits := 8 * bf.numSlices * bf.chunkSize
-	fmtLength := md5.Size
-	bf.hashfnname = "md5"
-
-	if totalHashBits > 384 {
-		bf.hashfnname = "sha512"
-		fmtLength = sha512.Size
-	} else if totalHashBits > 256 {
-		bf.hashfnname = "sha384"
-		fmtLength = sha512.Size384
-	} else if totalHashBits > 160 {
-		bf.hashfnname = "sha256"
-		fmtLength = sha256.Size
-	} else if totalHashBits > 128 {
-		bf.hashfnname = "sha1"
-		fmtLength = sha1.Size
+	bf.hasher = bf.opts.hasher
+	if bf.hasher == nil {
+		bf.hasher = pybloomHasher(totalHashBits)
+	}
+
+	// IndexSalted with python-bloomfilter hashes keeps the original number of salts of this package,
+	// so the existing files produce identical bits, see saltsDiverge.
+	fmtLength := bf.hasher.Size() / bf.chunkSize
+	if bf.opts.indexMode == IndexSalted && bf.hasher == pybloomHasher(totalHashBits) {
+		fmtLength = bf.hasher.Size()
 	}
 
 	numSalts := bf.numSlices / fmtLength
@@ -203,16 +207,28 @@ func (bf *BloomFilter) makeSalts() {
 		numSalts++
 	}
 
+	if bf.opts.indexMode == IndexDoubleHashing {
+		// two 64 bits hashes from one or two digests
+		numSalts = 1
+		if bf.hasher.Size() < 16 {
+			numSalts = 2
+		}
+	}
+
 	bf.saltFunctions = []*hashFN{}
 	for i := 0; i < numSalts; i++ {
-		s := sum(bf.hashfnname, packInt(i))
-		bf.saltFunctions = append(bf.saltFunctions, newHashFN(bf.hashfnname, s))
+		salt := packInt(i)
+		if bf.opts.seed != 0 {
+			salt = append(salt, packUint64(bf.opts.seed)...)
+		}
+		s := bf.hasher.Sum(nil, salt)
+		bf.saltFunctions = append(bf.saltFunctions, newHashFN(bf.hasher, s))
 	}
 }
 
 // Count is a "getter". Returns all number of added keys.
 func (bf *BloomFilter) Count() int64 {
-	return bf.count
+	return atomic.LoadInt64(&bf.count)
 }
 
 // Capacity is a "getter". Returns full Capacity
@@ -225,6 +241,73 @@ func (bf *BloomFilter) ErrorRate() float64 {
 	return bf.errorRate
 }
 
+// NumSlices is a "getter". Returns number of slices (hash functions).
+func (bf *BloomFilter) NumSlices() int {
+	return bf.numSlices
+}
+
+// BitsPerSlice is a "getter". Returns number of bits in one slice.
+func (bf *BloomFilter) BitsPerSlice() uint64 {
+	return bf.bitsPerSlice
+}
+
+// FileFormat is a "getter". Returns format of ToBytes and ToFile.
+func (bf *BloomFilter) FileFormat() FileFormat {
+	return bf.opts.format
+}
+
+// Compression is a "getter". Returns compression of ToFile.
+func (bf *BloomFilter) Compression() Compression {
+	return bf.opts.compression
+}
+
+// PybloomCompatible returns true if python-bloomfilter sets the same bits for the same keys,
+// so filter may be saved by WritePybloom.
+func (bf *BloomFilter) PybloomCompatible() bool {
+
+	if bf.opts.seed != 0 || bf.hasher != pybloomHasher(8*bf.numSlices*bf.chunkSize) {
+		return false
+	}
+
+	switch bf.opts.indexMode {
+	case IndexPybloom:
+		return true
+	case IndexSalted:
+		return !bf.saltsDiverge()
+	}
+
+	return false
+}
+
+// saltsDiverge returns true if python-bloomfilter needs more salts than IndexSalted makes.
+// One digest of python-bloomfilter hash has Size()/chunkSize positions, IndexSalted
+// counts Size() positions, so they differ when positions do not fit into one digest.
+func (bf *BloomFilter) saltsDiverge() bool {
+	return bf.hasher == pybloomHasher(8*bf.numSlices*bf.chunkSize) && bf.numSlices*bf.chunkSize > bf.hasher.Size()
+}
+
+// positionMode returns index mode which defines positions of keys.
+// IndexPybloom and IndexSalted set the same bits while salts do not diverge.
+func (bf *BloomFilter) positionMode() IndexMode {
+	if bf.opts.indexMode == IndexPybloom && !bf.saltsDiverge() {
+		return IndexSalted
+	}
+	return bf.opts.indexMode
+}
+
+// Clear removes all keys. Memory of filter is reused.
+func (bf *BloomFilter) Clear() error {
+
+	if bf.readOnly() {
+		return readOnlyError
+	}
+
+	resetBits(bf.bitarray)
+	atomic.StoreInt64(&bf.count, 0)
+
+	return nil
+}
+
 // Merge integrates 2 filters. Filters must have the same parameters
 func (bf *BloomFilter) Merge(bfNew *BloomFilter) error {
 
@@ -232,7 +315,12 @@ func (bf *BloomFilter) Merge(bfNew *BloomFilter) error {
 		return err
 	}
 
-	return bf.bitarray.Merge(bfNew.bitarray)
+	return mergeBits(bf.bitarray, bfNew.bitarray)
+}
+
+// Compatible returns error if filters have different sizes or hashing, so they can not be merged.
+func (bf *BloomFilter) Compatible(bfNew *BloomFilter) error {
+	return bf.compare(bfNew)
 }
 
 func (bf *BloomFilter) compare(bfNew *BloomFilter) error {
@@ -261,7 +349,19 @@ func (bf *BloomFilter) compare(bfNew *BloomFilter) error {
 		return fmt.Errorf("Wrong length for errorRate: %f != %f", bf.errorRate, bfNew.errorRate)
 	}
 
-	return bf.bitarray.Compare(bfNew.bitarray)
+	if bf.hasher.Name() != bfNew.hasher.Name() {
+		return fmt.Errorf("Wrong hash function: %s != %s", bf.hasher.Name(), bfNew.hasher.Name())
+	}
+
+	if bf.opts.seed != bfNew.opts.seed {
+		return fmt.Errorf("Wrong seed: %d != %d", bf.opts.seed, bfNew.opts.seed)
+	}
+
+	if bf.positionMode() != bfNew.positionMode() {
+		return fmt.Errorf("Wrong index mode: %d != %d", bf.opts.indexMode, bfNew.opts.indexMode)
+	}
+
+	return compareBits(bf.bitarray, bfNew.bitarray)
 }
 
 // ToFile saves bloom filter to file by file name.
@@ -272,25 +372,37 @@ func (bf *BloomFilter) ToFile(fileName string) error {
 		return err
 	}
 
-	binBuf := bytes.NewBuffer([]byte{})
-	if err := bf.ToBytes(binBuf); err != nil {
+	writer := bufio.NewWriter(file)
+	if bf.opts.compression != CompressionNone {
+		err = bf.writeCompressed(writer)
+	} else {
+		_, err = bf.WriteTo(writer)
+	}
+	if err != nil {
 		return err
 	}
 
-	_, err = file.Write(binBuf.Bytes())
-	return err
+	return writer.Flush()
 }
 
-// ToBytes returns binary image of bloom filter
+// ToBytes returns binary image of bloom filter. Filters which can not be
+// described by python-bloomfilter header are saved with extended header.
+// Filters with WithFileFormat(FormatV2) are saved in format v2.
 func (bf *BloomFilter) ToBytes(binBuf *bytes.Buffer) error {
+	binBuf.Grow(int(bf.BinarySize()))
+	_, err := bf.WriteTo(binBuf)
+	return err
+}
 
-	binary.Write(binBuf, binary.LittleEndian, bf.errorRate)
-	binary.Write(binBuf, binary.LittleEndian, uint64(bf.numSlices))
-	binary.Write(binBuf, binary.LittleEndian, uint64(bf.bitsPerSlice))
-	binary.Write(binBuf, binary.LittleEndian, uint64(bf.capacity))
-	binary.Write(binBuf, binary.LittleEndian, uint64(bf.count))
+// writeHeader writes python-bloomfilter header, extended header is written before it if it is needed.
+func writeHeader(binBuf *bytes.Buffer, bf *BloomFilter) {
 
-	return bf.bitarray.ToBytes(binBuf)
+	// python-bloomfilter header is read as IndexSalted, so other salts need extended header
+	if !bf.PybloomCompatible() || bf.saltsDiverge() {
+		writeExtHeader(binBuf, bf)
+	}
+
+	writePybloomHeader(binBuf, bf)
 }
 
 // FromFile creates new bloom filter from file
@@ -310,9 +422,63 @@ func FromFile(fileName string, lengths ...int64) (*BloomFilter, error) {
 	return FromReader(bufio.NewReader(file), length)
 }
 
-// FromReader creates new bloom filter from bufio.Reader
+// FromReader creates new bloom filter from bufio.Reader.
+// It reads python-bloomfilter format, extended one, format v2 and compressed files. Checksum of format v2 is checked.
+// Python file, which needs more salts than one digest (see PybloomCompatible), gives false negatives,
+// such file must be read by FromPybloom.
 func FromReader(reader *bufio.Reader, length int64) (*BloomFilter, error) {
 
+	if isV2(reader) {
+		return readV2(reader)
+	}
+
+	if isCompressed(reader) {
+		return readCompressed(reader)
+	}
+
+	bf, headerLen, err := readHeader(reader)
+	if err != nil {
+		return nil, err
+	}
+
+	if bf.bitarray, err = newBitArray(bf.opts, bf.numBits); err != nil {
+		return nil, err
+	}
+
+	// bits are read by exact length, so truncated file is not loaded
+	if length > 0 && length-headerLen < int64((bf.numBits+7)/8) {
+		return nil, io.ErrUnexpectedEOF
+	}
+
+	if err := readBits(bf, reader); err != nil {
+		return nil, err
+	}
+
+	return bf, nil
+}
+
+// readHeader reads headers and creates filter without bit array.
+// Returns length of all read headers.
+func readHeader(reader io.Reader) (*BloomFilter, int64, error) {
+
+	bf := &BloomFilter{}
+
+	magic := make([]byte, len(extMagic), len(extMagic))
+	if _, err := io.ReadFull(reader, magic); err != nil {
+		return nil, 0, err
+	}
+
+	extLen := int64(0)
+	if bytes.Equal(magic, extMagic) {
+		var err error
+		if extLen, err = readExtHeader(reader, &bf.opts); err != nil {
+			return nil, 0, err
+		}
+	} else {
+		// python-bloomfilter format, magic is error rate
+		reader = io.MultiReader(bytes.NewReader(magic), reader)
+	}
+
 	var header struct {
 		ErrorRate    float64
 		NumSlices    int64
@@ -324,23 +490,26 @@ func FromReader(reader *bufio.Reader, length int64) (*BloomFilter, error) {
 	const headerLen = int64(unsafe.Sizeof(header))
 
 	b := make([]byte, headerLen, headerLen)
-	_, err := reader.Read(b)
-	if err != nil {
-		return nil, err
+	if _, err := io.ReadFull(reader, b); err != nil {
+		return nil, 0, err
 	}
 
 	r := bytes.NewReader(b)
 	if err := binary.Read(r, binary.LittleEndian, &header); err != nil {
-		return nil, err
+		return nil, 0, err
 	}
 
-	bf := &BloomFilter{}
-	bf.setup(header.ErrorRate, uint64(header.BitsPerSlice), int(header.NumSlices), int64(header.Capacity), int64(header.Count))
+	// magic of other formats is negative error rate
+	if !(header.ErrorRate > 0 && header.ErrorRate < 1) {
+		return nil, 0, fmt.Errorf("wrong error rate of bloom filter: %v", header.ErrorRate)
+	}
 
-	if length > 0 {
-		length = length - headerLen
+	if header.NumSlices < 1 || header.BitsPerSlice < 1 || header.BitsPerSlice > math.MaxInt64/8/header.NumSlices ||
+		header.Capacity < 0 || header.Count < 0 {
+		return nil, 0, fmt.Errorf("wrong sizes of bloom filter")
 	}
-	bf.bitarray.Read(reader, length)
 
-	return bf, nil
+	bf.setup(header.ErrorRate, uint64(header.BitsPerSlice), int(header.NumSlices), int64(header.Capacity), int64(header.Count))
+
+	return bf, headerLen + extLen, nil
 }
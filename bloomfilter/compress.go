@@ -0,0 +1,115 @@
+package bloomfilter
+
+import (
+	"bufio"
+	"bytes"
+	"compress/gzip"
+	"encoding/binary"
+	"fmt"
+	"io"
+
+	"github.com/iostrovok/go-bloom-filter/bloomfilter/internal/rle"
+)
+
+/*
+	Compressed file is written by ToFile if filter has WithCompression(CompressionGzip).
+	FromFile and FromReader detect it by magic.
+
+	magic      [8]byte  "GoBloom\xfd", invalid float64 for python-bloomfilter error rate
+	version    uint32   compressedVersion
+	method     uint32   Compression
+	data       gzip stream of image of WriteTo, long runs of zero bytes are encoded by package rle
+*/
+
+const compressedVersion = uint32(1)
+
+var compressedMagic = []byte{'G', 'o', 'B', 'l', 'o', 'o', 'm', 0xfd}
+
+type compressedHeader struct {
+	Magic   [8]byte
+	Version uint32
+	Method  uint32
+}
+
+// writeCompressed writes compressed image of filter to w.
+func (bf *BloomFilter) writeCompressed(w io.Writer) error {
+
+	header := compressedHeader{
+		Version: compressedVersion,
+		Method:  uint32(bf.opts.compression),
+	}
+	copy(header.Magic[:], compressedMagic)
+
+	if err := binary.Write(w, binary.LittleEndian, header); err != nil {
+		return err
+	}
+
+	gz := gzip.NewWriter(w)
+	z := rle.NewWriter(gz)
+
+	if _, err := bf.WriteTo(z); err != nil {
+		return err
+	}
+
+	if err := z.Close(); err != nil {
+		return err
+	}
+
+	return gz.Close()
+}
+
+// isCompressed returns true if data of reader starts with magic of compressed file.
+func isCompressed(reader *bufio.Reader) bool {
+	b, err := reader.Peek(len(compressedMagic))
+	return err == nil && bytes.Equal(b, compressedMagic)
+}
+
+// readCompressed reads filter from compressed file. Checksum of gzip is checked.
+func readCompressed(reader io.Reader) (*BloomFilter, error) {
+
+	var header compressedHeader
+	if err := binary.Read(reader, binary.LittleEndian, &header); err != nil {
+		return nil, err
+	}
+
+	if !bytes.Equal(header.Magic[:], compressedMagic) {
+		return nil, fmt.Errorf("wrong format of compressed bloom filter")
+	}
+
+	if header.Version != compressedVersion {
+		return nil, fmt.Errorf("unknown format version: %d", header.Version)
+	}
+
+	if Compression(header.Method) != CompressionGzip {
+		return nil, fmt.Errorf("unknown compression: %d", header.Method)
+	}
+
+	gz, err := gzip.NewReader(reader)
+	if err != nil {
+		return nil, err
+	}
+	defer gz.Close()
+
+	// the next gzip stream is not a part of filter
+	gz.Multistream(false)
+
+	z := rle.NewReader(gz)
+
+	bf := &BloomFilter{}
+	if _, err := bf.ReadFrom(z); err != nil {
+		return nil, err
+	}
+
+	// gzip checks its checksum at the end of stream
+	n, err := io.Copy(io.Discard, z)
+	if err != nil {
+		return nil, err
+	}
+	if n > 0 {
+		return nil, fmt.Errorf("unexpected %d bytes after bloom filter", n)
+	}
+
+	bf.opts.compression = CompressionGzip
+
+	return bf, nil
+}
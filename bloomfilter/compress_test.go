@@ -0,0 +1,93 @@
+package bloomfilter
+
+import (
+	"bufio"
+	"bytes"
+	"io"
+	"os"
+	"path/filepath"
+
+	"github.com/iostrovok/go-bloom-filter/bloomfilter/fortesting"
+	. "gopkg.in/check.v1"
+)
+
+func (s *filterTestSuite) TestCompression(c *C) {
+
+	testArray := fortesting.ArrayForTesting()
+	dir := c.MkDir()
+
+	for i, opts := range [][]Option{
+		{},
+		{WithFileFormat(FormatV2)},
+		{WithHashStrategy(HashXXHash64), WithConcurrency(ConcurrencyAtomic)},
+		{WithStorage(StorageSparse)},
+	} {
+		filter, err := NewWithOptions(1000*1000, append(opts, WithCompression(CompressionGzip))...)
+		c.Assert(err, IsNil)
+		c.Assert(filter.Compression(), Equals, CompressionGzip)
+
+		for _, s := range testArray {
+			_, err := filter.Add([]byte(s))
+			c.Assert(err, IsNil)
+		}
+
+		fileName := filepath.Join(dir, "compressed.bin")
+		c.Assert(filter.ToFile(fileName), IsNil)
+
+		data, err := os.ReadFile(fileName)
+		c.Assert(err, IsNil)
+		c.Assert(data[:len(compressedMagic)], DeepEquals, compressedMagic)
+		c.Assert(int64(len(data)) < filter.BinarySize()/20, Equals, true, Commentf("%d: %d", i, len(data)))
+
+		loaded, err := FromFile(fileName)
+		c.Assert(err, IsNil)
+		c.Assert(Equal(filter, loaded), Equals, true)
+		c.Assert(loaded.Count(), Equals, filter.Count())
+		c.Assert(loaded.FileFormat(), Equals, filter.FileFormat())
+		c.Assert(loaded.Compression(), Equals, CompressionGzip)
+
+		// ReadFrom reads compressed file and leaves data after it
+		stream := bytes.NewReader(append(append([]byte{}, data...), "tail"...))
+		read := &BloomFilter{}
+		n, err := read.ReadFrom(stream)
+		c.Assert(err, IsNil)
+		c.Assert(n, Equals, int64(len(data)))
+		c.Assert(Equal(filter, read), Equals, true)
+		c.Assert(read.Compression(), Equals, CompressionGzip)
+		tail, err := io.ReadAll(stream)
+		c.Assert(err, IsNil)
+		c.Assert(string(tail), Equals, "tail")
+
+		unmarshaled := &BloomFilter{}
+		c.Assert(unmarshaled.UnmarshalBinary(data), IsNil)
+		c.Assert(Equal(filter, unmarshaled), Equals, true)
+		c.Assert((&BloomFilter{}).UnmarshalBinary(append(append([]byte{}, data...), "tail"...)), NotNil)
+
+		// ToBytes is not compressed
+		binBuf := bytes.NewBuffer([]byte{})
+		c.Assert(loaded.ToBytes(binBuf), IsNil)
+		c.Assert(int64(binBuf.Len()), Equals, filter.BinarySize())
+
+		// saved again without changes
+		again := filepath.Join(dir, "again.bin")
+		c.Assert(loaded.ToFile(again), IsNil)
+		againData, err := os.ReadFile(again)
+		c.Assert(err, IsNil)
+		c.Assert(againData, DeepEquals, data)
+
+		// damaged and truncated files
+		for _, j := range []int{len(compressedMagic) + 1, 12, len(data) / 2, len(data) - 6} {
+			broken := append([]byte{}, data...)
+			broken[j] ^= 0x20
+			_, err := FromReader(bufio.NewReader(bytes.NewReader(broken)), 0)
+			c.Assert(err, NotNil, Commentf("%d: byte %d", i, j))
+		}
+		_, err = FromReader(bufio.NewReader(bytes.NewReader(data[:len(data)-10])), 0)
+		c.Assert(err, NotNil)
+		_, err = (&BloomFilter{}).ReadFrom(bytes.NewReader(data[:len(data)-10]))
+		c.Assert(err, NotNil)
+
+		_, err = FromFileMapped(fileName, false)
+		c.Assert(err, NotNil)
+	}
+}
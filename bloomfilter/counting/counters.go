@@ -0,0 +1,93 @@
+package counting
+
+// counters is an array of unsigned counters of fixed size (2...8 bits).
+// Counter may be placed in two neighbour bytes.
+type counters struct {
+	data   []byte
+	length uint64
+	bits   uint
+	max    uint8
+}
+
+func newCounters(length uint64, bits uint) *counters {
+	size := (length*uint64(bits) + 7) / 8
+	return &counters{
+		data:   make([]byte, size, size),
+		length: length,
+		bits:   bits,
+		max:    uint8(1<<bits - 1),
+	}
+}
+
+func (c *counters) get(i uint64) uint8 {
+	bit := i * uint64(c.bits)
+	j := bit / 8
+	shift := bit % 8
+
+	v := uint16(c.data[j])
+	if shift+uint64(c.bits) > 8 {
+		v |= uint16(c.data[j+1]) << 8
+	}
+
+	return uint8(v>>shift) & c.max
+}
+
+func (c *counters) set(i uint64, value uint8) {
+	bit := i * uint64(c.bits)
+	j := bit / 8
+	shift := bit % 8
+
+	mask := uint16(c.max) << shift
+	v := uint16(value) << shift
+
+	c.data[j] = c.data[j]&^uint8(mask) | uint8(v)
+	if shift+uint64(c.bits) > 8 {
+		c.data[j+1] = c.data[j+1]&^uint8(mask>>8) | uint8(v>>8)
+	}
+}
+
+// inc increases counter. Returns false if counter is saturated.
+func (c *counters) inc(i uint64) bool {
+	v := c.get(i)
+	if v == c.max {
+		return false
+	}
+
+	c.set(i, v+1)
+	return v+1 < c.max
+}
+
+// dec decreases counter. Saturated and zero counters are not changed.
+func (c *counters) dec(i uint64) {
+	v := c.get(i)
+	if v == c.max || v == 0 {
+		return
+	}
+	c.set(i, v-1)
+}
+
+// clone returns copy of counters.
+func (c *counters) clone() *counters {
+	out := *c
+	out.data = append([]byte{}, c.data...)
+	return &out
+}
+
+// merge adds values of other counters. Returns true if some counter is saturated.
+func (c *counters) merge(a *counters) bool {
+	overflow := false
+	for i := uint64(0); i < c.length; i++ {
+		v := a.get(i)
+		if v == 0 {
+			continue
+		}
+
+		sum := uint16(c.get(i)) + uint16(v)
+		if sum >= uint16(c.max) {
+			sum = uint16(c.max)
+			overflow = true
+		}
+		c.set(i, uint8(sum))
+	}
+	return overflow
+}
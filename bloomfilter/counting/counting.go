@@ -0,0 +1,266 @@
+package counting
+
+import (
+	"fmt"
+	"sync"
+
+	"github.com/iostrovok/go-bloom-filter/bloomfilter"
+)
+
+/*
+	Counting bloom filter keeps a small counter instead of every bit of bloom filter,
+	so keys may be removed. It uses the same sizes and hashing as bloomfilter.BloomFilter.
+
+	Counter which reaches maximum value is saturated: it is never changed by Add and Remove,
+	so removing of other keys does not produce false negative results.
+*/
+
+// DefaultCounterBits is size of counter in bits which is used by New.
+const DefaultCounterBits = 4
+
+var capacityError error
+var overflowError error
+
+func init() {
+	capacityError = fmt.Errorf("BloomFilter is at capacity")
+	overflowError = fmt.Errorf("counter overflow")
+}
+
+// Filter is a structure for counting bloom filter.
+type Filter struct {
+	mc sync.RWMutex
+
+	// index calculates positions of keys. Its own bits are not used, so they are kept in empty sparse array.
+	index *bloomfilter.BloomFilter
+	opts  []bloomfilter.Option
+
+	counters *counters
+
+	count     int64
+	overflows int64
+}
+
+// New is constructor. It checks parameters and creates new counting bloom filter with 4 bits counters.
+func New(capacity int64, errorRates ...float64) (*Filter, error) {
+
+	if len(errorRates) > 0 {
+		return NewWithOptions(capacity, DefaultCounterBits, bloomfilter.WithErrorRate(errorRates[0]))
+	}
+
+	return NewWithOptions(capacity, DefaultCounterBits)
+}
+
+// NewWithOptions is constructor with size of counter (2...8 bits) and options of bloom filter.
+func NewWithOptions(capacity int64, counterBits int, opts ...bloomfilter.Option) (*Filter, error) {
+
+	if counterBits < 2 || counterBits > 8 {
+		return nil, fmt.Errorf("counterBits must be between 2 and 8")
+	}
+
+	index, err := bloomfilter.NewWithOptions(capacity, indexOptions(opts)...)
+	if err != nil {
+		return nil, err
+	}
+
+	return newFilter(index, opts, uint(counterBits)), nil
+}
+
+// indexOptions returns options of index: the same hashing without store and with sparse bits.
+func indexOptions(opts []bloomfilter.Option) []bloomfilter.Option {
+	out := make([]bloomfilter.Option, 0, len(opts)+3)
+	out = append(out, opts...)
+	return append(out,
+		bloomfilter.WithStore(nil),
+		bloomfilter.WithConcurrency(bloomfilter.ConcurrencyLocked),
+		bloomfilter.WithStorage(bloomfilter.StorageSparse))
+}
+
+func newFilter(index *bloomfilter.BloomFilter, opts []bloomfilter.Option, counterBits uint) *Filter {
+	return &Filter{
+		index:    index,
+		opts:     opts,
+		counters: newCounters(uint64(index.NumSlices())*index.BitsPerSlice(), counterBits),
+	}
+}
+
+// Add new key. Returns true if key has been found before adding.
+// Every Add increases counters, so the same key must be removed so many times as it is added.
+// Error is returned if filter is at capacity (nothing is added)
+// or some counter is saturated (key is added, but it can not be removed completely).
+func (f *Filter) Add(key []byte) (bool, error) {
+
+	var buf [32]uint64
+	locations := f.index.Locations(buf[:0], key)
+
+	f.mc.Lock()
+	defer f.mc.Unlock()
+
+	if f.count > f.index.Capacity() {
+		return false, capacityError
+	}
+
+	found := true
+	overflow := false
+	for _, i := range locations {
+		if found && f.counters.get(i) == 0 {
+			found = false
+		}
+		if !f.counters.inc(i) {
+			overflow = true
+		}
+	}
+
+	f.count++
+
+	if overflow {
+		f.overflows++
+		return found, overflowError
+	}
+
+	return found, nil
+}
+
+// Remove decreases counters of key. Returns false if key is not found, filter is not changed then.
+func (f *Filter) Remove(key []byte) bool {
+
+	var buf [32]uint64
+	locations := f.index.Locations(buf[:0], key)
+
+	f.mc.Lock()
+	defer f.mc.Unlock()
+
+	for _, i := range locations {
+		if f.counters.get(i) == 0 {
+			return false
+		}
+	}
+
+	for _, i := range locations {
+		f.counters.dec(i)
+	}
+
+	f.count--
+	return true
+}
+
+// Check key. Returns true/false
+func (f *Filter) Check(key []byte) bool {
+	return f.CountKey(key) > 0
+}
+
+// CountKey returns the smallest counter of key. It is an upper bound of number of key additions.
+func (f *Filter) CountKey(key []byte) int {
+
+	var buf [32]uint64
+	locations := f.index.Locations(buf[:0], key)
+
+	f.mc.RLock()
+	defer f.mc.RUnlock()
+
+	res := int(f.counters.max)
+	for _, i := range locations {
+		if v := int(f.counters.get(i)); v < res {
+			res = v
+		}
+	}
+	return res
+}
+
+// Count is a "getter". Returns number of added and not removed keys.
+func (f *Filter) Count() int64 {
+	f.mc.RLock()
+	defer f.mc.RUnlock()
+
+	return f.count
+}
+
+// Capacity is a "getter". Returns full Capacity
+func (f *Filter) Capacity() int64 {
+	return f.index.Capacity()
+}
+
+// ErrorRate is a "getter". Returns error rate for current filter
+func (f *Filter) ErrorRate() float64 {
+	return f.index.ErrorRate()
+}
+
+// CounterBits is a "getter". Returns size of one counter in bits.
+func (f *Filter) CounterBits() int {
+	return int(f.counters.bits)
+}
+
+// Overflows is a "getter". Returns number of Add calls which have saturated some counter.
+func (f *Filter) Overflows() int64 {
+	f.mc.RLock()
+	defer f.mc.RUnlock()
+
+	return f.overflows
+}
+
+// Merge adds counters of other filter into current. Filters must have the same parameters.
+// Merge of filter with itself does nothing.
+func (f *Filter) Merge(fNew *Filter) error {
+
+	if f == fNew {
+		return nil
+	}
+
+	if f.counters.bits != fNew.counters.bits {
+		return fmt.Errorf("Wrong length for counterBits: %d != %d", f.counters.bits, fNew.counters.bits)
+	}
+
+	// checks sizes and hashing
+	if err := f.index.Compatible(fNew.index); err != nil {
+		return err
+	}
+
+	// only one filter is locked at a time, so a.Merge(b) and b.Merge(a) do not deadlock
+	fNew.mc.RLock()
+	other := fNew.counters.clone()
+	count, overflows := fNew.count, fNew.overflows
+	fNew.mc.RUnlock()
+
+	f.mc.Lock()
+	defer f.mc.Unlock()
+
+	if f.counters.merge(other) {
+		f.overflows++
+	}
+	f.count += count
+	f.overflows += overflows
+
+	return nil
+}
+
+// ToBloomFilter returns plain bloom filter with bits which have not zero counters.
+// Result has the same hashing, so it finds the same keys.
+func (f *Filter) ToBloomFilter() (*bloomfilter.BloomFilter, error) {
+
+	out, err := bloomfilter.NewWithOptions(f.index.Capacity(), f.opts...)
+	if err != nil {
+		return nil, err
+	}
+
+	f.mc.RLock()
+	defer f.mc.RUnlock()
+
+	locations := make([]uint64, 0, 1024)
+	for i := uint64(0); i < f.counters.length; i++ {
+		if f.counters.get(i) == 0 {
+			continue
+		}
+		locations = append(locations, i)
+		if len(locations) == cap(locations) {
+			if err := out.SetLocations(locations, 0); err != nil {
+				return nil, err
+			}
+			locations = locations[:0]
+		}
+	}
+
+	if err := out.SetLocations(locations, f.count); err != nil {
+		return nil, err
+	}
+
+	return out, nil
+}
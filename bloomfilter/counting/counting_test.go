@@ -0,0 +1,314 @@
+package counting
+
+import (
+	"bufio"
+	"bytes"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/iostrovok/go-bloom-filter/bloomfilter"
+	"github.com/iostrovok/go-bloom-filter/bloomfilter/array"
+	"github.com/iostrovok/go-bloom-filter/bloomfilter/fortesting"
+	. "gopkg.in/check.v1"
+)
+
+type countTestSuite struct{}
+
+// Hook up gocheck into the "go test" runner.
+func Test(t *testing.T) { TestingT(t) }
+
+var _ = Suite(&countTestSuite{})
+
+func (s *countTestSuite) TestNew(c *C) {
+
+	filter, err := New(0, .001)
+	c.Assert(err, NotNil)
+	c.Assert(filter, IsNil)
+
+	filter, err = NewWithOptions(100, 1)
+	c.Assert(err, NotNil)
+	c.Assert(filter, IsNil)
+
+	filter, err = NewWithOptions(100, 9)
+	c.Assert(err, NotNil)
+	c.Assert(filter, IsNil)
+
+	filter, err = New(100)
+	c.Assert(err, IsNil)
+	c.Assert(filter.CounterBits(), Equals, DefaultCounterBits)
+	c.Assert(filter.ErrorRate(), Equals, 0.001)
+	c.Assert(filter.Capacity(), Equals, int64(100))
+}
+
+func (s *countTestSuite) TestCounters(c *C) {
+
+	for _, bits := range []uint{2, 3, 4, 5, 7, 8} {
+		cnt := newCounters(1000, bits)
+		for i := uint64(0); i < cnt.length; i++ {
+			cnt.set(i, uint8(i)&cnt.max)
+		}
+		for i := uint64(0); i < cnt.length; i++ {
+			c.Assert(cnt.get(i), Equals, uint8(i)&cnt.max)
+		}
+	}
+
+	cnt := newCounters(10, 2)
+	c.Assert(cnt.inc(5), Equals, true)
+	c.Assert(cnt.inc(5), Equals, true)
+	c.Assert(cnt.inc(5), Equals, false)
+	c.Assert(cnt.inc(5), Equals, false)
+	c.Assert(cnt.get(5), Equals, uint8(3))
+	cnt.dec(5)
+	c.Assert(cnt.get(5), Equals, uint8(3))
+	c.Assert(cnt.get(4), Equals, uint8(0))
+	c.Assert(cnt.get(6), Equals, uint8(0))
+}
+
+func (s *countTestSuite) TestAddRemove(c *C) {
+
+	testArray := fortesting.ArrayForTesting()
+
+	filter, err := New(int64(len(testArray)), 0.0001)
+	c.Assert(err, IsNil)
+
+	for _, s := range testArray {
+		res, err := filter.Add([]byte(s))
+		c.Assert(err, IsNil)
+		c.Assert(res, Equals, false)
+	}
+	c.Assert(filter.Count(), Equals, int64(len(testArray)))
+
+	for _, s := range testArray {
+		c.Assert(filter.Check([]byte(s)), Equals, true)
+	}
+
+	half := len(testArray) / 2
+	for _, s := range testArray[:half] {
+		c.Assert(filter.Remove([]byte(s)), Equals, true)
+	}
+	c.Assert(filter.Count(), Equals, int64(len(testArray)-half))
+
+	countBad := 0
+	for _, s := range testArray[:half] {
+		if filter.Check([]byte(s)) {
+			countBad++
+		}
+	}
+	c.Assert(countBad < 3, Equals, true)
+
+	for _, s := range testArray[half:] {
+		c.Assert(filter.Check([]byte(s)), Equals, true)
+	}
+
+	c.Assert(filter.Remove([]byte("unknown key")), Equals, false)
+
+	_, err = filter.Add([]byte("twice"))
+	c.Assert(err, IsNil)
+	res, err := filter.Add([]byte("twice"))
+	c.Assert(err, IsNil)
+	c.Assert(res, Equals, true)
+	c.Assert(filter.CountKey([]byte("twice")) >= 2, Equals, true)
+
+	c.Assert(filter.Remove([]byte("twice")), Equals, true)
+	c.Assert(filter.Check([]byte("twice")), Equals, true)
+	c.Assert(filter.Remove([]byte("twice")), Equals, true)
+	c.Assert(filter.Check([]byte("twice")), Equals, false)
+}
+
+func (s *countTestSuite) TestOverflow(c *C) {
+
+	filter, err := NewWithOptions(100, 2)
+	c.Assert(err, IsNil)
+
+	key := []byte("key")
+	_, err = filter.Add(key)
+	c.Assert(err, IsNil)
+	_, err = filter.Add(key)
+	c.Assert(err, IsNil)
+	_, err = filter.Add(key)
+	c.Assert(err, NotNil)
+	c.Assert(filter.Overflows(), Equals, int64(1))
+	c.Assert(filter.CountKey(key), Equals, 3)
+
+	// saturated counters are never decreased
+	for i := 0; i < 5; i++ {
+		c.Assert(filter.Remove(key), Equals, true)
+	}
+	c.Assert(filter.Check(key), Equals, true)
+}
+
+func (s *countTestSuite) TestMerge(c *C) {
+
+	testArray := fortesting.ArrayForTesting()
+	half := len(testArray) / 2
+
+	filterA, err := New(int64(len(testArray)), 0.0001)
+	c.Assert(err, IsNil)
+	filterB, err := New(int64(len(testArray)), 0.0001)
+	c.Assert(err, IsNil)
+
+	for _, s := range testArray[:half] {
+		_, err := filterA.Add([]byte(s))
+		c.Assert(err, IsNil)
+	}
+	for _, s := range testArray[half:] {
+		_, err := filterB.Add([]byte(s))
+		c.Assert(err, IsNil)
+	}
+
+	c.Assert(filterA.Merge(filterB), IsNil)
+	c.Assert(filterA.Count(), Equals, int64(len(testArray)))
+
+	for _, s := range testArray {
+		c.Assert(filterA.Check([]byte(s)), Equals, true)
+	}
+
+	for _, s := range testArray[half:] {
+		c.Assert(filterA.Remove([]byte(s)), Equals, true)
+	}
+	for _, s := range testArray[:half] {
+		c.Assert(filterA.Check([]byte(s)), Equals, true)
+	}
+
+	filterC, err := New(int64(len(testArray)), 0.001)
+	c.Assert(err, IsNil)
+	c.Assert(filterA.Merge(filterC), NotNil)
+
+	filterD, err := NewWithOptions(int64(len(testArray)), 8, bloomfilter.WithErrorRate(0.0001))
+	c.Assert(err, IsNil)
+	c.Assert(filterA.Merge(filterD), NotNil)
+
+	filterE, err := NewWithOptions(int64(len(testArray)), 4,
+		bloomfilter.WithErrorRate(0.0001), bloomfilter.WithHashStrategy(bloomfilter.HashXXHash64))
+	c.Assert(err, IsNil)
+	c.Assert(filterA.Merge(filterE), NotNil)
+
+	// merge with itself
+	count := filterA.Count()
+	c.Assert(filterA.Merge(filterA), IsNil)
+	c.Assert(filterA.Count(), Equals, count)
+
+	// merges in both directions at the same time
+	done := make(chan error, 2)
+	for i := 0; i < 100; i++ {
+		go func() { done <- filterA.Merge(filterB) }()
+		go func() { done <- filterB.Merge(filterA) }()
+		c.Assert(<-done, IsNil)
+		c.Assert(<-done, IsNil)
+	}
+}
+
+func (s *countTestSuite) TestIndexStore(c *C) {
+
+	// bits of index are not allocated by store of options
+	calls := 0
+	factory := func(length uint64) (bloomfilter.BitStore, error) {
+		calls++
+		return array.New(length), nil
+	}
+
+	filter, err := NewWithOptions(1000, 4, bloomfilter.WithStore(factory), bloomfilter.WithConcurrency(bloomfilter.ConcurrencyAtomic))
+	c.Assert(err, IsNil)
+	c.Assert(calls, Equals, 0)
+
+	_, err = filter.Add([]byte("key"))
+	c.Assert(err, IsNil)
+	c.Assert(filter.Check([]byte("key")), Equals, true)
+
+	plain, err := filter.ToBloomFilter()
+	c.Assert(err, IsNil)
+	c.Assert(calls, Equals, 1)
+	c.Assert(plain.Check([]byte("key")), Equals, true)
+
+	// file keeps parameters of index, not its bits
+	binBuf := bytes.NewBuffer([]byte{})
+	c.Assert(filter.ToBytes(binBuf), IsNil)
+	c.Assert(filter.ToFile(filepath.Join(c.MkDir(), "counting.bin")), IsNil)
+	c.Assert(calls, Equals, 1)
+}
+
+func (s *countTestSuite) TestToBloomFilter(c *C) {
+
+	testArray := fortesting.ArrayForTesting()
+
+	for _, opts := range [][]bloomfilter.Option{
+		{},
+		{bloomfilter.WithHashStrategy(bloomfilter.HashMurmur3), bloomfilter.WithIndexMode(bloomfilter.IndexDoubleHashing)},
+	} {
+		filter, err := NewWithOptions(int64(len(testArray)), 4, opts...)
+		c.Assert(err, IsNil)
+
+		plain, err := bloomfilter.NewWithOptions(int64(len(testArray)), opts...)
+		c.Assert(err, IsNil)
+
+		for _, s := range testArray {
+			_, err := filter.Add([]byte(s))
+			c.Assert(err, IsNil)
+			_, err = plain.Add([]byte(s))
+			c.Assert(err, IsNil)
+		}
+
+		exported, err := filter.ToBloomFilter()
+		c.Assert(err, IsNil)
+		c.Assert(exported.Count(), Equals, plain.Count())
+
+		bufA := bytes.NewBuffer([]byte{})
+		c.Assert(exported.ToBytes(bufA), IsNil)
+		bufB := bytes.NewBuffer([]byte{})
+		c.Assert(plain.ToBytes(bufB), IsNil)
+		c.Assert(bufA.Bytes(), DeepEquals, bufB.Bytes())
+	}
+}
+
+func (s *countTestSuite) TestToBytes(c *C) {
+
+	testArray := fortesting.ArrayForTesting()
+
+	filter, err := NewWithOptions(int64(len(testArray)), 5, bloomfilter.WithHashStrategy(bloomfilter.HashXXHash64),
+		bloomfilter.WithSeed(3), bloomfilter.WithIndexMode(bloomfilter.IndexDoubleHashing))
+	c.Assert(err, IsNil)
+
+	for _, s := range testArray {
+		_, err := filter.Add([]byte(s))
+		c.Assert(err, IsNil)
+	}
+
+	fileName := filepath.Join(c.MkDir(), "counting.bin")
+	c.Assert(filter.ToFile(fileName), IsNil)
+
+	filterNew, err := FromFile(fileName)
+	c.Assert(err, IsNil)
+	c.Assert(filterNew.Count(), Equals, filter.Count())
+	c.Assert(filterNew.CounterBits(), Equals, 5)
+	c.Assert(filterNew.counters.data, DeepEquals, filter.counters.data)
+	c.Assert(bloomfilter.SeedOf(filterNew.opts...), Equals, uint64(3))
+	c.Assert(bloomfilter.IndexModeOf(filterNew.opts...), Equals, bloomfilter.IndexDoubleHashing)
+	c.Assert(bloomfilter.HashStrategyOf(filterNew.opts...), Equals, bloomfilter.HashXXHash64)
+	c.Assert(filterNew.index.ErrorRate(), Equals, filter.index.ErrorRate())
+	c.Assert(filterNew.index.Capacity(), Equals, filter.index.Capacity())
+
+	// file has no bits of index
+	data, err := os.ReadFile(fileName)
+	c.Assert(err, IsNil)
+	saved := bytes.NewBuffer([]byte{})
+	c.Assert(filterNew.ToBytes(saved), IsNil)
+	c.Assert(saved.Bytes(), DeepEquals, data)
+	c.Assert(len(data)-len(filter.counters.data), Equals, len(magic)+24+2+len(bloomfilter.HashXXHash64)+44)
+
+	for _, s := range testArray {
+		c.Assert(filterNew.Remove([]byte(s)), Equals, true)
+	}
+	c.Assert(filterNew.Count(), Equals, int64(0))
+
+	// saved filter and new one are compatible
+	c.Assert(filter.Merge(filterNew), IsNil)
+
+	_, err = FromReader(bufio.NewReader(bytes.NewReader(data[1:])))
+	c.Assert(err, NotNil)
+
+	for _, size := range []int{20, 40, 60, len(data) - 1} {
+		_, err = FromReader(bufio.NewReader(bytes.NewReader(data[:size])))
+		c.Assert(err, NotNil)
+	}
+}
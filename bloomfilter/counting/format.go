@@ -0,0 +1,189 @@
+package counting
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/binary"
+	"fmt"
+	"io"
+	"math"
+	"os"
+
+	"github.com/iostrovok/go-bloom-filter/bloomfilter"
+)
+
+/*
+	Binary format of counting bloom filter:
+
+	magic         [8]byte  "GoCount\xff"
+	version       uint32   formatVersion
+	counter bits  uint32
+	count         int64
+	overflows     int64
+	hash          uint16 length + name of hash strategy, empty for HashAuto
+	seed          uint64
+	index mode    uint32
+	error rate    float64
+	num slices    uint64
+	bits/slice    uint64
+	capacity      int64
+	counters      (numSlices * bitsPerSlice * counterBits + 7) / 8 bytes
+
+	Index is described by its parameters only, its bits are made of counters (see ToBloomFilter).
+*/
+
+const formatVersion = uint32(2)
+
+var magic = []byte{'G', 'o', 'C', 'o', 'u', 'n', 't', 0xff}
+
+type header struct {
+	Version     uint32
+	CounterBits uint32
+	Count       int64
+	Overflows   int64
+}
+
+type indexParams struct {
+	Seed         uint64
+	IndexMode    uint32
+	ErrorRate    float64
+	NumSlices    uint64
+	BitsPerSlice uint64
+	Capacity     int64
+}
+
+// ToFile saves counting bloom filter to file by file name.
+func (f *Filter) ToFile(fileName string) error {
+	file, err := os.Create(fileName)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+
+	binBuf := bytes.NewBuffer([]byte{})
+	if err := f.ToBytes(binBuf); err != nil {
+		return err
+	}
+
+	_, err = file.Write(binBuf.Bytes())
+	return err
+}
+
+// ToBytes returns binary image of counting bloom filter.
+// Index is saved by its parameters, so store of options is not called.
+func (f *Filter) ToBytes(binBuf *bytes.Buffer) error {
+
+	f.mc.RLock()
+	defer f.mc.RUnlock()
+
+	hash := bloomfilter.HashStrategyOf(f.opts...)
+
+	binBuf.Write(magic)
+	binary.Write(binBuf, binary.LittleEndian, header{
+		Version:     formatVersion,
+		CounterBits: uint32(f.counters.bits),
+		Count:       f.count,
+		Overflows:   f.overflows,
+	})
+	binary.Write(binBuf, binary.LittleEndian, uint16(len(hash)))
+	binBuf.WriteString(string(hash))
+	binary.Write(binBuf, binary.LittleEndian, indexParams{
+		Seed:         bloomfilter.SeedOf(f.opts...),
+		IndexMode:    uint32(bloomfilter.IndexModeOf(f.opts...)),
+		ErrorRate:    f.index.ErrorRate(),
+		NumSlices:    uint64(f.index.NumSlices()),
+		BitsPerSlice: f.index.BitsPerSlice(),
+		Capacity:     f.index.Capacity(),
+	})
+
+	_, err := binBuf.Write(f.counters.data)
+	return err
+}
+
+// FromFile creates new counting bloom filter from file
+func FromFile(fileName string) (*Filter, error) {
+
+	file, err := os.Open(fileName)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+
+	return FromReader(bufio.NewReader(file))
+}
+
+// FromReader creates new counting bloom filter from bufio.Reader
+func FromReader(reader *bufio.Reader) (*Filter, error) {
+
+	b := make([]byte, len(magic), len(magic))
+	if _, err := io.ReadFull(reader, b); err != nil {
+		return nil, err
+	}
+
+	if !bytes.Equal(b, magic) {
+		return nil, fmt.Errorf("wrong format of counting bloom filter")
+	}
+
+	var h header
+	if err := binary.Read(reader, binary.LittleEndian, &h); err != nil {
+		return nil, err
+	}
+
+	if h.Version != formatVersion {
+		return nil, fmt.Errorf("unknown format version: %d", h.Version)
+	}
+
+	if h.CounterBits < 2 || h.CounterBits > 8 {
+		return nil, fmt.Errorf("wrong counter bits: %d", h.CounterBits)
+	}
+
+	var nameLength uint16
+	if err := binary.Read(reader, binary.LittleEndian, &nameLength); err != nil {
+		return nil, err
+	}
+
+	name := make([]byte, nameLength)
+	if _, err := io.ReadFull(reader, name); err != nil {
+		return nil, err
+	}
+
+	var p indexParams
+	if err := binary.Read(reader, binary.LittleEndian, &p); err != nil {
+		return nil, err
+	}
+
+	if p.NumSlices < 1 || p.BitsPerSlice < 1 || p.BitsPerSlice > math.MaxInt64/8/p.NumSlices || p.Capacity < 0 {
+		return nil, fmt.Errorf("wrong sizes of counting bloom filter")
+	}
+
+	// options which create the same sizes and hashing as saved filter
+	opts := []bloomfilter.Option{
+		bloomfilter.WithKM(int(p.NumSlices), p.NumSlices*p.BitsPerSlice),
+		bloomfilter.WithErrorRate(p.ErrorRate),
+		bloomfilter.WithSeed(p.Seed),
+		bloomfilter.WithIndexMode(bloomfilter.IndexMode(p.IndexMode)),
+	}
+
+	if len(name) > 0 {
+		hasher, find := bloomfilter.LookupHasher(string(name))
+		if !find {
+			return nil, fmt.Errorf("unknown hash function: %q", name)
+		}
+		opts = append(opts, bloomfilter.WithHasher(hasher))
+	}
+
+	index, err := bloomfilter.NewWithOptions(p.Capacity, indexOptions(opts)...)
+	if err != nil {
+		return nil, err
+	}
+
+	f := newFilter(index, opts, uint(h.CounterBits))
+	if _, err := io.ReadFull(reader, f.counters.data); err != nil {
+		return nil, err
+	}
+
+	f.count = h.Count
+	f.overflows = h.Overflows
+
+	return f, nil
+}
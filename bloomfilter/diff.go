@@ -0,0 +1,56 @@
+package bloomfilter
+
+// DiffReport describes bits which are set in one of two filters only.
+type DiffReport struct {
+	// Slices keeps number of differing bits for every slice
+	Slices []uint64
+	// Positions keeps the first differing bits in ascending order, see limit of Diff
+	Positions []uint64
+	// Total is number of differing bits
+	Total uint64
+}
+
+// Equal is a "getter". Returns true if there are no differing bits.
+func (r *DiffReport) Equal() bool {
+	return r.Total == 0
+}
+
+// Equal returns true if filters have the same parameters and the same bits.
+// Count of keys is not compared, it may differ for replicas which get the same keys twice.
+// Bits are compared word by word, so filters are not copied.
+func Equal(a, b *BloomFilter) bool {
+
+	if err := a.compare(b); err != nil {
+		return false
+	}
+
+	return xorPopCounter(a.bitarray, b.bitarray)(0, a.numBits) == 0
+}
+
+// Diff returns differing bits of filters with the same parameters.
+// Positions of report keeps not more than limit first positions. Filters are not copied.
+func Diff(a, b *BloomFilter, limit int) (*DiffReport, error) {
+
+	if err := a.compare(b); err != nil {
+		return nil, err
+	}
+
+	count := xorPopCounter(a.bitarray, b.bitarray)
+
+	out := &DiffReport{
+		Slices: make([]uint64, a.numSlices, a.numSlices),
+	}
+
+	for i := range out.Slices {
+		begin := uint64(i) * a.bitsPerSlice
+		out.Slices[i] = count(begin, begin+a.bitsPerSlice)
+		out.Total += out.Slices[i]
+	}
+
+	out.Positions = []uint64{}
+	if out.Total > 0 && limit > 0 {
+		out.Positions = xorPositions(a.bitarray, b.bitarray, limit)
+	}
+
+	return out, nil
+}
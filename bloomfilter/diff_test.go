@@ -0,0 +1,80 @@
+package bloomfilter
+
+import (
+	"bufio"
+	"bytes"
+
+	"github.com/iostrovok/go-bloom-filter/bloomfilter/fortesting"
+	. "gopkg.in/check.v1"
+)
+
+func (s *filterTestSuite) TestEqualDiff(c *C) {
+
+	testArray := fortesting.ArrayForTesting()
+
+	filterA, err := New(int64(len(testArray)), 0.001)
+	c.Assert(err, IsNil)
+	filterB, err := NewWithOptions(int64(len(testArray)), WithErrorRate(0.001), WithConcurrency(ConcurrencyAtomic))
+	c.Assert(err, IsNil)
+
+	for _, s := range testArray {
+		_, err := filterA.Add([]byte(s))
+		c.Assert(err, IsNil)
+		_, err = filterB.Add([]byte(s))
+		c.Assert(err, IsNil)
+	}
+
+	c.Assert(Equal(filterA, filterB), Equals, true)
+
+	binBuf := bytes.NewBuffer([]byte{})
+	c.Assert(filterA.ToBytes(binBuf), IsNil)
+	loaded, err := FromReader(bufio.NewReader(binBuf), 0)
+	c.Assert(err, IsNil)
+	c.Assert(Equal(filterA, loaded), Equals, true)
+
+	report, err := Diff(filterA, loaded, 10)
+	c.Assert(err, IsNil)
+	c.Assert(report.Equal(), Equals, true)
+	c.Assert(report.Positions, HasLen, 0)
+	c.Assert(report.Slices, HasLen, filterA.NumSlices())
+
+	_, err = filterB.Add([]byte("new key"), true)
+	c.Assert(err, IsNil)
+	c.Assert(Equal(filterA, filterB), Equals, false)
+
+	report, err = Diff(filterA, filterB, 3)
+	c.Assert(err, IsNil)
+	c.Assert(report.Equal(), Equals, false)
+	c.Assert(report.Total <= uint64(filterA.NumSlices()), Equals, true)
+	c.Assert(len(report.Positions) <= 3, Equals, true)
+
+	bySlice := make([]uint64, filterA.NumSlices())
+	all, err := Diff(filterA, filterB, 1000)
+	c.Assert(err, IsNil)
+	c.Assert(uint64(len(all.Positions)), Equals, all.Total)
+	for i, pos := range all.Positions {
+		if i > 0 {
+			c.Assert(pos > all.Positions[i-1], Equals, true)
+		}
+		c.Assert(filterB.bitarray.Get(pos), Equals, true)
+		c.Assert(filterA.bitarray.Get(pos), Equals, false)
+		bySlice[pos/filterA.BitsPerSlice()]++
+	}
+	c.Assert(bySlice, DeepEquals, all.Slices)
+	c.Assert(report.Positions, DeepEquals, all.Positions[:len(report.Positions)])
+
+	// arrays of the same type are compared without copies
+	_, err = loaded.Add([]byte("new key"), true)
+	c.Assert(err, IsNil)
+	c.Assert(Equal(filterA, loaded), Equals, false)
+	same, err := Diff(filterA, loaded, 1000)
+	c.Assert(err, IsNil)
+	c.Assert(same, DeepEquals, all)
+	c.Assert(Equal(filterB, loaded), Equals, true)
+
+	other, err := New(int64(len(testArray)), 0.01)
+	c.Assert(err, IsNil)
+	c.Assert(Equal(filterA, other), Equals, false)
+	_, err = Diff(filterA, other, 10)
+	c.Assert(err, NotNil)
+}
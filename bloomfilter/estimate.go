@@ -0,0 +1,72 @@
+package bloomfilter
+
+import (
+	"math"
+)
+
+// EstimateCardinality returns number of keys in filter which is estimated by
+// number of set bits (Swamidass & Baldi, 2007). Every key sets one bit in every slice,
+// so estimation for slice with X set bits is -bitsPerSlice * ln(1 - X / bitsPerSlice).
+// Result is an average for all slices. It works for merged and read from file filters too.
+// Returns +Inf if some slice is full.
+func (bf *BloomFilter) EstimateCardinality() float64 {
+	return bf.estimateCardinality(bf.slicesPopCount())
+}
+
+// estimateCardinality returns number of keys by numbers of set bits of slices.
+func (bf *BloomFilter) estimateCardinality(popCounts []uint64) float64 {
+
+	total := 0.0
+	for _, x := range popCounts {
+		total += estimateSlice(x, bf.bitsPerSlice)
+	}
+
+	return total / float64(bf.numSlices)
+}
+
+// FillRatio returns part of set bits in filter.
+func (bf *BloomFilter) FillRatio() float64 {
+	return float64(counterOf(bf.bitarray).PopCount()) / float64(bf.numBits)
+}
+
+// EstimatedFPR returns false positive rate which is calculated by real set bits.
+// Unknown key is found if it hits set bit in every slice, so rate is a product
+// of fill ratios of all slices. It may be compared with ErrorRate() for overfilled or merged filters.
+func (bf *BloomFilter) EstimatedFPR() float64 {
+
+	res := 1.0
+	for _, x := range bf.slicesPopCount() {
+		res *= float64(x) / float64(bf.bitsPerSlice)
+	}
+
+	return res
+}
+
+// slicesPopCount returns number of set bits for every slice.
+func (bf *BloomFilter) slicesPopCount() []uint64 {
+	return bf.slicesCount(counterOf(bf.bitarray).PopCountRange)
+}
+
+// unionSlicesPopCount returns number of set bits of union of filters for every slice.
+// Union is not created, so no copy of bits is made.
+func (bf *BloomFilter) unionSlicesPopCount(other *BloomFilter) []uint64 {
+	return bf.slicesCount(orPopCounter(bf.bitarray, other.bitarray))
+}
+
+// slicesCount returns result of count for range of every slice.
+func (bf *BloomFilter) slicesCount(count func(begin, end uint64) uint64) []uint64 {
+	out := make([]uint64, bf.numSlices, bf.numSlices)
+	for i := range out {
+		begin := uint64(i) * bf.bitsPerSlice
+		out[i] = count(begin, begin+bf.bitsPerSlice)
+	}
+	return out
+}
+
+func estimateSlice(setBits, bitsPerSlice uint64) float64 {
+	if setBits >= bitsPerSlice {
+		return math.Inf(1)
+	}
+	m := float64(bitsPerSlice)
+	return -m * math.Log1p(-float64(setBits)/m)
+}
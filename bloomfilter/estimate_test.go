@@ -0,0 +1,83 @@
+package bloomfilter
+
+import (
+	"bufio"
+	"bytes"
+	"math"
+
+	"github.com/iostrovok/go-bloom-filter/bloomfilter/fortesting"
+	. "gopkg.in/check.v1"
+)
+
+func (s *filterTestSuite) TestEstimateCardinality(c *C) {
+
+	testArray := fortesting.ArrayForTesting()
+	half := len(testArray) / 2
+
+	filterA, err := New(int64(len(testArray)), 0.001)
+	c.Assert(err, IsNil)
+	c.Assert(filterA.EstimateCardinality(), Equals, float64(0))
+
+	filterB, err := New(int64(len(testArray)), 0.001)
+	c.Assert(err, IsNil)
+
+	for _, s := range testArray[:half] {
+		_, err := filterA.Add([]byte(s))
+		c.Assert(err, IsNil)
+	}
+	for _, s := range testArray[half:] {
+		_, err := filterB.Add([]byte(s))
+		c.Assert(err, IsNil)
+	}
+
+	estimate := filterA.EstimateCardinality()
+	c.Assert(math.Abs(estimate-float64(half)) < 0.05*float64(half), Equals, true, Commentf("%f", estimate))
+
+	c.Assert(filterA.Merge(filterB), IsNil)
+	c.Assert(filterA.Count(), Equals, int64(half))
+
+	estimate = filterA.EstimateCardinality()
+	total := float64(len(testArray))
+	c.Assert(math.Abs(estimate-total) < 0.05*total, Equals, true, Commentf("%f", estimate))
+
+	binBuf := bytes.NewBuffer([]byte{})
+	c.Assert(filterA.ToBytes(binBuf), IsNil)
+	filterNew, err := FromReader(bufio.NewReader(binBuf), 0)
+	c.Assert(err, IsNil)
+	c.Assert(filterNew.EstimateCardinality(), Equals, estimate)
+
+	full, err := NewWithOptions(10, WithKM(2, 4), WithOverflowPolicy(OverflowIgnore))
+	c.Assert(err, IsNil)
+	for _, s := range testArray[:100] {
+		_, err := full.Add([]byte(s))
+		c.Assert(err, IsNil)
+	}
+	c.Assert(math.IsInf(full.EstimateCardinality(), 1), Equals, true)
+}
+
+func (s *filterTestSuite) TestEstimatedFPR(c *C) {
+
+	testArray := fortesting.ArrayForTesting()
+
+	filter, err := NewWithOptions(int64(len(testArray)), WithErrorRate(0.01), WithOverflowPolicy(OverflowIgnore))
+	c.Assert(err, IsNil)
+	c.Assert(filter.FillRatio(), Equals, float64(0))
+	c.Assert(filter.EstimatedFPR(), Equals, float64(0))
+
+	for _, s := range testArray {
+		_, err := filter.Add([]byte(s))
+		c.Assert(err, IsNil)
+	}
+
+	// optimal filter at capacity has a half of set bits
+	c.Assert(math.Abs(filter.FillRatio()-0.5) < 0.05, Equals, true, Commentf("%f", filter.FillRatio()))
+	c.Assert(filter.EstimatedFPR() < 0.015, Equals, true, Commentf("%f", filter.EstimatedFPR()))
+	c.Assert(filter.EstimatedFPR() > 0.005, Equals, true, Commentf("%f", filter.EstimatedFPR()))
+
+	// overfilled filter
+	for _, s := range testArray {
+		_, err := filter.Add([]byte(s + "-more"))
+		c.Assert(err, IsNil)
+	}
+	c.Assert(filter.EstimatedFPR() > 0.05, Equals, true, Commentf("%f", filter.EstimatedFPR()))
+}
@@ -0,0 +1,257 @@
+package bloomfilter
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/binary"
+	"fmt"
+	"hash/crc32"
+	"io"
+	"math"
+)
+
+/*
+	Extended header is written before python-bloomfilter header when filter
+	uses options which python-bloomfilter format can not describe.
+
+	magic      [8]byte  "GoBloom\xff", invalid float64 for python-bloomfilter error rate
+	version    uint32   extFormatVersion
+	hasher     uint16 length + name
+	seed       uint64
+	index mode uint32
+*/
+
+const extFormatVersion = uint32(1)
+
+var extMagic = []byte{'G', 'o', 'B', 'l', 'o', 'o', 'm', 0xff}
+
+func writeExtHeader(binBuf *bytes.Buffer, bf *BloomFilter) {
+	binBuf.Write(extMagic)
+	binary.Write(binBuf, binary.LittleEndian, extFormatVersion)
+	binary.Write(binBuf, binary.LittleEndian, uint16(len(bf.hasher.Name())))
+	binBuf.WriteString(bf.hasher.Name())
+	binary.Write(binBuf, binary.LittleEndian, bf.opts.seed)
+	binary.Write(binBuf, binary.LittleEndian, uint32(bf.opts.indexMode))
+}
+
+// readExtHeader reads extended header after magic into options.
+// Returns length of read header with magic.
+func readExtHeader(reader io.Reader, o *options) (int64, error) {
+
+	var header struct {
+		Version    uint32
+		NameLength uint16
+	}
+	if err := binary.Read(reader, binary.LittleEndian, &header); err != nil {
+		return 0, err
+	}
+
+	if header.Version != extFormatVersion {
+		return 0, fmt.Errorf("unknown format version: %d", header.Version)
+	}
+
+	name := make([]byte, header.NameLength)
+	if _, err := io.ReadFull(reader, name); err != nil {
+		return 0, err
+	}
+
+	hasher, find := LookupHasher(string(name))
+	if !find {
+		return 0, fmt.Errorf("unknown hash function: %q", name)
+	}
+
+	var tail struct {
+		Seed      uint64
+		IndexMode uint32
+	}
+	if err := binary.Read(reader, binary.LittleEndian, &tail); err != nil {
+		return 0, err
+	}
+
+	if tail.IndexMode > uint32(IndexPybloom) {
+		return 0, fmt.Errorf("unknown index mode: %d", tail.IndexMode)
+	}
+
+	o.hashStrategy = HashStrategy(hasher.Name())
+	o.hasher = hasher
+	o.seed = tail.Seed
+	o.indexMode = IndexMode(tail.IndexMode)
+
+	return int64(len(extMagic) + 4 + 2 + len(name) + 8 + 4), nil
+}
+
+/*
+	Format v2 is self-describing and is protected by checksum. It is written for filters
+	with WithFileFormat(FormatV2), FromReader and FromMapping detect it by magic.
+
+	magic        [8]byte  "GoBloom\xfe", invalid float64 for python-bloomfilter error rate
+	version      uint32   v2FormatVersion
+	bit layout   uint32   layoutSlices
+	hash         uint16 length + name of hash strategy, empty for HashAuto
+	seed         uint64
+	index mode   uint32
+	error rate   float64
+	num slices   uint64
+	bits/slice   uint64
+	capacity     int64
+	count        int64
+	bits size    uint64   number of bytes of bits
+	bits         [bits size]byte
+	crc32c       uint32   Castagnoli checksum of all bytes before it
+*/
+
+const v2FormatVersion = uint32(2)
+
+// layoutSlices keeps slices one by one, bit i of filter is bit i%8 of byte i/8.
+const layoutSlices = uint32(0)
+
+var v2Magic = []byte{'G', 'o', 'B', 'l', 'o', 'o', 'm', 0xfe}
+
+var castagnoli = crc32.MakeTable(crc32.Castagnoli)
+
+type v2Params struct {
+	Seed         uint64
+	IndexMode    uint32
+	ErrorRate    float64
+	NumSlices    uint64
+	BitsPerSlice uint64
+	Capacity     int64
+	Count        int64
+	BitsSize     uint64
+}
+
+// v2TailLen is length of count and bits size, they are the last fields of header.
+const v2TailLen = 16
+
+// writeV2Header writes all fields of format v2 before bits.
+func writeV2Header(binBuf *bytes.Buffer, bf *BloomFilter) {
+	binBuf.Write(v2Magic)
+	binary.Write(binBuf, binary.LittleEndian, v2FormatVersion)
+	binary.Write(binBuf, binary.LittleEndian, layoutSlices)
+	binary.Write(binBuf, binary.LittleEndian, uint16(len(bf.opts.hashStrategy)))
+	binBuf.WriteString(string(bf.opts.hashStrategy))
+	binary.Write(binBuf, binary.LittleEndian, v2Params{
+		Seed:         bf.opts.seed,
+		IndexMode:    uint32(bf.opts.indexMode),
+		ErrorRate:    bf.errorRate,
+		NumSlices:    uint64(bf.numSlices),
+		BitsPerSlice: bf.bitsPerSlice,
+		Capacity:     bf.capacity,
+		Count:        bf.Count(),
+		BitsSize:     (bf.numBits + 7) / 8,
+	})
+}
+
+// isV2 returns true if data of reader starts with magic of format v2.
+func isV2(reader *bufio.Reader) bool {
+	b, err := reader.Peek(len(v2Magic))
+	return err == nil && bytes.Equal(b, v2Magic)
+}
+
+// readV2 reads filter in format v2 and checks its checksum. Bytes after filter are not read.
+func readV2(reader io.Reader) (*BloomFilter, error) {
+
+	crc := crc32.New(castagnoli)
+	tee := io.TeeReader(reader, crc)
+
+	bf, _, err := readV2Header(tee)
+	if err != nil {
+		return nil, err
+	}
+
+	if bf.bitarray, err = newBitArray(bf.opts, bf.numBits); err != nil {
+		return nil, err
+	}
+
+	if err := readBits(bf, tee); err != nil {
+		return nil, err
+	}
+
+	var sum uint32
+	if err := binary.Read(reader, binary.LittleEndian, &sum); err != nil {
+		return nil, err
+	}
+
+	if sum != crc.Sum32() {
+		return nil, checksumError
+	}
+
+	return bf, nil
+}
+
+// readV2Header reads header of format v2 and creates filter without bit array.
+// Returns length of header.
+func readV2Header(reader io.Reader) (*BloomFilter, int64, error) {
+
+	var header struct {
+		Magic      [8]byte
+		Version    uint32
+		Layout     uint32
+		NameLength uint16
+	}
+	if err := binary.Read(reader, binary.LittleEndian, &header); err != nil {
+		return nil, 0, err
+	}
+
+	if !bytes.Equal(header.Magic[:], v2Magic) {
+		return nil, 0, fmt.Errorf("wrong format of bloom filter")
+	}
+
+	if header.Version != v2FormatVersion {
+		return nil, 0, fmt.Errorf("unknown format version: %d", header.Version)
+	}
+
+	if header.Layout != layoutSlices {
+		return nil, 0, fmt.Errorf("unknown bit layout: %d", header.Layout)
+	}
+
+	name := make([]byte, header.NameLength)
+	if _, err := io.ReadFull(reader, name); err != nil {
+		return nil, 0, err
+	}
+
+	var params v2Params
+	if err := binary.Read(reader, binary.LittleEndian, &params); err != nil {
+		return nil, 0, err
+	}
+
+	o := defaultOptions()
+	o.errorRate = params.ErrorRate
+	o.seed = params.Seed
+	o.indexMode = IndexMode(params.IndexMode)
+	o.format = FormatV2
+
+	if len(name) > 0 {
+		hasher, find := LookupHasher(string(name))
+		if !find {
+			return nil, 0, fmt.Errorf("unknown hash function: %q", name)
+		}
+		o.hashStrategy = HashStrategy(hasher.Name())
+		o.hasher = hasher
+	}
+
+	bf, err := newFromParams(o, params)
+	if err != nil {
+		return nil, 0, err
+	}
+
+	return bf, int64(binary.Size(header) + len(name) + binary.Size(params)), nil
+}
+
+// newFromParams checks options and saved sizes and creates filter without bit array.
+func newFromParams(o options, params v2Params) (*BloomFilter, error) {
+
+	if err := o.check(); err != nil {
+		return nil, err
+	}
+
+	if params.NumSlices < 1 || params.BitsPerSlice < 1 || params.BitsPerSlice > math.MaxUint64/8/params.NumSlices ||
+		params.BitsSize != (params.NumSlices*params.BitsPerSlice+7)/8 {
+		return nil, fmt.Errorf("wrong sizes of bloom filter")
+	}
+
+	bf := &BloomFilter{opts: o}
+	bf.setup(params.ErrorRate, params.BitsPerSlice, int(params.NumSlices), params.Capacity, params.Count)
+
+	return bf, nil
+}
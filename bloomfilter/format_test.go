@@ -0,0 +1,94 @@
+package bloomfilter
+
+import (
+	"bufio"
+	"bytes"
+	"io"
+	"os"
+	"path/filepath"
+
+	"github.com/iostrovok/go-bloom-filter/bloomfilter/fortesting"
+	. "gopkg.in/check.v1"
+)
+
+func (s *filterTestSuite) TestFormatV2(c *C) {
+
+	testArray := fortesting.ArrayForTesting()
+
+	for _, opts := range [][]Option{
+		{},
+		{WithHashStrategy(HashXXHash64), WithIndexMode(IndexDoubleHashing), WithSeed(7)},
+		{WithKM(5, 100000)},
+		{WithConcurrency(ConcurrencyAtomic)},
+	} {
+		filter, err := NewWithOptions(int64(len(testArray)), append(opts, WithFileFormat(FormatV2))...)
+		c.Assert(err, IsNil)
+		c.Assert(filter.FileFormat(), Equals, FormatV2)
+
+		for _, s := range testArray {
+			_, err := filter.Add([]byte(s))
+			c.Assert(err, IsNil)
+		}
+
+		binBuf := bytes.NewBuffer([]byte{})
+		c.Assert(filter.ToBytes(binBuf), IsNil)
+		data := binBuf.Bytes()
+		c.Assert(data[:len(v2Magic)], DeepEquals, v2Magic)
+
+		loaded, err := FromReader(bufio.NewReader(bytes.NewReader(data)), 0)
+		c.Assert(err, IsNil)
+		c.Assert(Equal(filter, loaded), Equals, true)
+		c.Assert(loaded.Count(), Equals, filter.Count())
+		c.Assert(loaded.ErrorRate(), Equals, filter.ErrorRate())
+		c.Assert(loaded.FileFormat(), Equals, FormatV2)
+		c.Assert(loaded.hasher.Name(), Equals, filter.hasher.Name())
+		c.Assert(loaded.PybloomCompatible(), Equals, filter.PybloomCompatible())
+
+		again := bytes.NewBuffer([]byte{})
+		c.Assert(loaded.ToBytes(again), IsNil)
+		c.Assert(again.Bytes(), DeepEquals, data)
+
+		// every changed byte is found by checksum
+		for _, i := range []int{len(v2Magic) + 4, 40, len(data) / 2, len(data) - 1} {
+			broken := append([]byte{}, data...)
+			broken[i] ^= 0x10
+			_, err = FromReader(bufio.NewReader(bytes.NewReader(broken)), 0)
+			c.Assert(err, NotNil)
+		}
+
+		_, err = FromReader(bufio.NewReader(bytes.NewReader(data[:len(data)-5])), 0)
+		c.Assert(err, NotNil)
+
+		fileName := filepath.Join(c.MkDir(), "v2.bin")
+		c.Assert(filter.ToFile(fileName), IsNil)
+		loaded, err = FromFile(fileName)
+		c.Assert(err, IsNil)
+		c.Assert(Equal(filter, loaded), Equals, true)
+	}
+
+	// legacy format is default and is still read
+	filter, err := New(int64(len(testArray)))
+	c.Assert(err, IsNil)
+	c.Assert(filter.FileFormat(), Equals, FormatLegacy)
+	binBuf := bytes.NewBuffer([]byte{})
+	c.Assert(filter.ToBytes(binBuf), IsNil)
+	legacy := binBuf.Bytes()
+	loaded, err := FromReader(bufio.NewReader(bytes.NewReader(legacy)), 0)
+	c.Assert(err, IsNil)
+	c.Assert(loaded.FileFormat(), Equals, FormatLegacy)
+
+	// truncated legacy file is not loaded
+	for _, size := range []int{len(legacy) - 1, len(legacy) / 2, 41} {
+		_, err = FromReader(bufio.NewReader(bytes.NewReader(legacy[:size])), 0)
+		c.Assert(err, Equals, io.ErrUnexpectedEOF)
+		_, err = FromReader(bufio.NewReader(bytes.NewReader(legacy)), int64(size))
+		c.Assert(err, Equals, io.ErrUnexpectedEOF)
+	}
+	fileName := filepath.Join(c.MkDir(), "truncated.bin")
+	c.Assert(os.WriteFile(fileName, legacy[:len(legacy)-3], 0o644), IsNil)
+	_, err = FromFile(fileName)
+	c.Assert(err, Equals, io.ErrUnexpectedEOF)
+
+	_, err = NewWithOptions(100, WithFileFormat(FileFormat(10)))
+	c.Assert(err, NotNil)
+}
@@ -0,0 +1,94 @@
+package fortesting
+
+import (
+	"compress/gzip"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+
+	t "gopkg.in/check.v1"
+)
+
+// PybloomCase describes golden file of python-bloomfilter BloomFilter, see testfiles/pybloom/generate.py.
+type PybloomCase struct {
+	Name         string  `json:"name"`
+	Capacity     int64   `json:"capacity"`
+	ErrorRate    float64 `json:"error_rate"`
+	Keys         int     `json:"keys"`
+	NumSlices    int     `json:"num_slices"`
+	BitsPerSlice uint64  `json:"bits_per_slice"`
+	Hash         string  `json:"hash"`
+	Count        int64   `json:"count"`
+}
+
+// PybloomScalableCase describes golden file of python-bloomfilter ScalableBloomFilter.
+type PybloomScalableCase struct {
+	Name            string   `json:"name"`
+	InitialCapacity int      `json:"initial_capacity"`
+	ErrorRate       float64  `json:"error_rate"`
+	Mode            int      `json:"mode"`
+	Keys            int      `json:"keys"`
+	Filters         int      `json:"filters"`
+	Hashes          []string `json:"hashes"`
+}
+
+// PybloomCases is content of testfiles/pybloom/cases.json.
+type PybloomCases struct {
+	Source   string                `json:"source"`
+	Version  string                `json:"version"`
+	Plain    []PybloomCase         `json:"plain"`
+	Scalable []PybloomScalableCase `json:"scalable"`
+}
+
+// PybloomIndexes is positions of keys which python-bloomfilter calculates, see testfiles/pybloom/indexes.json.
+type PybloomIndexes struct {
+	NumSlices    int        `json:"num_slices"`
+	BitsPerSlice uint64     `json:"bits_per_slice"`
+	Hash         string     `json:"hash"`
+	Keys         []string   `json:"keys"`
+	Indexes      [][]uint64 `json:"indexes"`
+}
+
+// LoadPybloomCases reads description of golden files.
+func LoadPybloomCases(c *t.C) PybloomCases {
+	var out PybloomCases
+	loadPybloomJSON(c, "cases.json", &out)
+	return out
+}
+
+// LoadPybloomIndexes reads positions of keys.
+func LoadPybloomIndexes(c *t.C) []PybloomIndexes {
+	var out []PybloomIndexes
+	loadPybloomJSON(c, "indexes.json", &out)
+	return out
+}
+
+// ReadPybloomFile returns unpacked golden file.
+func ReadPybloomFile(c *t.C, name string) []byte {
+	file, err := os.Open(Dir() + "/pybloom/" + name)
+	c.Assert(err, t.IsNil)
+	defer file.Close()
+
+	gz, err := gzip.NewReader(file)
+	c.Assert(err, t.IsNil)
+
+	data, err := io.ReadAll(gz)
+	c.Assert(err, t.IsNil)
+	return data
+}
+
+// PybloomKeys returns keys of golden file, they are made by keys() of generate.py.
+func PybloomKeys(prefix string, n int) []string {
+	out := make([]string, n, n)
+	for i := range out {
+		out[i] = fmt.Sprintf("%s-%d", prefix, i)
+	}
+	return out
+}
+
+func loadPybloomJSON(c *t.C, name string, v interface{}) {
+	data, err := os.ReadFile(Dir() + "/pybloom/" + name)
+	c.Assert(err, t.IsNil)
+	c.Assert(json.Unmarshal(data, v), t.IsNil)
+}
@@ -0,0 +1,396 @@
+package bloomfilter
+
+import (
+	"crypto/md5"
+	"crypto/sha1"
+	"crypto/sha256"
+	"crypto/sha512"
+	"encoding/binary"
+	"fmt"
+	"math/bits"
+	"sync"
+)
+
+// Hasher is a hash function which is used to find bit positions for a key.
+// Implementations must be safe for concurrent use.
+type Hasher interface {
+	// Name returns unique name of hash function. Filters are matched by name.
+	Name() string
+	// Size returns number of bytes which Sum appends.
+	Size() int
+	// Sum appends digest of data to dst and returns the resulting slice.
+	Sum(dst, data []byte) []byte
+}
+
+var (
+	// MD5 is md5 hasher
+	MD5 Hasher = cryptoHasher{name: "md5", size: md5.Size}
+	// SHA1 is sha1 hasher
+	SHA1 Hasher = cryptoHasher{name: "sha1", size: sha1.Size}
+	// SHA256 is sha256 hasher
+	SHA256 Hasher = cryptoHasher{name: "sha256", size: sha256.Size}
+	// SHA384 is sha384 hasher
+	SHA384 Hasher = cryptoHasher{name: "sha384", size: sha512.Size384}
+	// SHA512 is sha512 hasher
+	SHA512 Hasher = cryptoHasher{name: "sha512", size: sha512.Size}
+
+	// FNV1a64 is 64 bits FNV-1a hasher
+	FNV1a64 Hasher = fnv1a64Hasher{}
+	// Murmur3 is 128 bits x64 MurmurHash3 hasher
+	Murmur3 Hasher = murmur3Hasher{}
+	// XXHash64 is 64 bits xxHash hasher
+	XXHash64 Hasher = xxhash64Hasher{}
+)
+
+var hashers = struct {
+	mc   sync.RWMutex
+	list map[string]Hasher
+}{
+	list: map[string]Hasher{},
+}
+
+func init() {
+	for _, h := range []Hasher{MD5, SHA1, SHA256, SHA384, SHA512, FNV1a64, Murmur3, XXHash64} {
+		if err := RegisterHasher(h); err != nil {
+			panic(err)
+		}
+	}
+}
+
+// RegisterHasher adds hasher to list of known hashers. Filters created
+// with custom hasher may be loaded from file only if hasher is registered.
+func RegisterHasher(h Hasher) error {
+
+	if h.Name() == "" {
+		return fmt.Errorf("hasher name is empty")
+	}
+
+	if h.Size() < 8 {
+		return fmt.Errorf("hasher %s: size must be >= 8", h.Name())
+	}
+
+	hashers.mc.Lock()
+	defer hashers.mc.Unlock()
+
+	if _, find := hashers.list[h.Name()]; find {
+		return fmt.Errorf("hasher %s is already registered", h.Name())
+	}
+
+	hashers.list[h.Name()] = h
+	return nil
+}
+
+// LookupHasher returns registered hasher by name.
+func LookupHasher(name string) (Hasher, bool) {
+	hashers.mc.RLock()
+	defer hashers.mc.RUnlock()
+
+	h, find := hashers.list[name]
+	return h, find
+}
+
+// pybloomHasher returns hasher which python-bloomfilter uses for totalHashBits.
+func pybloomHasher(totalHashBits int) Hasher {
+
+	if totalHashBits > 384 {
+		return SHA512
+	} else if totalHashBits > 256 {
+		return SHA384
+	} else if totalHashBits > 160 {
+		return SHA256
+	} else if totalHashBits > 128 {
+		return SHA1
+	}
+
+	return MD5
+}
+
+// --------------------------------------------------------
+
+type cryptoHasher struct {
+	name string
+	size int
+}
+
+func (h cryptoHasher) Name() string {
+	return h.name
+}
+
+func (h cryptoHasher) Size() int {
+	return h.size
+}
+
+func (h cryptoHasher) Sum(dst, b []byte) []byte {
+
+	switch h.name {
+	case "sha512":
+		t := sha512.Sum512(b)
+		return append(dst, t[:]...)
+	case "sha384":
+		t := sha512.Sum384(b)
+		return append(dst, t[:]...)
+	case "sha256":
+		t := sha256.Sum256(b)
+		return append(dst, t[:]...)
+	case "sha1":
+		t := sha1.Sum(b)
+		return append(dst, t[:]...)
+	case "md5":
+		t := md5.Sum(b)
+		return append(dst, t[:]...)
+	default:
+		// nothing. We will never be here.
+	}
+
+	return dst
+}
+
+// --------------------------------------------------------
+
+const (
+	fnvOffset64 = 14695981039346656037
+	fnvPrime64  = 1099511628211
+)
+
+type fnv1a64Hasher struct{}
+
+func (fnv1a64Hasher) Name() string {
+	return "fnv1a64"
+}
+
+func (fnv1a64Hasher) Size() int {
+	return 8
+}
+
+func (fnv1a64Hasher) Sum(dst, b []byte) []byte {
+	h := uint64(fnvOffset64)
+	for _, c := range b {
+		h ^= uint64(c)
+		h *= fnvPrime64
+	}
+	return binary.LittleEndian.AppendUint64(dst, h)
+}
+
+// --------------------------------------------------------
+
+const (
+	murmurC1 = 0x87c37b91114253d5
+	murmurC2 = 0x4cf5ad432745937f
+)
+
+type murmur3Hasher struct{}
+
+func (murmur3Hasher) Name() string {
+	return "murmur3-128"
+}
+
+func (murmur3Hasher) Size() int {
+	return 16
+}
+
+func (murmur3Hasher) Sum(dst, b []byte) []byte {
+	h1, h2 := murmur3Sum128(b, 0)
+	dst = binary.LittleEndian.AppendUint64(dst, h1)
+	return binary.LittleEndian.AppendUint64(dst, h2)
+}
+
+func murmur3Sum128(b []byte, seed uint64) (uint64, uint64) {
+
+	h1, h2 := seed, seed
+	length := uint64(len(b))
+
+	for len(b) >= 16 {
+		k1 := binary.LittleEndian.Uint64(b)
+		k2 := binary.LittleEndian.Uint64(b[8:])
+		b = b[16:]
+
+		k1 *= murmurC1
+		k1 = bits.RotateLeft64(k1, 31)
+		k1 *= murmurC2
+		h1 ^= k1
+
+		h1 = bits.RotateLeft64(h1, 27)
+		h1 += h2
+		h1 = h1*5 + 0x52dce729
+
+		k2 *= murmurC2
+		k2 = bits.RotateLeft64(k2, 33)
+		k2 *= murmurC1
+		h2 ^= k2
+
+		h2 = bits.RotateLeft64(h2, 31)
+		h2 += h1
+		h2 = h2*5 + 0x38495ab5
+	}
+
+	var k1, k2 uint64
+	switch len(b) {
+	case 15:
+		k2 ^= uint64(b[14]) << 48
+		fallthrough
+	case 14:
+		k2 ^= uint64(b[13]) << 40
+		fallthrough
+	case 13:
+		k2 ^= uint64(b[12]) << 32
+		fallthrough
+	case 12:
+		k2 ^= uint64(b[11]) << 24
+		fallthrough
+	case 11:
+		k2 ^= uint64(b[10]) << 16
+		fallthrough
+	case 10:
+		k2 ^= uint64(b[9]) << 8
+		fallthrough
+	case 9:
+		k2 ^= uint64(b[8])
+		k2 *= murmurC2
+		k2 = bits.RotateLeft64(k2, 33)
+		k2 *= murmurC1
+		h2 ^= k2
+		fallthrough
+	case 8:
+		k1 ^= uint64(b[7]) << 56
+		fallthrough
+	case 7:
+		k1 ^= uint64(b[6]) << 48
+		fallthrough
+	case 6:
+		k1 ^= uint64(b[5]) << 40
+		fallthrough
+	case 5:
+		k1 ^= uint64(b[4]) << 32
+		fallthrough
+	case 4:
+		k1 ^= uint64(b[3]) << 24
+		fallthrough
+	case 3:
+		k1 ^= uint64(b[2]) << 16
+		fallthrough
+	case 2:
+		k1 ^= uint64(b[1]) << 8
+		fallthrough
+	case 1:
+		k1 ^= uint64(b[0])
+		k1 *= murmurC1
+		k1 = bits.RotateLeft64(k1, 31)
+		k1 *= murmurC2
+		h1 ^= k1
+	}
+
+	h1 ^= length
+	h2 ^= length
+
+	h1 += h2
+	h2 += h1
+
+	h1 = murmurFmix64(h1)
+	h2 = murmurFmix64(h2)
+
+	h1 += h2
+	h2 += h1
+
+	return h1, h2
+}
+
+func murmurFmix64(k uint64) uint64 {
+	k ^= k >> 33
+	k *= 0xff51afd7ed558ccd
+	k ^= k >> 33
+	k *= 0xc4ceb9fe1a85ec53
+	k ^= k >> 33
+	return k
+}
+
+// --------------------------------------------------------
+
+const (
+	xxPrime1 uint64 = 11400714785074694791
+	xxPrime2 uint64 = 14029467366897019727
+	xxPrime3 uint64 = 1609587929392839161
+	xxPrime4 uint64 = 9650029242287828579
+	xxPrime5 uint64 = 2870177450012600261
+)
+
+type xxhash64Hasher struct{}
+
+func (xxhash64Hasher) Name() string {
+	return "xxhash64"
+}
+
+func (xxhash64Hasher) Size() int {
+	return 8
+}
+
+func (xxhash64Hasher) Sum(dst, b []byte) []byte {
+	return binary.LittleEndian.AppendUint64(dst, xxhash64Sum(b, 0))
+}
+
+func xxhash64Sum(b []byte, seed uint64) uint64 {
+
+	length := uint64(len(b))
+	var h uint64
+
+	if len(b) >= 32 {
+		v1 := seed + xxPrime1 + xxPrime2
+		v2 := seed + xxPrime2
+		v3 := seed
+		v4 := seed - xxPrime1
+
+		for len(b) >= 32 {
+			v1 = xxRound(v1, binary.LittleEndian.Uint64(b))
+			v2 = xxRound(v2, binary.LittleEndian.Uint64(b[8:]))
+			v3 = xxRound(v3, binary.LittleEndian.Uint64(b[16:]))
+			v4 = xxRound(v4, binary.LittleEndian.Uint64(b[24:]))
+			b = b[32:]
+		}
+
+		h = bits.RotateLeft64(v1, 1) + bits.RotateLeft64(v2, 7) + bits.RotateLeft64(v3, 12) + bits.RotateLeft64(v4, 18)
+		h = xxMergeRound(h, v1)
+		h = xxMergeRound(h, v2)
+		h = xxMergeRound(h, v3)
+		h = xxMergeRound(h, v4)
+	} else {
+		h = seed + xxPrime5
+	}
+
+	h += length
+
+	for len(b) >= 8 {
+		h ^= xxRound(0, binary.LittleEndian.Uint64(b))
+		h = bits.RotateLeft64(h, 27)*xxPrime1 + xxPrime4
+		b = b[8:]
+	}
+
+	if len(b) >= 4 {
+		h ^= uint64(binary.LittleEndian.Uint32(b)) * xxPrime1
+		h = bits.RotateLeft64(h, 23)*xxPrime2 + xxPrime3
+		b = b[4:]
+	}
+
+	for _, c := range b {
+		h ^= uint64(c) * xxPrime5
+		h = bits.RotateLeft64(h, 11) * xxPrime1
+	}
+
+	h ^= h >> 33
+	h *= xxPrime2
+	h ^= h >> 29
+	h *= xxPrime3
+	h ^= h >> 32
+
+	return h
+}
+
+func xxRound(acc, input uint64) uint64 {
+	acc += input * xxPrime2
+	acc = bits.RotateLeft64(acc, 31)
+	return acc * xxPrime1
+}
+
+func xxMergeRound(acc, val uint64) uint64 {
+	val = xxRound(0, val)
+	acc ^= val
+	return acc*xxPrime1 + xxPrime4
+}
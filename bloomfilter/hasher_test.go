@@ -0,0 +1,81 @@
+package bloomfilter
+
+import (
+	"encoding/binary"
+
+	"github.com/iostrovok/go-bloom-filter/bloomfilter/fortesting"
+	. "gopkg.in/check.v1"
+)
+
+type shortHasher struct{}
+
+func (shortHasher) Name() string             { return "short" }
+func (shortHasher) Size() int                { return 4 }
+func (shortHasher) Sum(dst, b []byte) []byte { return append(dst, 1, 2, 3, 4) }
+
+func (s *filterTestSuite) TestHasherVectors(c *C) {
+
+	c.Assert(binary.LittleEndian.Uint64(FNV1a64.Sum(nil, []byte{})), Equals, uint64(0xcbf29ce484222325))
+	c.Assert(binary.LittleEndian.Uint64(FNV1a64.Sum(nil, []byte("a"))), Equals, uint64(0xaf63dc4c8601ec8c))
+
+	c.Assert(binary.LittleEndian.Uint64(XXHash64.Sum(nil, []byte{})), Equals, uint64(0xef46db3751d8e999))
+	c.Assert(binary.LittleEndian.Uint64(XXHash64.Sum(nil, []byte("abc"))), Equals, uint64(0x44bc2cf5ad770999))
+
+	out := Murmur3.Sum(nil, []byte{})
+	c.Assert(out, DeepEquals, make([]byte, 16))
+
+	out = Murmur3.Sum(nil, []byte("hello"))
+	c.Assert(binary.LittleEndian.Uint64(out), Equals, uint64(0xcbd8a7b341bd9b02))
+	c.Assert(binary.LittleEndian.Uint64(out[8:]), Equals, uint64(0x5b1e906a48ae1d19))
+
+	prefix := []byte{9, 9}
+	c.Assert(MD5.Sum(prefix, []byte("a"))[:2], DeepEquals, prefix)
+	c.Assert(len(SHA384.Sum(nil, []byte("a"))), Equals, SHA384.Size())
+}
+
+func (s *filterTestSuite) TestRegisterHasher(c *C) {
+
+	c.Assert(RegisterHasher(MD5), NotNil)
+	c.Assert(RegisterHasher(shortHasher{}), NotNil)
+
+	h, find := LookupHasher("xxhash64")
+	c.Assert(find, Equals, true)
+	c.Assert(h, Equals, XXHash64)
+
+	_, find = LookupHasher("short")
+	c.Assert(find, Equals, false)
+
+	filter, err := NewWithOptions(100, WithHasher(shortHasher{}))
+	c.Assert(err, NotNil)
+	c.Assert(filter, IsNil)
+}
+
+func (s *filterTestSuite) TestHashers(c *C) {
+
+	testArray := fortesting.ArrayForTesting()
+
+	for _, strategy := range []HashStrategy{HashFNV1a64, HashMurmur3, HashXXHash64, HashMD5} {
+
+		filter, err := NewWithOptions(int64(len(testArray)), WithHashStrategy(strategy), WithErrorRate(0.0001))
+		c.Assert(err, IsNil)
+		c.Assert(filter.hasher.Name(), Equals, string(strategy))
+
+		for _, s := range testArray {
+			res, err := filter.Add([]byte(s))
+			c.Assert(err, IsNil)
+			c.Assert(res, Equals, false)
+		}
+
+		for _, s := range testArray {
+			c.Assert(filter.Check([]byte(s)), Equals, true)
+		}
+
+		countBad := 0
+		for _, s := range testArray {
+			if filter.Check([]byte(s + "eee-h")) {
+				countBad++
+			}
+		}
+		c.Assert(countBad < 3, Equals, true)
+	}
+}
@@ -0,0 +1,192 @@
+// Package rle encodes long runs of zero bytes. Bit arrays of young filters are mostly zero bytes,
+// flate can not compress a run better than 258 bytes per match, so long runs are encoded before compression.
+//
+// Data is a list of blocks:
+//
+//	literal length  uvarint
+//	literal         [literal length]byte
+//	zero run        uvarint   number of zero bytes after literal
+package rle
+
+import (
+	"bufio"
+	"encoding/binary"
+	"io"
+)
+
+// MinRun is the shortest run of zero bytes which is encoded as run, shorter runs are kept in literal.
+const MinRun = 16
+
+// maxLiteral limits buffered literal of Writer.
+const maxLiteral = 64 * 1024
+
+// Writer encodes data which is written to it. Close must be called after the last Write.
+type Writer struct {
+	w       io.Writer
+	literal []byte
+	zeros   uint64
+	varint  [binary.MaxVarintLen64]byte
+}
+
+// NewWriter is constructor. Encoded data is written to w.
+func NewWriter(w io.Writer) *Writer {
+	return &Writer{
+		w:       w,
+		literal: make([]byte, 0, maxLiteral),
+	}
+}
+
+// Write encodes p.
+func (z *Writer) Write(p []byte) (int, error) {
+
+	for i := 0; i < len(p); {
+
+		j := i
+		for j < len(p) && p[j] == 0 {
+			j++
+		}
+		z.zeros += uint64(j - i)
+		if j == len(p) {
+			break
+		}
+
+		if err := z.endRun(); err != nil {
+			return i, err
+		}
+
+		i = j
+		for j < len(p) && p[j] != 0 {
+			j++
+		}
+		z.literal = append(z.literal, p[i:j]...)
+		i = j
+
+		if len(z.literal) >= maxLiteral {
+			if err := z.flush(0); err != nil {
+				return i, err
+			}
+		}
+	}
+
+	return len(p), nil
+}
+
+// Close writes buffered data. Underlying writer is not closed.
+func (z *Writer) Close() error {
+
+	if err := z.endRun(); err != nil {
+		return err
+	}
+
+	if len(z.literal) > 0 {
+		return z.flush(0)
+	}
+
+	return nil
+}
+
+// endRun writes long run of zeros with literal before it, short run is added to literal.
+func (z *Writer) endRun() error {
+
+	if z.zeros >= MinRun {
+		err := z.flush(z.zeros)
+		z.zeros = 0
+		return err
+	}
+
+	for ; z.zeros > 0; z.zeros-- {
+		z.literal = append(z.literal, 0)
+	}
+
+	return nil
+}
+
+// flush writes block of buffered literal and run of zeros.
+func (z *Writer) flush(zeros uint64) error {
+
+	n := binary.PutUvarint(z.varint[:], uint64(len(z.literal)))
+	if _, err := z.w.Write(z.varint[:n]); err != nil {
+		return err
+	}
+
+	if _, err := z.w.Write(z.literal); err != nil {
+		return err
+	}
+	z.literal = z.literal[:0]
+
+	n = binary.PutUvarint(z.varint[:], zeros)
+	_, err := z.w.Write(z.varint[:n])
+	return err
+}
+
+// Reader decodes data of Writer.
+type Reader struct {
+	r       *bufio.Reader
+	literal uint64 // not read bytes of literal
+	zeros   uint64 // not returned zeros of run
+	inBlock bool   // run of current block is not read yet
+}
+
+// NewReader is constructor. Encoded data is read from r.
+func NewReader(r io.Reader) *Reader {
+	return &Reader{r: bufio.NewReader(r)}
+}
+
+// Read decodes data into p.
+func (z *Reader) Read(p []byte) (int, error) {
+
+	n := 0
+	for n < len(p) {
+
+		if z.literal > 0 {
+			m := min(uint64(len(p)-n), z.literal)
+			k, err := io.ReadFull(z.r, p[n:n+int(m)])
+			n += k
+			z.literal -= uint64(k)
+			if err != nil {
+				return n, unexpected(err)
+			}
+			continue
+		}
+
+		if z.inBlock {
+			zeros, err := binary.ReadUvarint(z.r)
+			if err != nil {
+				return n, unexpected(err)
+			}
+			z.zeros = zeros
+			z.inBlock = false
+		}
+
+		if z.zeros > 0 {
+			m := min(uint64(len(p)-n), z.zeros)
+			clear(p[n : n+int(m)])
+			n += int(m)
+			z.zeros -= m
+			continue
+		}
+
+		literal, err := binary.ReadUvarint(z.r)
+		if err == io.EOF {
+			if n > 0 {
+				return n, nil
+			}
+			return 0, io.EOF
+		}
+		if err != nil {
+			return n, err
+		}
+		z.literal = literal
+		z.inBlock = true
+	}
+
+	return n, nil
+}
+
+// unexpected changes io.EOF inside of block to io.ErrUnexpectedEOF.
+func unexpected(err error) error {
+	if err == io.EOF {
+		return io.ErrUnexpectedEOF
+	}
+	return err
+}
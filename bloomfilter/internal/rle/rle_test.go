@@ -0,0 +1,99 @@
+package rle
+
+import (
+	"bytes"
+	"io"
+	"math/rand"
+	"testing"
+
+	. "gopkg.in/check.v1"
+)
+
+type rleTestSuite struct{}
+
+// Hook up gocheck into the "go test" runner.
+func Test(t *testing.T) { TestingT(t) }
+
+var _ = Suite(&rleTestSuite{})
+
+func encode(c *C, chunks ...[]byte) []byte {
+	binBuf := bytes.NewBuffer([]byte{})
+	z := NewWriter(binBuf)
+	for _, chunk := range chunks {
+		n, err := z.Write(chunk)
+		c.Assert(err, IsNil)
+		c.Assert(n, Equals, len(chunk))
+	}
+	c.Assert(z.Close(), IsNil)
+	return binBuf.Bytes()
+}
+
+func (s *rleTestSuite) TestRoundTrip(c *C) {
+
+	random := rand.New(rand.NewSource(1))
+
+	sparse := make([]byte, 1<<20)
+	for i := 0; i < 300; i++ {
+		sparse[random.Intn(len(sparse))] = byte(random.Intn(255) + 1)
+	}
+
+	dense := make([]byte, 200*1000)
+	random.Read(dense)
+
+	short := []byte{1, 0, 0, 2, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 3, 0}
+
+	for _, data := range [][]byte{{}, {0}, {5}, make([]byte, 1000), short, sparse, dense} {
+
+		encoded := encode(c, data)
+		decoded, err := io.ReadAll(NewReader(bytes.NewReader(encoded)))
+		c.Assert(err, IsNil)
+		c.Assert(len(decoded), Equals, len(data))
+		c.Assert(bytes.Equal(decoded, data), Equals, true)
+
+		// writes split at any place give the same data
+		parts := [][]byte{}
+		for rest := data; len(rest) > 0; {
+			n := min(len(rest), random.Intn(5000)+1)
+			parts = append(parts, rest[:n])
+			rest = rest[n:]
+		}
+		decoded, err = io.ReadAll(NewReader(bytes.NewReader(encode(c, parts...))))
+		c.Assert(err, IsNil)
+		c.Assert(bytes.Equal(decoded, data), Equals, true)
+
+		// small reads
+		reader := NewReader(bytes.NewReader(encoded))
+		small := bytes.NewBuffer([]byte{})
+		buf := make([]byte, 7)
+		for {
+			n, err := reader.Read(buf)
+			small.Write(buf[:n])
+			if err == io.EOF {
+				break
+			}
+			c.Assert(err, IsNil)
+		}
+		c.Assert(bytes.Equal(small.Bytes(), data), Equals, true)
+	}
+
+	c.Assert(len(encode(c, sparse)) < 300*8, Equals, true)
+	c.Assert(len(encode(c, make([]byte, 1<<30/64))) < 8, Equals, true)
+}
+
+func (s *rleTestSuite) TestTruncated(c *C) {
+
+	data := make([]byte, 5000)
+	data[10] = 1
+	data[4000] = 2
+	encoded := encode(c, data)
+
+	for i := 1; i < len(encoded); i++ {
+		decoded, err := io.ReadAll(NewReader(bytes.NewReader(encoded[:i])))
+		if err == nil {
+			// truncated at end of block
+			c.Assert(len(decoded) < len(data), Equals, true)
+			continue
+		}
+		c.Assert(err, Equals, io.ErrUnexpectedEOF)
+	}
+}
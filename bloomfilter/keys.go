@@ -0,0 +1,35 @@
+package bloomfilter
+
+import (
+	"encoding/binary"
+	"unsafe"
+)
+
+// stringBytes returns bytes of string without copy. Filter never changes keys.
+func stringBytes(key string) []byte {
+	return unsafe.Slice(unsafe.StringData(key), len(key))
+}
+
+// AddString adds string key. It is the same as Add([]byte(key)) without copy of key.
+func (bf *BloomFilter) AddString(key string, skipChecks ...bool) (bool, error) {
+	return bf.Add(stringBytes(key), skipChecks...)
+}
+
+// CheckString checks string key.
+func (bf *BloomFilter) CheckString(key string) bool {
+	return bf.Check(stringBytes(key))
+}
+
+// AddUint64 adds key as 8 bytes in little-endian order.
+func (bf *BloomFilter) AddUint64(key uint64, skipChecks ...bool) (bool, error) {
+	var b [8]byte
+	binary.LittleEndian.PutUint64(b[:], key)
+	return bf.Add(b[:], skipChecks...)
+}
+
+// CheckUint64 checks key which is added by AddUint64.
+func (bf *BloomFilter) CheckUint64(key uint64) bool {
+	var b [8]byte
+	binary.LittleEndian.PutUint64(b[:], key)
+	return bf.Check(b[:])
+}
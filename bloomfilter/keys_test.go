@@ -0,0 +1,45 @@
+package bloomfilter
+
+import (
+	"encoding/binary"
+
+	"github.com/iostrovok/go-bloom-filter/bloomfilter/fortesting"
+	. "gopkg.in/check.v1"
+)
+
+func (s *filterTestSuite) TestTypedKeys(c *C) {
+
+	filter, err := New(10000, 0.0001)
+	c.Assert(err, IsNil)
+
+	for _, s := range fortesting.ArrayForTesting() {
+		res, err := filter.AddString(s)
+		c.Assert(err, IsNil)
+		c.Assert(res, Equals, false)
+	}
+
+	for i := uint64(0); i < 1000; i++ {
+		_, err := filter.AddUint64(i)
+		c.Assert(err, IsNil)
+	}
+
+	for _, s := range fortesting.ArrayForTesting() {
+		c.Assert(filter.CheckString(s), Equals, true)
+		c.Assert(filter.Check([]byte(s)), Equals, true)
+	}
+
+	b := make([]byte, 8)
+	for i := uint64(0); i < 1000; i++ {
+		c.Assert(filter.CheckUint64(i), Equals, true)
+		binary.LittleEndian.PutUint64(b, i)
+		c.Assert(filter.Check(b), Equals, true)
+	}
+
+	countBad := 0
+	for i := uint64(1000); i < 2000; i++ {
+		if filter.CheckUint64(i) {
+			countBad++
+		}
+	}
+	c.Assert(countBad < 3, Equals, true)
+}
@@ -0,0 +1,110 @@
+package bloomfilter
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+)
+
+// jsonFilter is JSON form of filter. Bits are saved as in ToBytes, encoding/json writes them by base64.
+type jsonFilter struct {
+	ErrorRate    float64      `json:"error_rate"`
+	NumSlices    uint64       `json:"num_slices"`
+	BitsPerSlice uint64       `json:"bits_per_slice"`
+	Capacity     int64        `json:"capacity"`
+	Count        int64        `json:"count"`
+	HashStrategy HashStrategy `json:"hash_strategy,omitempty"`
+	Seed         uint64       `json:"seed,omitempty"`
+	IndexMode    IndexMode    `json:"index_mode,omitempty"`
+	FileFormat   FileFormat   `json:"file_format,omitempty"`
+	Bits         []byte       `json:"bits"`
+}
+
+// MarshalBinary implements encoding.BinaryMarshaler. Data is the same as ToBytes writes.
+func (bf *BloomFilter) MarshalBinary() ([]byte, error) {
+	binBuf := bytes.NewBuffer([]byte{})
+	if err := bf.ToBytes(binBuf); err != nil {
+		return nil, err
+	}
+	return binBuf.Bytes(), nil
+}
+
+// UnmarshalBinary implements encoding.BinaryUnmarshaler. It reads all formats of FromReader.
+func (bf *BloomFilter) UnmarshalBinary(data []byte) error {
+
+	n, err := bf.ReadFrom(bytes.NewReader(data))
+	if err != nil {
+		return err
+	}
+
+	if n != int64(len(data)) {
+		return fmt.Errorf("unexpected %d bytes after bloom filter", int64(len(data))-n)
+	}
+
+	return nil
+}
+
+// GobEncode implements gob.GobEncoder, see MarshalBinary.
+func (bf *BloomFilter) GobEncode() ([]byte, error) {
+	return bf.MarshalBinary()
+}
+
+// GobDecode implements gob.GobDecoder, see UnmarshalBinary.
+func (bf *BloomFilter) GobDecode(data []byte) error {
+	return bf.UnmarshalBinary(data)
+}
+
+// MarshalJSON implements json.Marshaler. JSON keeps parameters of filter and base64 bits.
+func (bf *BloomFilter) MarshalJSON() ([]byte, error) {
+	return json.Marshal(jsonFilter{
+		ErrorRate:    bf.errorRate,
+		NumSlices:    uint64(bf.numSlices),
+		BitsPerSlice: bf.bitsPerSlice,
+		Capacity:     bf.capacity,
+		Count:        bf.Count(),
+		HashStrategy: bf.opts.hashStrategy,
+		Seed:         bf.opts.seed,
+		IndexMode:    bf.opts.indexMode,
+		FileFormat:   bf.opts.format,
+		Bits:         arrayBytes(bf.bitarray),
+	})
+}
+
+// UnmarshalJSON implements json.Unmarshaler. Parameters are checked as for files.
+func (bf *BloomFilter) UnmarshalJSON(data []byte) error {
+
+	var in jsonFilter
+	if err := json.Unmarshal(data, &in); err != nil {
+		return err
+	}
+
+	o := defaultOptions()
+	o.errorRate = in.ErrorRate
+	o.hashStrategy = in.HashStrategy
+	o.seed = in.Seed
+	o.indexMode = in.IndexMode
+	o.format = in.FileFormat
+
+	out, err := newFromParams(o, v2Params{
+		ErrorRate:    in.ErrorRate,
+		NumSlices:    in.NumSlices,
+		BitsPerSlice: in.BitsPerSlice,
+		Capacity:     in.Capacity,
+		Count:        in.Count,
+		BitsSize:     uint64(len(in.Bits)),
+	})
+	if err != nil {
+		return err
+	}
+
+	if out.bitarray, err = newBitArray(out.opts, out.numBits); err != nil {
+		return err
+	}
+
+	if err := out.bitarray.MergeBytes(in.Bits); err != nil {
+		return err
+	}
+
+	*bf = *out
+	return nil
+}
@@ -0,0 +1,115 @@
+package bloomfilter
+
+import (
+	"bytes"
+	"encoding"
+	"encoding/gob"
+	"encoding/json"
+
+	"github.com/iostrovok/go-bloom-filter/bloomfilter/fortesting"
+	. "gopkg.in/check.v1"
+)
+
+var (
+	_ encoding.BinaryMarshaler   = &BloomFilter{}
+	_ encoding.BinaryUnmarshaler = &BloomFilter{}
+	_ gob.GobEncoder             = &BloomFilter{}
+	_ gob.GobDecoder             = &BloomFilter{}
+	_ json.Marshaler             = &BloomFilter{}
+	_ json.Unmarshaler           = &BloomFilter{}
+)
+
+type filterHolder struct {
+	Name   string
+	Filter *BloomFilter
+}
+
+func (s *filterTestSuite) TestMarshal(c *C) {
+
+	testArray := fortesting.ArrayForTesting()
+
+	for _, opts := range [][]Option{
+		{},
+		{WithHashStrategy(HashXXHash64), WithIndexMode(IndexDoubleHashing), WithSeed(7)},
+		{WithFileFormat(FormatV2)},
+	} {
+		filter, err := NewWithOptions(int64(len(testArray)), opts...)
+		c.Assert(err, IsNil)
+
+		for _, s := range testArray {
+			_, err := filter.Add([]byte(s))
+			c.Assert(err, IsNil)
+		}
+
+		check := func(loaded *BloomFilter) {
+			c.Assert(Equal(filter, loaded), Equals, true)
+			c.Assert(loaded.Count(), Equals, filter.Count())
+			c.Assert(loaded.FileFormat(), Equals, filter.FileFormat())
+			c.Assert(loaded.hasher.Name(), Equals, filter.hasher.Name())
+			for _, s := range testArray {
+				c.Assert(loaded.Check([]byte(s)), Equals, true)
+			}
+		}
+
+		// binary
+		data, err := filter.MarshalBinary()
+		c.Assert(err, IsNil)
+		loaded := &BloomFilter{}
+		c.Assert(loaded.UnmarshalBinary(data), IsNil)
+		check(loaded)
+		c.Assert(loaded.UnmarshalBinary(append(data, 0)), NotNil)
+
+		// gob
+		buf := bytes.NewBuffer([]byte{})
+		c.Assert(gob.NewEncoder(buf).Encode(filterHolder{Name: "gob", Filter: filter}), IsNil)
+		var fromGob filterHolder
+		c.Assert(gob.NewDecoder(buf).Decode(&fromGob), IsNil)
+		c.Assert(fromGob.Name, Equals, "gob")
+		check(fromGob.Filter)
+
+		// json
+		text, err := json.Marshal(filterHolder{Name: "json", Filter: filter})
+		c.Assert(err, IsNil)
+		var fromJSON filterHolder
+		c.Assert(json.Unmarshal(text, &fromJSON), IsNil)
+		c.Assert(fromJSON.Name, Equals, "json")
+		check(fromJSON.Filter)
+
+		again, err := json.Marshal(fromJSON)
+		c.Assert(err, IsNil)
+		c.Assert(string(again), Equals, string(text))
+	}
+}
+
+func (s *filterTestSuite) TestUnmarshalJSONErrors(c *C) {
+
+	filter, err := New(1000, 0.01)
+	c.Assert(err, IsNil)
+	filter.Add([]byte("key"))
+
+	text, err := json.Marshal(filter)
+	c.Assert(err, IsNil)
+
+	var params map[string]interface{}
+	c.Assert(json.Unmarshal(text, &params), IsNil)
+	c.Assert(params["num_slices"], Equals, float64(filter.NumSlices()))
+	c.Assert(params["bits_per_slice"], Equals, float64(filter.BitsPerSlice()))
+
+	for key, value := range map[string]interface{}{
+		"bits":          "AAAA",
+		"error_rate":    2.0,
+		"num_slices":    0,
+		"hash_strategy": "unknown",
+		"index_mode":    5,
+	} {
+		broken := map[string]interface{}{}
+		for k, v := range params {
+			broken[k] = v
+		}
+		broken[key] = value
+
+		text, err := json.Marshal(broken)
+		c.Assert(err, IsNil)
+		c.Assert(json.Unmarshal(text, &BloomFilter{}), NotNil, Commentf("%s", key))
+	}
+}
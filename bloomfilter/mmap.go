@@ -0,0 +1,159 @@
+package bloomfilter
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/binary"
+	"fmt"
+	"hash/crc32"
+	"os"
+
+	"github.com/iostrovok/go-bloom-filter/bloomfilter/array"
+)
+
+// FromFileMapped opens bloom filter file in place by mmap. The file is not read into memory,
+// so opening is fast for huge files. Read-only filter returns error from Add.
+// Changes of writable filter are saved into file by Sync and Close.
+func FromFileMapped(fileName string, writable bool) (*BloomFilter, error) {
+
+	flag := os.O_RDONLY
+	if writable {
+		flag = os.O_RDWR
+	}
+
+	file, err := os.OpenFile(fileName, flag, 0)
+	if err != nil {
+		return nil, err
+	}
+	// mapping is valid after file closing
+	defer file.Close()
+
+	m, err := array.Map(file, writable)
+	if err != nil {
+		return nil, err
+	}
+
+	bf, err := FromMapping(m, 0)
+	if err != nil {
+		m.Close()
+		return nil, err
+	}
+
+	bf.ownMapping = true
+	return bf, nil
+}
+
+// FromMapping creates bloom filter which uses bits of mapped file from offset in place.
+// Mapping is not closed by Close of filter.
+func FromMapping(m *array.Mapping, offset int64) (*BloomFilter, error) {
+
+	data := m.Bytes()
+	if offset < 0 || offset >= int64(len(data)) {
+		return nil, fmt.Errorf("wrong offset of bloom filter: %d", offset)
+	}
+
+	reader := bufio.NewReader(bytes.NewReader(data[offset:]))
+	if isV2(reader) {
+		return fromMappingV2(m, offset)
+	}
+
+	if isCompressed(reader) {
+		return nil, fmt.Errorf("compressed bloom filter can not be mapped")
+	}
+
+	bf, headerLen, err := readHeader(reader)
+	if err != nil {
+		return nil, err
+	}
+
+	bits, err := array.NewMapped(m, offset+headerLen, bf.numBits)
+	if err != nil {
+		return nil, err
+	}
+
+	bf.bitarray = bits
+	bf.mapping = m
+	// count is the last field of header
+	bf.countOffset = offset + headerLen - 8
+
+	return bf, nil
+}
+
+// fromMappingV2 creates filter of format v2 in mapping. Checksum is not checked,
+// so opening of huge file is fast. Sync writes new checksum.
+func fromMappingV2(m *array.Mapping, offset int64) (*BloomFilter, error) {
+
+	bf, headerLen, err := readV2Header(bytes.NewReader(m.Bytes()[offset:]))
+	if err != nil {
+		return nil, err
+	}
+
+	bitsSize := int64((bf.numBits + 7) / 8)
+	if offset+headerLen+bitsSize+4 > int64(len(m.Bytes())) {
+		return nil, fmt.Errorf("mapped file is too short for bloom filter")
+	}
+
+	bits, err := array.NewMapped(m, offset+headerLen, bf.numBits)
+	if err != nil {
+		return nil, err
+	}
+
+	bf.bitarray = bits
+	bf.mapping = m
+	// count is before size of bits, checksum is after bits
+	bf.countOffset = offset + headerLen - v2TailLen
+	bf.imageOffset = offset
+	bf.checksumOffset = offset + headerLen + bitsSize
+
+	return bf, nil
+}
+
+// readOnly returns true if filter is placed in read-only mapping or is a snapshot.
+func (bf *BloomFilter) readOnly() bool {
+	return bf.mapping != nil && !bf.mapping.Writable() || bf.isSnapshot()
+}
+
+// Sync saves count and bits of writable mapped filter into file (msync).
+// Checksum of format v2 is calculated again.
+// It does nothing for other filters.
+func (bf *BloomFilter) Sync() error {
+	if bf.mapping == nil || !bf.mapping.Writable() {
+		return nil
+	}
+
+	data := bf.mapping.Bytes()
+	update := func() {
+		binary.LittleEndian.PutUint64(data[bf.countOffset:], uint64(bf.Count()))
+		if bf.checksumOffset > 0 {
+			sum := crc32.Checksum(data[bf.imageOffset:bf.checksumOffset], castagnoli)
+			binary.LittleEndian.PutUint32(data[bf.checksumOffset:], sum)
+		}
+	}
+
+	// checksum is calculated while array is locked, so it is not torn by Add
+	if bits, ok := bf.bitarray.(*array.Array); ok {
+		return bits.SyncWith(update)
+	}
+
+	update()
+	return bf.mapping.Sync()
+}
+
+// Close saves changes of mapped filter and unmaps file which is opened by FromFileMapped.
+// It releases pages of snapshot. Filter must not be used after Close. It does nothing for other filters.
+func (bf *BloomFilter) Close() error {
+	if snapshot, ok := bf.bitarray.(*array.Snapshot); ok {
+		snapshot.Release()
+		return nil
+	}
+
+	if err := bf.Sync(); err != nil {
+		return err
+	}
+
+	if !bf.ownMapping {
+		return nil
+	}
+
+	return bf.mapping.Close()
+}
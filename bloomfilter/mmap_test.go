@@ -0,0 +1,98 @@
+//go:build unix
+
+package bloomfilter
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+
+	"github.com/iostrovok/go-bloom-filter/bloomfilter/fortesting"
+	. "gopkg.in/check.v1"
+)
+
+func (s *filterTestSuite) TestFromFileMapped(c *C) {
+
+	testArray := fortesting.ArrayForTesting()
+	half := len(testArray) / 2
+
+	for _, opts := range [][]Option{
+		{},
+		{WithFileFormat(FormatV2)},
+		{WithHashStrategy(HashXXHash64), WithIndexMode(IndexDoubleHashing)},
+	} {
+		filter, err := NewWithOptions(int64(len(testArray)), opts...)
+		c.Assert(err, IsNil)
+
+		for _, s := range testArray[:half] {
+			_, err := filter.Add([]byte(s))
+			c.Assert(err, IsNil)
+		}
+
+		fileName := filepath.Join(c.MkDir(), "mapped.bin")
+		c.Assert(filter.ToFile(fileName), IsNil)
+
+		readOnly, err := FromFileMapped(fileName, false)
+		c.Assert(err, IsNil)
+		c.Assert(readOnly.Count(), Equals, filter.Count())
+		for _, s := range testArray[:half] {
+			c.Assert(readOnly.Check([]byte(s)), Equals, true)
+		}
+		_, err = readOnly.Add([]byte("new key"))
+		c.Assert(err, NotNil)
+		c.Assert(readOnly.Merge(filter), NotNil)
+		c.Assert(readOnly.SetLocations([]uint64{0}, 1), Equals, readOnlyError)
+
+		// copies are placed in heap
+		union, err := Union(readOnly, filter)
+		c.Assert(err, IsNil)
+		_, err = union.Add([]byte("new key"))
+		c.Assert(err, IsNil)
+		c.Assert(readOnly.Close(), IsNil)
+
+		writable, err := FromFileMapped(fileName, true)
+		c.Assert(err, IsNil)
+
+		// checksum is calculated while keys are added
+		done := make(chan bool)
+		go func() {
+			for _, s := range testArray[half:] {
+				writable.Add([]byte(s))
+			}
+			close(done)
+		}()
+		for finished := false; !finished; {
+			select {
+			case <-done:
+				finished = true
+			default:
+				c.Assert(writable.Sync(), IsNil)
+			}
+		}
+		c.Assert(writable.Sync(), IsNil)
+
+		for _, s := range testArray {
+			_, err := filter.Add([]byte(s))
+			c.Assert(err, IsNil)
+		}
+
+		// file is the same as saved by ToFile
+		data, err := os.ReadFile(fileName)
+		c.Assert(err, IsNil)
+		binBuf := bytes.NewBuffer([]byte{})
+		c.Assert(filter.ToBytes(binBuf), IsNil)
+		c.Assert(data, DeepEquals, binBuf.Bytes())
+
+		c.Assert(writable.Close(), IsNil)
+
+		filterNew, err := FromFile(fileName)
+		c.Assert(err, IsNil)
+		c.Assert(filterNew.Count(), Equals, int64(len(testArray)))
+		for _, s := range testArray {
+			c.Assert(filterNew.Check([]byte(s)), Equals, true)
+		}
+	}
+
+	_, err := FromFileMapped(filepath.Join(c.MkDir(), "unknown.bin"), false)
+	c.Assert(err, NotNil)
+}
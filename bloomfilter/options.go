@@ -0,0 +1,436 @@
+package bloomfilter
+
+import (
+	"fmt"
+	"math"
+)
+
+// HashStrategy selects the hash function which is used to find bit positions for a key.
+// It is a name of registered Hasher, see RegisterHasher.
+type HashStrategy string
+
+const (
+	// HashAuto selects md5, sha1, sha256, sha384 or sha512 by the number of hash bits
+	// the filter needs. It is the python-bloomfilter behaviour and the default one.
+	HashAuto HashStrategy = ""
+	// HashMD5 forces md5
+	HashMD5 HashStrategy = "md5"
+	// HashSHA1 forces sha1
+	HashSHA1 HashStrategy = "sha1"
+	// HashSHA256 forces sha256
+	HashSHA256 HashStrategy = "sha256"
+	// HashSHA384 forces sha384
+	HashSHA384 HashStrategy = "sha384"
+	// HashSHA512 forces sha512
+	HashSHA512 HashStrategy = "sha512"
+	// HashFNV1a64 selects 64 bits FNV-1a
+	HashFNV1a64 HashStrategy = "fnv1a64"
+	// HashMurmur3 selects 128 bits MurmurHash3
+	HashMurmur3 HashStrategy = "murmur3-128"
+	// HashXXHash64 selects 64 bits xxHash
+	HashXXHash64 HashStrategy = "xxhash64"
+)
+
+// ConcurrencyMode defines how the bit array is protected from concurrent access.
+type ConcurrencyMode int
+
+const (
+	// ConcurrencyLocked guards the bit array by sync.RWMutex. It is default mode.
+	ConcurrencyLocked ConcurrencyMode = iota
+	// ConcurrencyNone disables locking. Use it only if filter is used by one goroutine.
+	ConcurrencyNone
+	// ConcurrencyAtomic keeps bits in []uint64 and changes them by atomic operations without locks.
+	// It is the fastest mode for concurrent Add and Check.
+	ConcurrencyAtomic
+	// ConcurrencyStriped splits the bit array into stripes with own sync.RWMutex,
+	// so Add and Check of different stripes do not wait each other. See WithStripes.
+	ConcurrencyStriped
+)
+
+// StorageMode defines how bits are kept in memory.
+type StorageMode int
+
+const (
+	// StorageDense allocates all bits of filter at creation. It is default mode.
+	StorageDense StorageMode = iota
+	// StorageSparse keeps set bits in compressed containers until filter is filled enough,
+	// then bits are changed to dense array automatically. It is useful for big filters with few keys.
+	// ConcurrencyAtomic and ConcurrencyStriped always use dense storage.
+	StorageSparse
+)
+
+// FileFormat defines binary format of ToBytes and ToFile.
+type FileFormat int
+
+const (
+	// FormatLegacy is python-bloomfilter format. Filters which it can not describe get extended header.
+	// It is default format.
+	FormatLegacy FileFormat = iota
+	// FormatV2 is self-describing format with magic, version, hash strategy, bit layout and CRC32C checksum.
+	FormatV2
+)
+
+// Compression defines compression of files which are written by ToFile.
+type Compression int
+
+const (
+	// CompressionNone writes bits as they are. It is default value.
+	CompressionNone Compression = iota
+	// CompressionGzip encodes long runs of zero bytes and compresses result by gzip.
+	CompressionGzip
+)
+
+// OverflowPolicy defines behaviour of Add when filter is at capacity.
+type OverflowPolicy int
+
+const (
+	// OverflowError makes Add return error when filter is at capacity. It is default policy.
+	OverflowError OverflowPolicy = iota
+	// OverflowIgnore allows to add keys over capacity. The false positive rate grows in this case.
+	OverflowIgnore
+)
+
+// IndexMode defines how bit positions are derived from hashes.
+type IndexMode int
+
+const (
+	// IndexSalted hashes the key with a separate salt for every chunk of positions.
+	// It is python-bloomfilter behaviour and the default one. Filters with more than 64 bytes
+	// of positions (numSlices*chunkSize > 64) reuse the first salt, python-bloomfilter uses next salts.
+	IndexSalted IndexMode = iota
+	// IndexDoubleHashing hashes the key once and derives all positions
+	// as h1 + i*h2 mod bitsPerSlice (Kirsch-Mitzenmacher).
+	IndexDoubleHashing
+	// IndexPybloom is IndexSalted with exactly python-bloomfilter salts for any number of positions.
+	// It is used by FromPybloom.
+	IndexPybloom
+)
+
+// Option is a functional option for NewWithOptions.
+type Option func(*options)
+
+type options struct {
+	errorRate    float64
+	errorRateSet bool
+	numSlices    int
+	numBits      uint64
+	hashStrategy HashStrategy
+	hasher       Hasher
+	seed         uint64
+	indexMode    IndexMode
+	concurrency  ConcurrencyMode
+	storage      StorageMode
+	stripes      int
+	store        StoreFactory
+	format       FileFormat
+	compression  Compression
+	overflow     OverflowPolicy
+	workers      int
+}
+
+func defaultOptions() options {
+	return options{
+		errorRate: 0.001,
+	}
+}
+
+// WithErrorRate sets expected false positive rate. Default value is 0.001.
+func WithErrorRate(errorRate float64) Option {
+	return func(o *options) {
+		o.errorRate = errorRate
+		o.errorRateSet = true
+	}
+}
+
+// WithKM sets number of hash functions (k) and total number of bits (m) explicitly.
+// Error rate is not used for sizing in this case. If it is not set by WithErrorRate,
+// the expected error rate for full filter is calculated from k, m and capacity.
+func WithKM(k int, m uint64) Option {
+	return func(o *options) {
+		o.numSlices = k
+		o.numBits = m
+	}
+}
+
+// WithHashStrategy sets hash function by name. Default value is HashAuto.
+func WithHashStrategy(strategy HashStrategy) Option {
+	return func(o *options) {
+		o.hashStrategy = strategy
+		o.hasher = nil
+	}
+}
+
+// WithHasher sets custom hash function.
+// Register it by RegisterHasher if filter will be loaded from file.
+func WithHasher(hasher Hasher) Option {
+	return func(o *options) {
+		o.hashStrategy = HashStrategy(hasher.Name())
+		o.hasher = hasher
+	}
+}
+
+// WithSeed mixes seed into the hash salts. Filters with different seeds
+// set different bits for the same key. Default value is 0.
+func WithSeed(seed uint64) Option {
+	return func(o *options) {
+		o.seed = seed
+	}
+}
+
+// WithIndexMode sets the way of bit positions calculation. Default value is IndexSalted.
+func WithIndexMode(mode IndexMode) Option {
+	return func(o *options) {
+		o.indexMode = mode
+	}
+}
+
+// WithConcurrency sets concurrency mode. Default value is ConcurrencyLocked.
+func WithConcurrency(mode ConcurrencyMode) Option {
+	return func(o *options) {
+		o.concurrency = mode
+	}
+}
+
+// WithStripes sets number of locks for ConcurrencyStriped. Default value is array.DefaultStripes.
+func WithStripes(stripes int) Option {
+	return func(o *options) {
+		o.stripes = stripes
+	}
+}
+
+// WithStore sets factory of bit stores. Concurrency and storage modes are not used by filter with it,
+// the store must be safe for concurrent use itself. Filters which are loaded from files use array.Array.
+func WithStore(factory StoreFactory) Option {
+	return func(o *options) {
+		o.store = factory
+	}
+}
+
+// WithStorage sets storage mode of bits. Default value is StorageDense.
+func WithStorage(mode StorageMode) Option {
+	return func(o *options) {
+		o.storage = mode
+	}
+}
+
+// WithFileFormat sets format of ToBytes and ToFile. Default value is FormatLegacy.
+// Filters in all formats are read by FromReader and FromFile.
+func WithFileFormat(format FileFormat) Option {
+	return func(o *options) {
+		o.format = format
+	}
+}
+
+// WithCompression sets compression of ToFile. Default value is CompressionNone.
+// FromFile and FromReader detect compressed files, ToBytes and WriteTo are not compressed.
+func WithCompression(compression Compression) Option {
+	return func(o *options) {
+		o.compression = compression
+	}
+}
+
+// WithOverflowPolicy sets overflow policy. Default value is OverflowError.
+func WithOverflowPolicy(policy OverflowPolicy) Option {
+	return func(o *options) {
+		o.overflow = policy
+	}
+}
+
+// WithWorkers sets number of goroutines which hash keys in AddMany and CheckMany.
+// Default value is 1, keys are hashed by calling goroutine.
+func WithWorkers(workers int) Option {
+	return func(o *options) {
+		o.workers = workers
+	}
+}
+
+func (o *options) check() error {
+
+	if o.errorRate <= 0 || 1.0 < o.errorRate {
+		return fmt.Errorf("error Rate must be between 0 and 1")
+	}
+
+	if o.numSlices != 0 || o.numBits != 0 {
+		if o.numSlices < 1 {
+			return fmt.Errorf("k must be > 0")
+		}
+		if o.numBits < uint64(o.numSlices) {
+			return fmt.Errorf("m must be >= k")
+		}
+	}
+
+	if o.hasher == nil && o.hashStrategy != HashAuto {
+		hasher, find := LookupHasher(string(o.hashStrategy))
+		if !find {
+			return fmt.Errorf("unknown hash strategy: %q", o.hashStrategy)
+		}
+		o.hasher = hasher
+	}
+
+	if o.hasher != nil && o.hasher.Size() < 8 {
+		return fmt.Errorf("hasher %s: size must be >= 8", o.hasher.Name())
+	}
+
+	switch o.indexMode {
+	case IndexSalted, IndexDoubleHashing, IndexPybloom:
+	default:
+		return fmt.Errorf("unknown index mode: %d", o.indexMode)
+	}
+
+	switch o.concurrency {
+	case ConcurrencyLocked, ConcurrencyNone, ConcurrencyAtomic, ConcurrencyStriped:
+	default:
+		return fmt.Errorf("unknown concurrency mode: %d", o.concurrency)
+	}
+
+	switch o.storage {
+	case StorageDense, StorageSparse:
+	default:
+		return fmt.Errorf("unknown storage mode: %d", o.storage)
+	}
+
+	switch o.format {
+	case FormatLegacy, FormatV2:
+	default:
+		return fmt.Errorf("unknown file format: %d", o.format)
+	}
+
+	switch o.compression {
+	case CompressionNone, CompressionGzip:
+	default:
+		return fmt.Errorf("unknown compression: %d", o.compression)
+	}
+
+	switch o.overflow {
+	case OverflowError, OverflowIgnore:
+	default:
+		return fmt.Errorf("unknown overflow policy: %d", o.overflow)
+	}
+
+	if o.stripes < 0 {
+		return fmt.Errorf("stripes must be >= 0")
+	}
+
+	if o.workers < 0 {
+		return fmt.Errorf("workers must be >= 0")
+	}
+
+	return nil
+}
+
+// CheckOptions returns error if options are wrong. Nothing is created.
+func CheckOptions(opts ...Option) error {
+	o := defaultOptions()
+	for _, opt := range opts {
+		opt(&o)
+	}
+	return o.check()
+}
+
+// FileFormatOf returns file format which is set by options.
+func FileFormatOf(opts ...Option) FileFormat {
+	o := defaultOptions()
+	for _, opt := range opts {
+		opt(&o)
+	}
+	return o.format
+}
+
+// HashStrategyOf returns name of hash function which is set by options.
+func HashStrategyOf(opts ...Option) HashStrategy {
+	o := defaultOptions()
+	for _, opt := range opts {
+		opt(&o)
+	}
+	return o.hashStrategy
+}
+
+// SeedOf returns seed which is set by options.
+func SeedOf(opts ...Option) uint64 {
+	o := defaultOptions()
+	for _, opt := range opts {
+		opt(&o)
+	}
+	return o.seed
+}
+
+// IndexModeOf returns index mode which is set by options.
+func IndexModeOf(opts ...Option) IndexMode {
+	o := defaultOptions()
+	for _, opt := range opts {
+		opt(&o)
+	}
+	return o.indexMode
+}
+
+// CompressionOf returns compression which is set by options.
+func CompressionOf(opts ...Option) Compression {
+	o := defaultOptions()
+	for _, opt := range opts {
+		opt(&o)
+	}
+	return o.compression
+}
+
+// sizes returns error rate, number of slices and bits per slice for capacity.
+func (o *options) sizes(capacity int64) (float64, int, uint64) {
+
+	if o.numSlices == 0 {
+		// the same expression as python-bloomfilter, math.Log2 differs for powers of 2
+		numSlices := int(math.Ceil(math.Log(float64(1.0)/o.errorRate) / math.Log(2)))
+		bitsPerSlice := uint64(math.Ceil((float64(capacity) * math.Abs(math.Log(o.errorRate))) / (float64(numSlices) * log2Const)))
+		return o.errorRate, numSlices, bitsPerSlice
+	}
+
+	bitsPerSlice := o.numBits / uint64(o.numSlices)
+	if bitsPerSlice*uint64(o.numSlices) < o.numBits {
+		bitsPerSlice++
+	}
+
+	if o.errorRateSet {
+		return o.errorRate, o.numSlices, bitsPerSlice
+	}
+
+	// every key sets exactly one bit in every slice
+	fill := 1.0 - math.Exp(-float64(capacity)/float64(bitsPerSlice))
+	return math.Pow(fill, float64(o.numSlices)), o.numSlices, bitsPerSlice
+}
+
+// Options returns options which create filter with the same hashing and behaviour.
+// Capacity, error rate and sizes are not included.
+func (bf *BloomFilter) Options() []Option {
+
+	out := []Option{
+		WithSeed(bf.opts.seed),
+		WithIndexMode(bf.opts.indexMode),
+		WithConcurrency(bf.opts.concurrency),
+		WithOverflowPolicy(bf.opts.overflow),
+		WithWorkers(bf.opts.workers),
+	}
+
+	if bf.opts.hasher != nil {
+		out = append(out, WithHasher(bf.opts.hasher))
+	}
+
+	if bf.opts.stripes != 0 {
+		out = append(out, WithStripes(bf.opts.stripes))
+	}
+
+	if bf.opts.store != nil {
+		out = append(out, WithStore(bf.opts.store))
+	}
+
+	if bf.opts.format != FormatLegacy {
+		out = append(out, WithFileFormat(bf.opts.format))
+	}
+
+	if bf.opts.compression != CompressionNone {
+		out = append(out, WithCompression(bf.opts.compression))
+	}
+
+	if bf.opts.storage != StorageDense {
+		out = append(out, WithStorage(bf.opts.storage))
+	}
+
+	return out
+}
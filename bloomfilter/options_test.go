@@ -0,0 +1,183 @@
+package bloomfilter
+
+import (
+	"bufio"
+	"bytes"
+
+	"github.com/iostrovok/go-bloom-filter/bloomfilter/fortesting"
+	. "gopkg.in/check.v1"
+)
+
+func (s *filterTestSuite) TestNewWithOptionsErrors(c *C) {
+
+	filter, err := NewWithOptions(100, WithErrorRate(1.1))
+	c.Assert(err, NotNil)
+	c.Assert(filter, IsNil)
+
+	filter, err = NewWithOptions(100, WithKM(0, 100))
+	c.Assert(err, NotNil)
+	c.Assert(filter, IsNil)
+
+	filter, err = NewWithOptions(100, WithKM(10, 5))
+	c.Assert(err, NotNil)
+	c.Assert(filter, IsNil)
+
+	filter, err = NewWithOptions(100, WithHashStrategy("crc32"))
+	c.Assert(err, NotNil)
+	c.Assert(filter, IsNil)
+
+	filter, err = NewWithOptions(100, WithConcurrency(ConcurrencyMode(100)))
+	c.Assert(err, NotNil)
+	c.Assert(filter, IsNil)
+
+	filter, err = NewWithOptions(100, WithOverflowPolicy(OverflowPolicy(100)))
+	c.Assert(err, NotNil)
+	c.Assert(filter, IsNil)
+
+	filter, err = NewWithOptions(0)
+	c.Assert(err, NotNil)
+	c.Assert(filter, IsNil)
+}
+
+func (s *filterTestSuite) TestNewWithOptionsDefault(c *C) {
+
+	filterA, err := New(10000, 0.001)
+	c.Assert(err, IsNil)
+
+	filterB, err := NewWithOptions(10000, WithErrorRate(0.001), WithConcurrency(ConcurrencyNone))
+	c.Assert(err, IsNil)
+
+	for _, s := range fortesting.ArrayForTesting() {
+		_, err := filterA.Add([]byte(s))
+		c.Assert(err, IsNil)
+		_, err = filterB.Add([]byte(s))
+		c.Assert(err, IsNil)
+	}
+
+	bufA := bytes.NewBuffer([]byte{})
+	c.Assert(filterA.ToBytes(bufA), IsNil)
+
+	bufB := bytes.NewBuffer([]byte{})
+	c.Assert(filterB.ToBytes(bufB), IsNil)
+
+	c.Assert(bufA.Bytes(), DeepEquals, bufB.Bytes())
+}
+
+func (s *filterTestSuite) TestWithKM(c *C) {
+
+	filter, err := NewWithOptions(1000, WithKM(7, 10000))
+	c.Assert(err, IsNil)
+	c.Assert(filter.numSlices, Equals, 7)
+	c.Assert(filter.bitsPerSlice, Equals, uint64(1429))
+	c.Assert(filter.ErrorRate() > 0.005, Equals, true)
+	c.Assert(filter.ErrorRate() < 0.01, Equals, true)
+
+	filter, err = NewWithOptions(1000, WithKM(7, 10000), WithErrorRate(0.05))
+	c.Assert(err, IsNil)
+	c.Assert(filter.ErrorRate(), Equals, 0.05)
+}
+
+func (s *filterTestSuite) TestHashStrategyAndSeed(c *C) {
+
+	filterA, err := NewWithOptions(10000, WithHashStrategy(HashSHA512))
+	c.Assert(err, IsNil)
+	c.Assert(filterA.hasher, Equals, SHA512)
+
+	filterB, err := NewWithOptions(10000, WithSeed(12345))
+	c.Assert(err, IsNil)
+
+	filterC, err := NewWithOptions(10000)
+	c.Assert(err, IsNil)
+
+	for _, s := range fortesting.ArrayForTesting() {
+		_, err := filterA.Add([]byte(s))
+		c.Assert(err, IsNil)
+		_, err = filterB.Add([]byte(s))
+		c.Assert(err, IsNil)
+	}
+
+	for _, s := range fortesting.ArrayForTesting() {
+		c.Assert(filterA.Check([]byte(s)), Equals, true)
+		c.Assert(filterB.Check([]byte(s)), Equals, true)
+	}
+
+	c.Assert(filterC.Merge(filterA), NotNil)
+	c.Assert(filterC.Merge(filterB), NotNil)
+
+	for _, filter := range []*BloomFilter{filterA, filterB} {
+		binBuf := bytes.NewBuffer([]byte{})
+		c.Assert(filter.ToBytes(binBuf), IsNil)
+		c.Assert(binBuf.Bytes()[:len(extMagic)], DeepEquals, extMagic)
+
+		filterNew, err := FromReader(bufio.NewReader(binBuf), 0)
+		c.Assert(err, IsNil)
+		c.Assert(filterNew.hasher, Equals, filter.hasher)
+		c.Assert(filterNew.opts.seed, Equals, filter.opts.seed)
+		c.Assert(filter.Merge(filterNew), IsNil)
+
+		for _, s := range fortesting.ArrayForTesting() {
+			c.Assert(filterNew.Check([]byte(s)), Equals, true)
+		}
+	}
+
+	binBuf := bytes.NewBuffer([]byte{})
+	c.Assert(filterC.ToBytes(binBuf), IsNil)
+	c.Assert(binBuf.Bytes()[:len(extMagic)], Not(DeepEquals), extMagic)
+}
+
+func (s *filterTestSuite) TestDoubleHashing(c *C) {
+
+	testArray := fortesting.ArrayForTesting()
+
+	for _, strategy := range []HashStrategy{HashAuto, HashFNV1a64, HashMurmur3, HashXXHash64} {
+
+		filter, err := NewWithOptions(int64(len(testArray)), WithErrorRate(0.0001),
+			WithHashStrategy(strategy), WithIndexMode(IndexDoubleHashing))
+		c.Assert(err, IsNil)
+
+		for _, s := range testArray {
+			res, err := filter.Add([]byte(s))
+			c.Assert(err, IsNil)
+			c.Assert(res, Equals, false)
+		}
+
+		countBad := 0
+		for _, s := range testArray {
+			c.Assert(filter.Check([]byte(s)), Equals, true)
+			if filter.Check([]byte(s + "eee-dh")) {
+				countBad++
+			}
+		}
+		c.Assert(countBad < 3, Equals, true)
+
+		binBuf := bytes.NewBuffer([]byte{})
+		c.Assert(filter.ToBytes(binBuf), IsNil)
+		length := int64(binBuf.Len())
+
+		filterNew, err := FromReader(bufio.NewReader(binBuf), length)
+		c.Assert(err, IsNil)
+		c.Assert(filterNew.opts.indexMode, Equals, IndexDoubleHashing)
+		c.Assert(filterNew.Count(), Equals, filter.Count())
+
+		for _, s := range testArray {
+			c.Assert(filterNew.Check([]byte(s)), Equals, true)
+		}
+
+		filterSalted, err := NewWithOptions(int64(len(testArray)), WithErrorRate(0.0001), WithHashStrategy(strategy))
+		c.Assert(err, IsNil)
+		c.Assert(filterSalted.Merge(filter), NotNil)
+	}
+}
+
+func (s *filterTestSuite) TestOverflowIgnore(c *C) {
+
+	filter, err := NewWithOptions(100, WithErrorRate(.0001), WithOverflowPolicy(OverflowIgnore))
+	c.Assert(err, IsNil)
+
+	for _, s := range fortesting.ArrayForTesting() {
+		_, err := filter.Add([]byte(s))
+		c.Assert(err, IsNil)
+	}
+
+	c.Assert(filter.Count() > filter.Capacity(), Equals, true)
+}
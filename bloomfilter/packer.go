@@ -11,30 +11,29 @@ func packInt(val int) []byte {
 	return buf
 }
 
+func packUint64(val uint64) []byte {
+	buf := make([]byte, 8, 8)
+	binary.LittleEndian.PutUint64(buf, val)
+	return buf
+}
+
 func packFloat(val float64) []byte {
 	buf := make([]byte, 8, 8)
 	binary.LittleEndian.PutUint64(buf, math.Float64bits(val))
 	return buf
 }
 
-func unpack(chunkSize int, b []byte) []uint64 {
-
-	out := make([]uint64, len(b)/chunkSize, len(b)/chunkSize)
-
-	k := 0
-	for i := 0; i < len(b); i += chunkSize {
-
-		switch chunkSize {
-		case 8:
-			out[k] = binary.LittleEndian.Uint64(b[i : i+chunkSize])
-		case 4:
-			out[k] = uint64(binary.LittleEndian.Uint32(b[i : i+chunkSize]))
-		case 2:
-			out[k] = uint64(binary.LittleEndian.Uint16(b[i : i+chunkSize]))
-		}
+// unpackOne reads one little-endian chunk of chunkSize bytes.
+func unpackOne(chunkSize int, b []byte) uint64 {
 
-		k++
+	switch chunkSize {
+	case 8:
+		return binary.LittleEndian.Uint64(b)
+	case 4:
+		return uint64(binary.LittleEndian.Uint32(b))
+	case 2:
+		return uint64(binary.LittleEndian.Uint16(b))
 	}
 
-	return out
+	return 0
 }
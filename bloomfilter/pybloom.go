@@ -0,0 +1,123 @@
+package bloomfilter
+
+import (
+	"bytes"
+	"encoding/binary"
+	"fmt"
+	"io"
+	"math"
+)
+
+/*
+	python-bloomfilter (https://github.com/jaybaird/python-bloomfilter) writes BloomFilter.tofile as
+	struct '<dQQQQ' and bits of bitarray with little endian bit order:
+
+	error rate      float64
+	num slices      uint64
+	bits per slice  uint64
+	capacity        uint64
+	count           uint64
+	bits            [(num slices * bits per slice + 7) / 8]byte, bit i is bit i%8 of byte i/8
+
+	Keys of python filter are strings, they are hashed as UTF-8 bytes. So []byte(s) of Go finds key s of python.
+	Sizes of python-bloomfilter are
+		num slices     = ceil(log(1 / error rate, 2))
+		bits per slice = ceil(capacity * abs(log(error rate)) / (num slices * log(2) ** 2))
+	New calculates them by the same expressions.
+
+	Positions of a key are little-endian chunks of digest of salt + key, chunk is 2 bytes for bits per slice
+	less than 1 << 15, 4 bytes less than 1 << 31 and 8 bytes for others. Hash function is md5, sha1, sha256,
+	sha384 or sha512, the shortest one which has 8 * num slices * chunk bits. Salts are hash(hash(uint32(i))),
+	sha512 needs several salts for more than 64 bytes of positions. IndexSalted of this package
+	reuses the first salt then, so FromFile reads python-bloomfilter header as IndexSalted
+	and FromPybloom reads it as IndexPybloom.
+*/
+
+// PybloomHeaderLen is length of python-bloomfilter header before bits.
+const PybloomHeaderLen = 40
+
+type pybloomHeader struct {
+	ErrorRate    float64
+	NumSlices    uint64
+	BitsPerSlice uint64
+	Capacity     uint64
+	Count        uint64
+}
+
+// writePybloomHeader writes python-bloomfilter header.
+func writePybloomHeader(binBuf *bytes.Buffer, bf *BloomFilter) {
+	binary.Write(binBuf, binary.LittleEndian, pybloomHeader{
+		ErrorRate:    bf.errorRate,
+		NumSlices:    uint64(bf.numSlices),
+		BitsPerSlice: bf.bitsPerSlice,
+		Capacity:     uint64(bf.capacity),
+		Count:        uint64(bf.Count()),
+	})
+}
+
+// FromPybloom reads filter which is written by python-bloomfilter BloomFilter.tofile. Extended header,
+// format v2 and compressed files are not accepted. Filter uses IndexPybloom, so it finds all keys of python
+// filter for any number of slices and new keys get the same bits as python-bloomfilter sets.
+// Bytes after filter are not read.
+func FromPybloom(r io.Reader) (*BloomFilter, error) {
+
+	var header pybloomHeader
+	if err := binary.Read(r, binary.LittleEndian, &header); err != nil {
+		return nil, err
+	}
+
+	// magic of other formats is negative error rate
+	if !(header.ErrorRate > 0 && header.ErrorRate < 1) {
+		return nil, fmt.Errorf("wrong error rate of python-bloomfilter: %v", header.ErrorRate)
+	}
+
+	if header.Capacity < 1 || header.Capacity > math.MaxInt64 || header.Count > math.MaxInt64 {
+		return nil, fmt.Errorf("wrong capacity or count of python-bloomfilter: %d, %d", header.Capacity, header.Count)
+	}
+
+	o := defaultOptions()
+	o.errorRate = header.ErrorRate
+	o.indexMode = IndexPybloom
+
+	bf, err := newFromParams(o, v2Params{
+		ErrorRate:    header.ErrorRate,
+		NumSlices:    header.NumSlices,
+		BitsPerSlice: header.BitsPerSlice,
+		Capacity:     int64(header.Capacity),
+		Count:        int64(header.Count),
+		BitsSize:     (header.NumSlices*header.BitsPerSlice + 7) / 8,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	if bf.bitarray, err = newBitArray(bf.opts, bf.numBits); err != nil {
+		return nil, err
+	}
+
+	if err := readBits(bf, r); err != nil {
+		return nil, err
+	}
+
+	return bf, nil
+}
+
+// WritePybloom writes filter in python-bloomfilter format. It returns error if python-bloomfilter
+// sets other bits for the same keys, see PybloomCompatible. Returns number of written bytes.
+func (bf *BloomFilter) WritePybloom(w io.Writer) (int64, error) {
+
+	if !bf.PybloomCompatible() {
+		return 0, fmt.Errorf("filter can not be saved in python-bloomfilter format")
+	}
+
+	cw := &countingWriter{w: w}
+
+	header := bytes.NewBuffer([]byte{})
+	writePybloomHeader(header, bf)
+	if _, err := cw.Write(header.Bytes()); err != nil {
+		return cw.n, err
+	}
+
+	err := writeBits(cw, bf.bitarray)
+	return cw.n, err
+}
@@ -0,0 +1,207 @@
+package bloomfilter
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/binary"
+	"math"
+
+	"github.com/iostrovok/go-bloom-filter/bloomfilter/fortesting"
+	. "gopkg.in/check.v1"
+)
+
+func (s *filterTestSuite) TestPybloomGolden(c *C) {
+
+	cases := fortesting.LoadPybloomCases(c)
+	c.Assert(len(cases.Plain) > 0, Equals, true)
+	c.Assert(cases.Source, Not(Equals), "")
+	c.Assert(cases.Version, Not(Equals), "")
+
+	chunks := map[int]bool{}
+	hashes := map[string]bool{}
+
+	for _, tc := range cases.Plain {
+		comment := Commentf("%s", tc.Name)
+		golden := fortesting.ReadPybloomFile(c, "plain_"+tc.Name+".bin.gz")
+		keys := fortesting.PybloomKeys(tc.Name, tc.Keys)
+
+		// python file is read by the codec
+		loaded, err := FromPybloom(bytes.NewReader(golden))
+		c.Assert(err, IsNil, comment)
+		c.Assert(loaded.NumSlices(), Equals, tc.NumSlices, comment)
+		c.Assert(loaded.BitsPerSlice(), Equals, tc.BitsPerSlice, comment)
+		c.Assert(loaded.hasher.Name(), Equals, tc.Hash, comment)
+		c.Assert(loaded.Count(), Equals, tc.Count, comment)
+		c.Assert(loaded.PybloomCompatible(), Equals, true, comment)
+		for _, key := range keys {
+			c.Assert(loaded.Check([]byte(key)), Equals, true, comment)
+		}
+		chunks[loaded.chunkSize] = true
+		hashes[loaded.hasher.Name()] = true
+
+		written := bytes.NewBuffer([]byte{})
+		n, err := loaded.WritePybloom(written)
+		c.Assert(err, IsNil, comment)
+		c.Assert(n, Equals, int64(len(golden)), comment)
+		c.Assert(written.Bytes(), DeepEquals, golden, comment)
+
+		// the same keys give the same file
+		filter, err := NewWithOptions(tc.Capacity, WithErrorRate(tc.ErrorRate), WithIndexMode(IndexPybloom))
+		c.Assert(err, IsNil, comment)
+		for _, key := range keys {
+			_, err := filter.Add([]byte(key))
+			c.Assert(err, IsNil, comment)
+		}
+		written.Reset()
+		_, err = filter.WritePybloom(written)
+		c.Assert(err, IsNil, comment)
+		c.Assert(written.Bytes(), DeepEquals, golden, comment)
+
+		// Go file of the codec filter is read back with the same salts
+		binBuf := bytes.NewBuffer([]byte{})
+		c.Assert(loaded.ToBytes(binBuf), IsNil)
+		again, err := FromReader(bufio.NewReader(bytes.NewReader(binBuf.Bytes())), 0)
+		c.Assert(err, IsNil, comment)
+		c.Assert(Equal(loaded, again), Equals, true, comment)
+		for _, key := range keys {
+			c.Assert(again.Check([]byte(key)), Equals, true, comment)
+		}
+
+		// default filter is the same as python one while all positions fit into one digest
+		filter, err = New(tc.Capacity, tc.ErrorRate)
+		c.Assert(err, IsNil, comment)
+		for _, key := range keys {
+			_, err := filter.Add([]byte(key))
+			c.Assert(err, IsNil, comment)
+		}
+		binBuf.Reset()
+		c.Assert(filter.ToBytes(binBuf), IsNil)
+
+		if filter.saltsDiverge() {
+			c.Assert(filter.PybloomCompatible(), Equals, false, comment)
+			c.Assert(binBuf.Bytes()[:len(extMagic)], DeepEquals, extMagic, comment)
+			_, err = filter.WritePybloom(bytes.NewBuffer([]byte{}))
+			c.Assert(err, NotNil, comment)
+
+			// FromReader reads python file as default filter, so keys of python are lost
+			fromFile, err := FromReader(bufio.NewReader(bytes.NewReader(golden)), 0)
+			c.Assert(err, IsNil, comment)
+			missed := 0
+			for _, key := range keys {
+				if !fromFile.Check([]byte(key)) {
+					missed++
+				}
+			}
+			c.Assert(missed > 0, Equals, true, comment)
+		} else {
+			c.Assert(filter.PybloomCompatible(), Equals, true, comment)
+			c.Assert(binBuf.Bytes(), DeepEquals, golden, comment)
+
+			fromFile, err := FromReader(bufio.NewReader(bytes.NewReader(golden)), 0)
+			c.Assert(err, IsNil, comment)
+			c.Assert(Equal(fromFile, loaded), Equals, true, comment)
+		}
+	}
+
+	c.Assert(chunks, DeepEquals, map[int]bool{2: true, 4: true})
+	c.Assert(hashes, DeepEquals, map[string]bool{"md5": true, "sha1": true, "sha256": true, "sha384": true, "sha512": true})
+}
+
+func (s *filterTestSuite) TestPybloomIndexes(c *C) {
+
+	chunks := map[int]bool{}
+	diverge := false
+
+	for _, tc := range fortesting.LoadPybloomIndexes(c) {
+		comment := Commentf("%d x %d", tc.NumSlices, tc.BitsPerSlice)
+
+		// bit array is not needed for positions, chunks of 8 bytes need gigabytes
+		bf := &BloomFilter{opts: defaultOptions()}
+		bf.opts.indexMode = IndexPybloom
+		bf.setup(0.5, tc.BitsPerSlice, tc.NumSlices, 1, 0)
+		c.Assert(bf.hasher.Name(), Equals, tc.Hash, comment)
+		chunks[bf.chunkSize] = true
+
+		legacy := &BloomFilter{opts: defaultOptions()}
+		legacy.setup(0.5, tc.BitsPerSlice, tc.NumSlices, 1, 0)
+		diverge = diverge || legacy.saltsDiverge()
+
+		sc := scratchPool.Get().(*scratch)
+		for i, key := range tc.Keys {
+			c.Assert(positions(bf, key, sc), DeepEquals, tc.Indexes[i], comment)
+
+			if !legacy.saltsDiverge() {
+				c.Assert(positions(legacy, key, sc), DeepEquals, tc.Indexes[i], comment)
+			}
+		}
+		scratchPool.Put(sc)
+	}
+
+	c.Assert(chunks, DeepEquals, map[int]bool{2: true, 4: true, 8: true})
+	c.Assert(diverge, Equals, true)
+}
+
+func positions(bf *BloomFilter, key string, sc *scratch) []uint64 {
+	out := []uint64{}
+	hashes := bf.makeSaltIterator([]byte(key), sc)
+	for k, find := hashes.next(); find; k, find = hashes.next() {
+		out = append(out, k)
+	}
+	return out
+}
+
+func (s *filterTestSuite) TestPybloomHeader(c *C) {
+
+	filter, err := New(1000, 0.01)
+	c.Assert(err, IsNil)
+	filter.Add([]byte("key"))
+
+	binBuf := bytes.NewBuffer([]byte{})
+	_, err = filter.WritePybloom(binBuf)
+	c.Assert(err, IsNil)
+	golden := binBuf.Bytes()
+	c.Assert(len(golden), Equals, PybloomHeaderLen+int(filter.NumSlices()*int(filter.BitsPerSlice())+7)/8)
+
+	// python-bloomfilter file with tail
+	loaded, err := FromPybloom(bytes.NewReader(append(append([]byte{}, golden...), "tail"...)))
+	c.Assert(err, IsNil)
+	c.Assert(loaded.Check([]byte("key")), Equals, true)
+
+	broken := func(offset int, value uint64) []byte {
+		out := append([]byte{}, golden...)
+		binary.LittleEndian.PutUint64(out[offset:], value)
+		return out
+	}
+
+	for _, data := range [][]byte{
+		golden[:20],
+		golden[:len(golden)-1],
+		broken(0, math.Float64bits(0)),
+		broken(0, math.Float64bits(1)),
+		broken(0, math.Float64bits(math.NaN())),
+		broken(8, 0),
+		broken(16, 0),
+		broken(16, math.MaxUint64),
+		broken(24, 0),
+		broken(32, math.MaxUint64),
+	} {
+		_, err := FromPybloom(bytes.NewReader(data))
+		c.Assert(err, NotNil)
+	}
+
+	// other formats of this package are not python-bloomfilter
+	for _, opts := range [][]Option{{WithSeed(3)}, {WithFileFormat(FormatV2)}, {WithIndexMode(IndexDoubleHashing)}} {
+		other, err := NewWithOptions(1000, opts...)
+		c.Assert(err, IsNil)
+
+		binBuf := bytes.NewBuffer([]byte{})
+		c.Assert(other.ToBytes(binBuf), IsNil)
+		_, err = FromPybloom(bytes.NewReader(binBuf.Bytes()))
+		c.Assert(err, NotNil)
+
+		if other.opts.format == FormatLegacy {
+			_, err = other.WritePybloom(bytes.NewBuffer([]byte{}))
+			c.Assert(err, NotNil)
+		}
+	}
+}
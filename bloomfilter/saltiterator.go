@@ -1,21 +1,45 @@
 package bloomfilter
 
+import (
+	"encoding/binary"
+	"sync"
+)
+
 // --------------------------------------------------------
 
+// scratch keeps buffers for hashing. Add and Check take it from scratchPool,
+// so hashing makes no allocations in steady state.
+type scratch struct {
+	buf    []byte
+	digest []byte
+}
+
+var scratchPool = sync.Pool{
+	New: func() interface{} {
+		return &scratch{
+			buf:    make([]byte, 0, 256),
+			digest: make([]byte, 0, 64),
+		}
+	},
+}
+
 type hashFN struct {
-	name string
-	salt []byte
+	hasher Hasher
+	salt   []byte
 }
 
-func newHashFN(name string, salt []byte) (h *hashFN) {
+func newHashFN(hasher Hasher, salt []byte) (h *hashFN) {
 	return &hashFN{
-		name: name,
-		salt: salt,
+		hasher: hasher,
+		salt:   salt,
 	}
 }
 
-func (it *hashFN) run(key []byte) []byte {
-	return sum(it.name, append(it.salt, key...))
+// run returns digest of salt + key. Salt is copied to scratch buffer, so shared salt is never changed.
+func (it *hashFN) run(sc *scratch, key []byte) []byte {
+	sc.buf = append(append(sc.buf[:0], it.salt...), key...)
+	sc.digest = it.hasher.Sum(sc.digest[:0], sc.buf)
+	return sc.digest
 }
 
 type saltIterator struct {
@@ -24,29 +48,62 @@ type saltIterator struct {
 	numSaltFunctions int
 	saltFunctions    []*hashFN
 	key              []byte
-	tmpBody          []uint64
+	body             []byte
 	bitsPerSlice     uint64
 	chunkSize        int
+	sc               *scratch
+
+	doubleHashing bool
+	h1, h2        uint64
 }
 
 func (it *saltIterator) next() (uint64, bool) {
 
+	if it.doubleHashing {
+		return it.nextDouble()
+	}
+
 	if it.count >= it.numSlices || it.i > it.numSaltFunctions {
 		return 0, false
 	}
 
-	if it.count == 0 || it.j >= len(it.tmpBody) {
-		it.tmpBody = unpack(it.chunkSize, it.saltFunctions[it.i%len(it.saltFunctions)].run(it.key))
+	if it.count == 0 || it.j+it.chunkSize > len(it.body) {
+		it.body = it.saltFunctions[it.i%len(it.saltFunctions)].run(it.sc, it.key)
 		it.j = 0
 		it.i++
 	}
 
-	res := it.tmpBody[it.j] % it.bitsPerSlice
+	res := unpackOne(it.chunkSize, it.body[it.j:]) % it.bitsPerSlice
 
 	it.count++
-	it.j++
+	it.j += it.chunkSize
 
 	return res, true
 }
 
+// nextDouble returns indexes (h1 + i*h2) mod bitsPerSlice (Kirsch-Mitzenmacher).
+// The sum is calculated with uint64 wraparound, it mixes high bits of hashes into result.
+func (it *saltIterator) nextDouble() (uint64, bool) {
+
+	if it.count >= it.numSlices {
+		return 0, false
+	}
+
+	if it.count == 0 {
+		digest := it.saltFunctions[0].run(it.sc, it.key)
+		it.h1 = binary.LittleEndian.Uint64(digest)
+		if len(digest) >= 16 {
+			it.h2 = binary.LittleEndian.Uint64(digest[8:])
+		} else {
+			it.h2 = binary.LittleEndian.Uint64(it.saltFunctions[1].run(it.sc, it.key))
+		}
+	} else {
+		it.h1 += it.h2
+	}
+
+	it.count++
+
+	return it.h1 % it.bitsPerSlice, true
+}
+
 // --------------------------------------------------------
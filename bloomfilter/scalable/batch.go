@@ -0,0 +1,62 @@
+package scalable
+
+// AddMany adds keys in input order. Returns number of keys which were not found in filter.
+// Keys are checked by worker pool of inner filters, see bloomfilter.WithWorkers.
+func (sbf *Filter) AddMany(keys [][]byte) (added int, err error) {
+
+	found := sbf.CheckMany(keys)
+
+	for i, key := range keys {
+		if found[i] {
+			continue
+		}
+
+		// key may be added before by the same batch, so Add checks it again
+		res, err := sbf.Add(key)
+		if err != nil {
+			return added, err
+		}
+		if !res {
+			added++
+		}
+	}
+
+	return added, nil
+}
+
+// CheckMany checks keys. Result has the same order as keys.
+func (sbf *Filter) CheckMany(keys [][]byte) []bool {
+
+	out := make([]bool, len(keys), len(keys))
+
+	sbf.mc.RLock()
+	filters := sbf.filters
+	sbf.mc.RUnlock()
+
+	index := make([]int, len(keys), len(keys))
+	for i := range index {
+		index[i] = i
+	}
+
+	for i := len(filters) - 1; i > -1 && len(index) > 0; i-- {
+
+		rest := make([][]byte, len(index), len(index))
+		for j, k := range index {
+			rest[j] = keys[k]
+		}
+
+		res := filters[i].CheckMany(rest)
+
+		notFound := index[:0]
+		for j, k := range index {
+			if res[j] {
+				out[k] = true
+			} else {
+				notFound = append(notFound, k)
+			}
+		}
+		index = notFound
+	}
+
+	return out
+}
@@ -0,0 +1,146 @@
+package scalable
+
+import (
+	"bufio"
+	"bytes"
+
+	"github.com/iostrovok/go-bloom-filter/bloomfilter"
+	"github.com/iostrovok/go-bloom-filter/bloomfilter/array"
+	"github.com/iostrovok/go-bloom-filter/bloomfilter/fortesting"
+	. "gopkg.in/check.v1"
+)
+
+func (s *scalTestSuite) TestAddManyCheckMany(c *C) {
+
+	testArray := fortesting.ArrayForTesting()
+	keys := make([][]byte, 0, len(testArray)+100)
+	for _, s := range testArray {
+		keys = append(keys, []byte(s))
+	}
+	keys = append(keys, keys[:100]...)
+
+	for _, workers := range []int{1, 4} {
+		filter, err := NewWithOptions(100, 0.0001, SmallSetGrowth, bloomfilter.WithWorkers(workers))
+		c.Assert(err, IsNil)
+
+		added, err := filter.AddMany(keys)
+		c.Assert(err, IsNil)
+		c.Assert(added, Equals, len(testArray))
+		c.Assert(filter.Count(), Equals, int64(len(testArray)))
+
+		missed := make([][]byte, 0, 2*len(testArray))
+		for _, s := range testArray {
+			missed = append(missed, []byte(s), []byte(s+"eee-m"))
+		}
+
+		res := filter.CheckMany(missed)
+		c.Assert(len(res), Equals, len(missed))
+		for i := range missed {
+			c.Assert(res[i], Equals, i%2 == 0)
+		}
+	}
+}
+
+func (s *scalTestSuite) TestNewWithOptions(c *C) {
+
+	filter, err := NewWithOptions(100, 0.0001, SmallSetGrowth, bloomfilter.WithHashStrategy("unknown"))
+	c.Assert(err, NotNil)
+	c.Assert(filter, IsNil)
+
+	filter, err = NewWithOptions(100, 0.0001, SmallSetGrowth, bloomfilter.WithHashStrategy(bloomfilter.HashXXHash64))
+	c.Assert(err, IsNil)
+
+	for _, s := range fortesting.ArrayForTesting() {
+		res, err := filter.Add([]byte(s))
+		c.Assert(err, IsNil)
+		c.Assert(res, Equals, false)
+	}
+
+	filterNew, err := FromReader(bufio.NewReader(bytes.NewReader(filter.ToBytes())))
+	c.Assert(err, IsNil)
+	c.Assert(filterNew.Count(), Equals, filter.Count())
+	c.Assert(len(filterNew.opts) > 0, Equals, true)
+
+	for _, s := range fortesting.ArrayForTesting() {
+		c.Assert(filterNew.Check([]byte(s)), Equals, true)
+	}
+
+	c.Assert(filterNew.Merge(filter), IsNil)
+
+	filterMD5, err := New(100, 0.0001)
+	c.Assert(err, IsNil)
+	_, err = filterMD5.AddMany([][]byte{[]byte("a")})
+	c.Assert(err, IsNil)
+	c.Assert(filterMD5.Merge(filter), NotNil)
+}
+
+func (s *scalTestSuite) TestFromReaderEmpty(c *C) {
+
+	for _, opts := range [][]bloomfilter.Option{
+		{},
+		{bloomfilter.WithHashStrategy(bloomfilter.HashXXHash64)},
+		{bloomfilter.WithFileFormat(bloomfilter.FormatV2)},
+		{bloomfilter.WithFileFormat(bloomfilter.FormatV2), bloomfilter.WithHashStrategy(bloomfilter.HashXXHash64),
+			bloomfilter.WithSeed(7), bloomfilter.WithIndexMode(bloomfilter.IndexDoubleHashing)},
+	} {
+		filter, err := NewWithOptions(100, 0.0001, SmallSetGrowth, opts...)
+		c.Assert(err, IsNil)
+
+		// saved filter has no inner filters
+		data := filter.ToBytes()
+		filterNew, err := FromReader(bufio.NewReader(bytes.NewReader(data)))
+		c.Assert(err, IsNil)
+		c.Assert(len(filterNew.filters), Equals, 0)
+		c.Assert(filterNew.Count(), Equals, int64(0))
+
+		// format v2 keeps options of new inner filters
+		if bloomfilter.FileFormatOf(opts...) == bloomfilter.FormatV2 {
+			c.Assert(bloomfilter.FileFormatOf(filterNew.opts...), Equals, bloomfilter.FormatV2)
+			c.Assert(bloomfilter.HashStrategyOf(filterNew.opts...), Equals, bloomfilter.HashStrategyOf(opts...))
+			c.Assert(bloomfilter.SeedOf(filterNew.opts...), Equals, bloomfilter.SeedOf(opts...))
+			c.Assert(bloomfilter.IndexModeOf(filterNew.opts...), Equals, bloomfilter.IndexModeOf(opts...))
+			c.Assert(filterNew.ToBytes(), DeepEquals, data)
+		}
+
+		_, err = filterNew.Add([]byte("key"))
+		c.Assert(err, IsNil)
+		c.Assert(filterNew.Check([]byte("key")), Equals, true)
+
+		// the first inner filter of format v2 hashes keys as filter which was saved
+		if bloomfilter.FileFormatOf(opts...) == bloomfilter.FormatV2 {
+			_, err = filter.Add([]byte("key"))
+			c.Assert(err, IsNil)
+			c.Assert(Equal(filter, filterNew), Equals, true)
+		}
+	}
+}
+
+func (s *scalTestSuite) TestWithStore(c *C) {
+
+	stores := []*array.Atomic{}
+	factory := func(length uint64) (bloomfilter.BitStore, error) {
+		store := array.NewAtomic(length)
+		stores = append(stores, store)
+		return store, nil
+	}
+
+	filter, err := NewWithOptions(100, 0.0001, SmallSetGrowth, bloomfilter.WithStore(factory))
+	c.Assert(err, IsNil)
+	c.Assert(len(stores), Equals, 0)
+
+	testArray := fortesting.ArrayForTesting()
+	for _, s := range testArray {
+		_, err := filter.Add([]byte(s))
+		c.Assert(err, IsNil)
+	}
+	for _, s := range testArray {
+		c.Assert(filter.Check([]byte(s)), Equals, true)
+	}
+
+	// every inner filter has own store
+	c.Assert(len(stores), Equals, len(filter.filters))
+	for i, store := range stores {
+		c.Assert(store.Len(), Equals, uint64(filter.filters[i].NumSlices())*filter.filters[i].BitsPerSlice())
+		c.Assert(store.PopCount() > 0, Equals, true)
+	}
+}
@@ -0,0 +1,97 @@
+package scalable
+
+import (
+	"bufio"
+	"bytes"
+	"io"
+	"os"
+	"path/filepath"
+
+	"github.com/iostrovok/go-bloom-filter/bloomfilter"
+	"github.com/iostrovok/go-bloom-filter/bloomfilter/fortesting"
+	. "gopkg.in/check.v1"
+)
+
+func (s *scalTestSuite) TestCompression(c *C) {
+
+	testArray := fortesting.ArrayForTesting()
+	dir := c.MkDir()
+
+	for _, opts := range [][]bloomfilter.Option{
+		{},
+		{bloomfilter.WithFileFormat(bloomfilter.FormatV2)},
+	} {
+		filter, err := NewWithOptions(len(testArray)/2, 0.0001, LargeSetGrowth,
+			append(opts, bloomfilter.WithCompression(bloomfilter.CompressionGzip))...)
+		c.Assert(err, IsNil)
+
+		for _, s := range testArray {
+			_, err := filter.Add([]byte(s))
+			c.Assert(err, IsNil)
+		}
+		c.Assert(len(filter.filters) > 1, Equals, true)
+
+		fileName := filepath.Join(dir, "compressed.bin")
+		c.Assert(filter.ToFile(fileName), IsNil)
+
+		data, err := os.ReadFile(fileName)
+		c.Assert(err, IsNil)
+		c.Assert(data[:len(compressedMagic)], DeepEquals, compressedMagic)
+
+		loaded, err := FromFile(fileName)
+		c.Assert(err, IsNil)
+		c.Assert(Equal(filter, loaded), Equals, true)
+		c.Assert(loaded.Count(), Equals, filter.Count())
+		c.Assert(loaded.ToBytes(), DeepEquals, filter.ToBytes())
+		c.Assert(bloomfilter.CompressionOf(loaded.opts...), Equals, bloomfilter.CompressionGzip)
+
+		// ReadFrom reads compressed file and leaves data after it
+		stream := bytes.NewReader(append(append([]byte{}, data...), "tail"...))
+		read := &Filter{}
+		n, err := read.ReadFrom(stream)
+		c.Assert(err, IsNil)
+		c.Assert(n, Equals, int64(len(data)))
+		c.Assert(Equal(filter, read), Equals, true)
+		c.Assert(bloomfilter.CompressionOf(read.opts...), Equals, bloomfilter.CompressionGzip)
+		tail, err := io.ReadAll(stream)
+		c.Assert(err, IsNil)
+		c.Assert(string(tail), Equals, "tail")
+
+		unmarshaled := &Filter{}
+		c.Assert(unmarshaled.UnmarshalBinary(data), IsNil)
+		c.Assert(Equal(filter, unmarshaled), Equals, true)
+
+		// saved again without changes
+		again := filepath.Join(dir, "again.bin")
+		c.Assert(loaded.ToFile(again), IsNil)
+		againData, err := os.ReadFile(again)
+		c.Assert(err, IsNil)
+		c.Assert(againData, DeepEquals, data)
+
+		_, err = FromReader(bufio.NewReader(bytes.NewReader(data[:len(data)-10])))
+		c.Assert(err, NotNil)
+		_, err = (&Filter{}).ReadFrom(bytes.NewReader(data[:len(data)-10]))
+		c.Assert(err, NotNil)
+
+		_, err = FromFileMapped(fileName, false)
+		c.Assert(err, NotNil)
+	}
+
+	// young filter is mostly zero bytes
+	young, err := NewWithOptions(1000*1000, 0.001, SmallSetGrowth, bloomfilter.WithCompression(bloomfilter.CompressionGzip))
+	c.Assert(err, IsNil)
+	for _, s := range testArray {
+		_, err := young.Add([]byte(s))
+		c.Assert(err, IsNil)
+	}
+
+	fileName := filepath.Join(dir, "young.bin")
+	c.Assert(young.ToFile(fileName), IsNil)
+	info, err := os.Stat(fileName)
+	c.Assert(err, IsNil)
+	c.Assert(info.Size() < int64(len(young.ToBytes())/20), Equals, true)
+
+	loaded, err := FromFile(fileName)
+	c.Assert(err, IsNil)
+	c.Assert(Equal(young, loaded), Equals, true)
+}
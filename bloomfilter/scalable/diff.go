@@ -0,0 +1,61 @@
+package scalable
+
+import (
+	"fmt"
+
+	"github.com/iostrovok/go-bloom-filter/bloomfilter"
+)
+
+// Equal returns true if filters have the same parameters and inner filters with the same bits.
+func Equal(a, b *Filter) bool {
+
+	if err := a.compare(b); err != nil {
+		return false
+	}
+
+	filtersA, filtersB := a.innerFilters(), b.innerFilters()
+	if len(filtersA) != len(filtersB) {
+		return false
+	}
+
+	for i := range filtersA {
+		if !bloomfilter.Equal(filtersA[i], filtersB[i]) {
+			return false
+		}
+	}
+
+	return true
+}
+
+// Diff returns report of differing bits for every inner filter, see bloomfilter.Diff.
+// Filters must have the same parameters and the same number of inner filters.
+func Diff(a, b *Filter, limit int) ([]*bloomfilter.DiffReport, error) {
+
+	if err := a.compare(b); err != nil {
+		return nil, err
+	}
+
+	filtersA, filtersB := a.innerFilters(), b.innerFilters()
+	if len(filtersA) != len(filtersB) {
+		return nil, fmt.Errorf("Wrong number of filters: %d != %d", len(filtersA), len(filtersB))
+	}
+
+	out := make([]*bloomfilter.DiffReport, len(filtersA), len(filtersA))
+	for i := range filtersA {
+		report, err := bloomfilter.Diff(filtersA[i], filtersB[i], limit)
+		if err != nil {
+			return nil, err
+		}
+		out[i] = report
+	}
+
+	return out, nil
+}
+
+// innerFilters returns copy of list of inner filters
+func (sbf *Filter) innerFilters() []*bloomfilter.BloomFilter {
+	sbf.mc.RLock()
+	defer sbf.mc.RUnlock()
+
+	return append([]*bloomfilter.BloomFilter{}, sbf.filters...)
+}
@@ -0,0 +1,145 @@
+package scalable
+
+import (
+	"bufio"
+	"bytes"
+	"math"
+
+	"github.com/iostrovok/go-bloom-filter/bloomfilter"
+	"github.com/iostrovok/go-bloom-filter/bloomfilter/fortesting"
+	. "gopkg.in/check.v1"
+)
+
+func (s *scalTestSuite) TestEstimateCardinality(c *C) {
+
+	filter, err := New(100, 0.001)
+	c.Assert(err, IsNil)
+	c.Assert(filter.EstimateCardinality(), Equals, float64(0))
+
+	testArray := fortesting.ArrayForTesting()
+	for _, s := range testArray {
+		_, err := filter.Add([]byte(s))
+		c.Assert(err, IsNil)
+	}
+
+	estimate := filter.EstimateCardinality()
+	total := float64(filter.Count())
+	c.Assert(math.Abs(estimate-total) < 0.05*total, Equals, true, Commentf("%f", estimate))
+}
+
+func (s *scalTestSuite) TestEstimatedFPR(c *C) {
+
+	filter, err := New(100, 0.01)
+	c.Assert(err, IsNil)
+	c.Assert(filter.FillRatio(), Equals, float64(0))
+	c.Assert(filter.EstimatedFPR(), Equals, float64(0))
+
+	for _, s := range fortesting.ArrayForTesting() {
+		_, err := filter.Add([]byte(s))
+		c.Assert(err, IsNil)
+	}
+
+	c.Assert(filter.FillRatio() > 0, Equals, true)
+	c.Assert(filter.FillRatio() < 0.6, Equals, true)
+	c.Assert(filter.EstimatedFPR() > 0, Equals, true)
+	c.Assert(filter.EstimatedFPR() < 0.01, Equals, true, Commentf("%f", filter.EstimatedFPR()))
+
+	product := 1.0
+	for _, f := range filter.filters {
+		product *= 1 - f.EstimatedFPR()
+	}
+	c.Assert(filter.EstimatedFPR(), Equals, 1-product)
+}
+
+func (s *scalTestSuite) TestEqualDiff(c *C) {
+
+	testArray := fortesting.ArrayForTesting()
+
+	filterA, err := New(100, 0.001)
+	c.Assert(err, IsNil)
+	filterB, err := New(100, 0.001)
+	c.Assert(err, IsNil)
+
+	for _, s := range testArray {
+		_, err := filterA.Add([]byte(s))
+		c.Assert(err, IsNil)
+		_, err = filterB.Add([]byte(s))
+		c.Assert(err, IsNil)
+	}
+
+	c.Assert(Equal(filterA, filterB), Equals, true)
+
+	loaded, err := FromReader(bufio.NewReader(bytes.NewReader(filterA.ToBytes())))
+	c.Assert(err, IsNil)
+	c.Assert(Equal(filterA, loaded), Equals, true)
+
+	reports, err := Diff(filterA, loaded, 10)
+	c.Assert(err, IsNil)
+	c.Assert(reports, HasLen, len(filterA.filters))
+	for _, report := range reports {
+		c.Assert(report.Equal(), Equals, true)
+	}
+
+	last := len(filterB.filters) - 1
+	_, err = filterB.filters[last].Add([]byte("new key"), true)
+	c.Assert(err, IsNil)
+	c.Assert(Equal(filterA, filterB), Equals, false)
+
+	reports, err = Diff(filterA, filterB, 10)
+	c.Assert(err, IsNil)
+	for i, report := range reports {
+		c.Assert(report.Equal(), Equals, i != last)
+	}
+
+	small, err := New(100, 0.001)
+	c.Assert(err, IsNil)
+	c.Assert(Equal(filterA, small), Equals, false)
+	_, err = Diff(filterA, small, 10)
+	c.Assert(err, NotNil)
+}
+
+func (s *scalTestSuite) TestFormatV2(c *C) {
+
+	testArray := fortesting.ArrayForTesting()
+
+	filter, err := NewWithOptions(100, 0.001, LargeSetGrowth, bloomfilter.WithFileFormat(bloomfilter.FormatV2))
+	c.Assert(err, IsNil)
+	filter.Setup(LargeSetGrowth, 0.8, 100, 0.001)
+
+	for _, s := range testArray {
+		_, err := filter.Add([]byte(s))
+		c.Assert(err, IsNil)
+	}
+
+	data := filter.ToBytes()
+	c.Assert(data[:len(v2Magic)], DeepEquals, v2Magic)
+
+	loaded, err := FromReader(bufio.NewReader(bytes.NewReader(data)))
+	c.Assert(err, IsNil)
+	c.Assert(Equal(filter, loaded), Equals, true)
+	c.Assert(loaded.Count(), Equals, filter.Count())
+	c.Assert(loaded.ratio, Equals, 0.8)
+	c.Assert(loaded.ToBytes(), DeepEquals, data)
+
+	// header and every inner filter are checked
+	for _, i := range []int{12, int(v2HeaderLen(bloomfilter.HashAuto, len(filter.filters))) + 60, len(data) - 100} {
+		broken := append([]byte{}, data...)
+		broken[i] ^= 0x01
+		_, err = FromReader(bufio.NewReader(bytes.NewReader(broken)))
+		c.Assert(err, NotNil)
+	}
+
+	_, err = FromReader(bufio.NewReader(bytes.NewReader(data[:len(data)-3])))
+	c.Assert(err, NotNil)
+
+	// legacy format is still read
+	legacy, err := New(100, 0.001)
+	c.Assert(err, IsNil)
+	for _, s := range testArray {
+		_, err := legacy.Add([]byte(s))
+		c.Assert(err, IsNil)
+	}
+	loaded, err = FromReader(bufio.NewReader(bytes.NewReader(legacy.ToBytes())))
+	c.Assert(err, IsNil)
+	c.Assert(Equal(legacy, loaded), Equals, true)
+}
@@ -0,0 +1,185 @@
+package scalable
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/binary"
+	"fmt"
+	"hash/crc32"
+	"io"
+
+	"github.com/iostrovok/go-bloom-filter/bloomfilter"
+)
+
+/*
+	Format v2 of scalable bloom filter is written if inner filters use
+	bloomfilter.WithFileFormat(bloomfilter.FormatV2). FromReader detects it by magic.
+	Hashing of new inner filters is saved too, so filter without inner filters is read back
+	with the same options.
+
+	magic          [8]byte  "GoScale\xfe"
+	version        uint32   v2FormatVersion
+	layout         uint32   layoutFilters
+	hash           uint16 length + name of hash strategy, empty for HashAuto
+	seed           uint64
+	index mode     uint32
+	scale          int32
+	ratio          float64
+	initial cap    int64
+	error rate     float64
+	count filters  int32
+	sizes          [count filters]uint64
+	crc32c         uint32   Castagnoli checksum of all bytes before it
+	filters        inner filters in format v2, every filter has own checksum
+*/
+
+const v2FormatVersion = uint32(2)
+
+// layoutFilters keeps inner filters one by one after sizes.
+const layoutFilters = uint32(0)
+
+var v2Magic = []byte{'G', 'o', 'S', 'c', 'a', 'l', 'e', 0xfe}
+
+var castagnoli = crc32.MakeTable(crc32.Castagnoli)
+
+var checksumError = fmt.Errorf("wrong checksum of scalable bloom filter")
+
+type v2Header struct {
+	Magic   [8]byte
+	Version uint32
+	Layout  uint32
+}
+
+type v2Params struct {
+	Seed            uint64
+	IndexMode       uint32
+	Scale           int32
+	Ratio           float64
+	InitialCapacity int64
+	ErrorRate       float64
+	CountFilters    int32
+}
+
+// v2HeaderLen returns length of header, sizes and checksum before inner filters.
+func v2HeaderLen(hash bloomfilter.HashStrategy, countFilters int) int64 {
+	return int64(binary.Size(v2Header{}) + 2 + len(hash) + binary.Size(v2Params{}) + 8*countFilters + 4)
+}
+
+// writeV2Header writes header, sizes of inner filters and checksum of them.
+func (sbf *Filter) writeV2Header(binBuf *bytes.Buffer, filterSizes []uint64) {
+
+	header := v2Header{
+		Version: v2FormatVersion,
+		Layout:  layoutFilters,
+	}
+	copy(header.Magic[:], v2Magic)
+
+	hash := bloomfilter.HashStrategyOf(sbf.opts...)
+
+	begin := binBuf.Len()
+	binary.Write(binBuf, binary.LittleEndian, header)
+	binary.Write(binBuf, binary.LittleEndian, uint16(len(hash)))
+	binBuf.WriteString(string(hash))
+	binary.Write(binBuf, binary.LittleEndian, v2Params{
+		Seed:            bloomfilter.SeedOf(sbf.opts...),
+		IndexMode:       uint32(bloomfilter.IndexModeOf(sbf.opts...)),
+		Scale:           int32(sbf.scale),
+		Ratio:           sbf.ratio,
+		InitialCapacity: sbf.initialCapacity,
+		ErrorRate:       sbf.errorRate,
+		CountFilters:    int32(len(filterSizes)),
+	})
+	binary.Write(binBuf, binary.LittleEndian, filterSizes)
+	binary.Write(binBuf, binary.LittleEndian, crc32.Checksum(binBuf.Bytes()[begin:], castagnoli))
+}
+
+// isV2 returns true if data of reader starts with magic of format v2.
+func isV2(reader *bufio.Reader) bool {
+	b, err := reader.Peek(len(v2Magic))
+	return err == nil && bytes.Equal(b, v2Magic)
+}
+
+// readV2Header reads header of format v2, checks its checksum and creates filter without inner filters.
+func readV2Header(reader io.Reader) (*Filter, []uint64, error) {
+
+	crc := crc32.New(castagnoli)
+	tee := io.TeeReader(reader, crc)
+
+	var header v2Header
+	if err := binary.Read(tee, binary.LittleEndian, &header); err != nil {
+		return nil, nil, err
+	}
+
+	if !bytes.Equal(header.Magic[:], v2Magic) {
+		return nil, nil, fmt.Errorf("wrong format of scalable bloom filter")
+	}
+
+	if header.Version != v2FormatVersion {
+		return nil, nil, fmt.Errorf("unknown format version: %d", header.Version)
+	}
+
+	if header.Layout != layoutFilters {
+		return nil, nil, fmt.Errorf("unknown layout of filters: %d", header.Layout)
+	}
+
+	var nameLength uint16
+	if err := binary.Read(tee, binary.LittleEndian, &nameLength); err != nil {
+		return nil, nil, err
+	}
+
+	name := make([]byte, nameLength)
+	if _, err := io.ReadFull(tee, name); err != nil {
+		return nil, nil, err
+	}
+
+	var params v2Params
+	if err := binary.Read(tee, binary.LittleEndian, &params); err != nil {
+		return nil, nil, err
+	}
+
+	if params.CountFilters < 0 || params.CountFilters > 64 {
+		return nil, nil, fmt.Errorf("wrong number of filters: %d", params.CountFilters)
+	}
+
+	filterSizes := make([]uint64, params.CountFilters, params.CountFilters)
+	if err := binary.Read(tee, binary.LittleEndian, filterSizes); err != nil {
+		return nil, nil, err
+	}
+
+	var sum uint32
+	if err := binary.Read(reader, binary.LittleEndian, &sum); err != nil {
+		return nil, nil, err
+	}
+
+	if sum != crc.Sum32() {
+		return nil, nil, checksumError
+	}
+
+	// options of new inner filters, inner filters of file replace them by own ones
+	opts := []bloomfilter.Option{
+		bloomfilter.WithFileFormat(bloomfilter.FormatV2),
+		bloomfilter.WithSeed(params.Seed),
+		bloomfilter.WithIndexMode(bloomfilter.IndexMode(params.IndexMode)),
+	}
+
+	if len(name) > 0 {
+		hasher, find := bloomfilter.LookupHasher(string(name))
+		if !find {
+			return nil, nil, fmt.Errorf("unknown hash function: %q", name)
+		}
+		opts = append(opts, bloomfilter.WithHasher(hasher))
+	}
+
+	sbf, err := NewWithOptions(int(params.InitialCapacity), params.ErrorRate, int(params.Scale), opts...)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	if err := sbf.Setup(int(params.Scale), params.Ratio, params.InitialCapacity, params.ErrorRate); err != nil {
+		return nil, nil, err
+	}
+
+	sbf.filters = make([]*bloomfilter.BloomFilter, params.CountFilters, params.CountFilters)
+
+	return sbf, filterSizes, nil
+}
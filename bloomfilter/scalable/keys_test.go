@@ -0,0 +1,31 @@
+package scalable
+
+import (
+	"github.com/iostrovok/go-bloom-filter/bloomfilter/fortesting"
+	. "gopkg.in/check.v1"
+)
+
+func (s *scalTestSuite) TestTypedKeys(c *C) {
+
+	filter, err := New(100, 0.0001)
+	c.Assert(err, IsNil)
+
+	for _, s := range fortesting.ArrayForTesting() {
+		_, err := filter.AddString(s)
+		c.Assert(err, IsNil)
+	}
+
+	for i := uint64(0); i < 1000; i++ {
+		_, err := filter.AddUint64(i)
+		c.Assert(err, IsNil)
+	}
+
+	for _, s := range fortesting.ArrayForTesting() {
+		c.Assert(filter.CheckString(s), Equals, true)
+		c.Assert(filter.Check([]byte(s)), Equals, true)
+	}
+
+	for i := uint64(0); i < 1000; i++ {
+		c.Assert(filter.CheckUint64(i), Equals, true)
+	}
+}
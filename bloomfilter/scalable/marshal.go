@@ -0,0 +1,96 @@
+package scalable
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+
+	"github.com/iostrovok/go-bloom-filter/bloomfilter"
+)
+
+// jsonFilter is JSON form of scalable filter, inner filters are saved by bloomfilter.BloomFilter.MarshalJSON.
+type jsonFilter struct {
+	Scale           int                        `json:"scale"`
+	Ratio           float64                    `json:"ratio"`
+	InitialCapacity int64                      `json:"initial_capacity"`
+	ErrorRate       float64                    `json:"error_rate"`
+	Filters         []*bloomfilter.BloomFilter `json:"filters"`
+}
+
+// MarshalBinary implements encoding.BinaryMarshaler. Data is the same as ToBytes returns.
+func (sbf *Filter) MarshalBinary() ([]byte, error) {
+	binBuf := bytes.NewBuffer([]byte{})
+	if _, err := sbf.WriteTo(binBuf); err != nil {
+		return nil, err
+	}
+	return binBuf.Bytes(), nil
+}
+
+// UnmarshalBinary implements encoding.BinaryUnmarshaler. It reads all formats of FromReader.
+func (sbf *Filter) UnmarshalBinary(data []byte) error {
+
+	n, err := sbf.ReadFrom(bytes.NewReader(data))
+	if err != nil {
+		return err
+	}
+
+	if n != int64(len(data)) {
+		return fmt.Errorf("unexpected %d bytes after scalable bloom filter", int64(len(data))-n)
+	}
+
+	return nil
+}
+
+// GobEncode implements gob.GobEncoder, see MarshalBinary.
+func (sbf *Filter) GobEncode() ([]byte, error) {
+	return sbf.MarshalBinary()
+}
+
+// GobDecode implements gob.GobDecoder, see UnmarshalBinary.
+func (sbf *Filter) GobDecode(data []byte) error {
+	return sbf.UnmarshalBinary(data)
+}
+
+// MarshalJSON implements json.Marshaler. JSON keeps parameters of filter and all inner filters.
+func (sbf *Filter) MarshalJSON() ([]byte, error) {
+
+	sbf.mc.RLock()
+	defer sbf.mc.RUnlock()
+
+	return json.Marshal(jsonFilter{
+		Scale:           sbf.scale,
+		Ratio:           sbf.ratio,
+		InitialCapacity: sbf.initialCapacity,
+		ErrorRate:       sbf.errorRate,
+		Filters:         sbf.filters,
+	})
+}
+
+// UnmarshalJSON implements json.Unmarshaler. Parameters are checked as for files.
+func (sbf *Filter) UnmarshalJSON(data []byte) error {
+
+	var in jsonFilter
+	if err := json.Unmarshal(data, &in); err != nil {
+		return err
+	}
+
+	out := &Filter{}
+	if err := out.Setup(in.Scale, in.Ratio, in.InitialCapacity, in.ErrorRate); err != nil {
+		return err
+	}
+
+	for i, filter := range in.Filters {
+		if filter == nil {
+			return fmt.Errorf("filter %d is not found", i)
+		}
+	}
+
+	out.filters = in.Filters
+	if out.filters == nil {
+		out.filters = []*bloomfilter.BloomFilter{}
+	}
+	out.setOptions()
+
+	sbf.replace(out)
+	return nil
+}
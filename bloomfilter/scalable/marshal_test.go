@@ -0,0 +1,95 @@
+package scalable
+
+import (
+	"bytes"
+	"encoding"
+	"encoding/gob"
+	"encoding/json"
+
+	"github.com/iostrovok/go-bloom-filter/bloomfilter"
+	"github.com/iostrovok/go-bloom-filter/bloomfilter/fortesting"
+	. "gopkg.in/check.v1"
+)
+
+var (
+	_ encoding.BinaryMarshaler   = &Filter{}
+	_ encoding.BinaryUnmarshaler = &Filter{}
+	_ gob.GobEncoder             = &Filter{}
+	_ gob.GobDecoder             = &Filter{}
+	_ json.Marshaler             = &Filter{}
+	_ json.Unmarshaler           = &Filter{}
+)
+
+type filterHolder struct {
+	Name   string
+	Filter *Filter
+}
+
+func (s *scalTestSuite) TestMarshal(c *C) {
+
+	testArray := fortesting.ArrayForTesting()
+
+	for _, opts := range [][]bloomfilter.Option{
+		{},
+		{bloomfilter.WithFileFormat(bloomfilter.FormatV2)},
+		{bloomfilter.WithHashStrategy(bloomfilter.HashFNV1a64)},
+	} {
+		filter, err := NewWithOptions(100, 0.001, SmallSetGrowth, opts...)
+		c.Assert(err, IsNil)
+		filter.Setup(SmallSetGrowth, 0.8, 100, 0.001)
+
+		for _, s := range testArray {
+			_, err := filter.Add([]byte(s))
+			c.Assert(err, IsNil)
+		}
+
+		check := func(loaded *Filter) {
+			c.Assert(Equal(filter, loaded), Equals, true)
+			c.Assert(loaded.Count(), Equals, filter.Count())
+			c.Assert(loaded.ratio, Equals, filter.ratio)
+			c.Assert(loaded.ToBytes(), DeepEquals, filter.ToBytes())
+			for _, s := range testArray {
+				c.Assert(loaded.Check([]byte(s)), Equals, true)
+			}
+		}
+
+		// binary
+		data, err := filter.MarshalBinary()
+		c.Assert(err, IsNil)
+		c.Assert(data, DeepEquals, filter.ToBytes())
+		loaded := &Filter{}
+		c.Assert(loaded.UnmarshalBinary(data), IsNil)
+		c.Assert(loaded.UnmarshalBinary(append(data, 0)), NotNil)
+
+		// gob
+		buf := bytes.NewBuffer([]byte{})
+		c.Assert(gob.NewEncoder(buf).Encode(filterHolder{Name: "gob", Filter: filter}), IsNil)
+		var fromGob filterHolder
+		c.Assert(gob.NewDecoder(buf).Decode(&fromGob), IsNil)
+		c.Assert(fromGob.Name, Equals, "gob")
+		check(fromGob.Filter)
+
+		// json
+		text, err := json.Marshal(filterHolder{Name: "json", Filter: filter})
+		c.Assert(err, IsNil)
+		var fromJSON filterHolder
+		c.Assert(json.Unmarshal(text, &fromJSON), IsNil)
+		c.Assert(fromJSON.Name, Equals, "json")
+		check(fromJSON.Filter)
+
+		// loaded filter grows with the same options
+		for _, s := range testArray {
+			_, err := fromJSON.Filter.Add([]byte("more " + s))
+			c.Assert(err, IsNil)
+		}
+	}
+
+	c.Assert(json.Unmarshal([]byte(`{"scale":2,"ratio":0.9,"initial_capacity":100,"error_rate":0.01,"filters":[null]}`), &Filter{}), NotNil)
+	c.Assert(json.Unmarshal([]byte(`{"scale":0,"ratio":0.9,"initial_capacity":100,"error_rate":0.01}`), &Filter{}), NotNil)
+
+	empty := &Filter{}
+	c.Assert(json.Unmarshal([]byte(`{"scale":2,"ratio":0.9,"initial_capacity":100,"error_rate":0.01}`), empty), IsNil)
+	_, err := empty.Add([]byte("key"))
+	c.Assert(err, IsNil)
+	c.Assert(empty.Check([]byte("key")), Equals, true)
+}
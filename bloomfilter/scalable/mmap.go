@@ -0,0 +1,112 @@
+package scalable
+
+import (
+	"bufio"
+	"bytes"
+	"fmt"
+	"os"
+
+	"github.com/iostrovok/go-bloom-filter/bloomfilter"
+	"github.com/iostrovok/go-bloom-filter/bloomfilter/array"
+)
+
+// FromFileMapped opens scalable bloom filter file in place by mmap. Inner filters are not read into memory,
+// so opening is fast for huge files. Read-only filter returns error from Add.
+// Changes of writable filter are saved into file by Sync and Close. Mapped filter can not grow,
+// Add returns error when the last inner filter is full.
+func FromFileMapped(fileName string, writable bool) (*Filter, error) {
+
+	flag := os.O_RDONLY
+	if writable {
+		flag = os.O_RDWR
+	}
+
+	file, err := os.OpenFile(fileName, flag, 0)
+	if err != nil {
+		return nil, err
+	}
+	// mapping is valid after file closing
+	defer file.Close()
+
+	m, err := array.Map(file, writable)
+	if err != nil {
+		return nil, err
+	}
+
+	sbf, err := fromMapping(m)
+	if err != nil {
+		m.Close()
+		return nil, err
+	}
+
+	return sbf, nil
+}
+
+func fromMapping(m *array.Mapping) (*Filter, error) {
+
+	reader := bufio.NewReader(bytes.NewReader(m.Bytes()))
+	if isCompressed(reader) {
+		return nil, fmt.Errorf("compressed scalable bloom filter can not be mapped")
+	}
+
+	var sbf *Filter
+	var filterSizes []uint64
+	var err error
+	var offset int64
+
+	// header, sizes of filters and filters
+	if isV2(reader) {
+		sbf, filterSizes, err = readV2Header(reader)
+		if err == nil {
+			offset = v2HeaderLen(bloomfilter.HashStrategyOf(sbf.opts...), len(filterSizes))
+		}
+	} else {
+		sbf, filterSizes, err = readHeader(reader)
+		offset = int64(PybloomHeaderLen + 8*len(filterSizes))
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	for i, length := range filterSizes {
+		sbf.filters[i], err = bloomfilter.FromMapping(m, offset)
+		if err != nil {
+			return nil, err
+		}
+		offset += int64(length)
+	}
+
+	sbf.setOptions()
+	sbf.mapping = m
+
+	return sbf, nil
+}
+
+// Sync saves changes of writable mapped filter into file (msync).
+// It does nothing for other filters.
+func (sbf *Filter) Sync() error {
+	sbf.mc.RLock()
+	defer sbf.mc.RUnlock()
+
+	for _, f := range sbf.filters {
+		if err := f.Sync(); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// Close saves changes of mapped filter and unmaps file.
+// Filter must not be used after Close. It does nothing for other filters.
+func (sbf *Filter) Close() error {
+	if err := sbf.Sync(); err != nil {
+		return err
+	}
+
+	if sbf.mapping == nil {
+		return nil
+	}
+
+	return sbf.mapping.Close()
+}
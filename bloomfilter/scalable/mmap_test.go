@@ -0,0 +1,82 @@
+//go:build unix
+
+package scalable
+
+import (
+	"os"
+	"path/filepath"
+
+	"github.com/iostrovok/go-bloom-filter/bloomfilter"
+	"github.com/iostrovok/go-bloom-filter/bloomfilter/fortesting"
+	. "gopkg.in/check.v1"
+)
+
+func (s *scalTestSuite) TestFromFileMapped(c *C) {
+
+	filter, err := New(100, 0.001)
+	c.Assert(err, IsNil)
+	checkFromFileMapped(c, filter)
+
+	filter, err = NewWithOptions(100, 0.001, SmallSetGrowth, bloomfilter.WithFileFormat(bloomfilter.FormatV2))
+	c.Assert(err, IsNil)
+	checkFromFileMapped(c, filter)
+}
+
+func checkFromFileMapped(c *C, filter *Filter) {
+
+	testArray := fortesting.ArrayForTesting()
+
+	for _, s := range testArray {
+		_, err := filter.Add([]byte(s))
+		c.Assert(err, IsNil)
+	}
+
+	fileName := filepath.Join(c.MkDir(), "mapped.bin")
+	c.Assert(filter.ToFile(fileName), IsNil)
+
+	readOnly, err := FromFileMapped(fileName, false)
+	c.Assert(err, IsNil)
+	c.Assert(readOnly.Count(), Equals, filter.Count())
+	c.Assert(len(readOnly.filters), Equals, len(filter.filters))
+	for _, s := range testArray {
+		c.Assert(readOnly.Check([]byte(s)), Equals, true)
+	}
+	_, err = readOnly.Add([]byte("new key"))
+	c.Assert(err, NotNil)
+	c.Assert(readOnly.Close(), IsNil)
+
+	writable, err := FromFileMapped(fileName, true)
+	c.Assert(err, IsNil)
+
+	// the last inner filter is not full yet
+	last := writable.filters[len(writable.filters)-1]
+	free := last.Capacity() - last.Count()
+	added := int64(0)
+	keys := []string{}
+	for i := 0; added < free; i++ {
+		found, err := writable.Add([]byte(testArray[i] + "-mapped"))
+		c.Assert(err, IsNil)
+		if !found {
+			added++
+		}
+		keys = append(keys, testArray[i]+"-mapped")
+	}
+
+	_, err = writable.Add([]byte("one more key"))
+	c.Assert(err, NotNil)
+	c.Assert(writable.Close(), IsNil)
+
+	data, err := os.ReadFile(fileName)
+	c.Assert(err, IsNil)
+	c.Assert(len(data), Equals, len(filter.ToBytes()))
+
+	filterNew, err := FromFile(fileName)
+	c.Assert(err, IsNil)
+	c.Assert(filterNew.Count(), Equals, filter.Count()+free)
+	for _, s := range testArray {
+		c.Assert(filterNew.Check([]byte(s)), Equals, true)
+	}
+	for _, s := range keys {
+		c.Assert(filterNew.Check([]byte(s)), Equals, true)
+	}
+}
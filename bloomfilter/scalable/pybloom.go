@@ -0,0 +1,128 @@
+package scalable
+
+import (
+	"bytes"
+	"encoding/binary"
+	"fmt"
+	"io"
+
+	"github.com/iostrovok/go-bloom-filter/bloomfilter"
+)
+
+/*
+	python-bloomfilter writes ScalableBloomFilter.tofile as 32 bytes header: struct '<idQd' and '<l',
+	then '<Q' size of every inner filter and inner filters in format of bloomfilter.FromPybloom.
+
+	scale             int32    SMALL_SET_GROWTH (2) or LARGE_SET_GROWTH (4)
+	ratio             float64  0.9
+	initial capacity  uint64
+	error rate        float64
+	count filters     int32
+	sizes             [count filters]uint64
+	filters           every one is bloomfilter.PybloomHeaderLen + (bits + 7) / 8 bytes
+
+	The first inner filter has initial capacity and error rate * (1 - ratio), every next one
+	has capacity * scale and error rate * ratio of previous one. Add of this package does the same.
+*/
+
+// PybloomHeaderLen is length of python-bloomfilter header before sizes of inner filters.
+const PybloomHeaderLen = 32
+
+type pybloomHeader struct {
+	Scale           int32
+	Ratio           float64
+	InitialCapacity uint64
+	ErrorRate       float64
+	CountFilters    int32
+}
+
+// FromPybloom reads scalable filter which is written by python-bloomfilter ScalableBloomFilter.tofile.
+// Header must be exactly python-bloomfilter one, every inner filter is read by bloomfilter.FromPybloom
+// and must have the size from header. Bytes after filter are not read.
+func FromPybloom(r io.Reader) (*Filter, error) {
+
+	var header pybloomHeader
+	if err := binary.Read(r, binary.LittleEndian, &header); err != nil {
+		return nil, err
+	}
+
+	if header.CountFilters < 0 || header.CountFilters > 64 {
+		return nil, fmt.Errorf("wrong number of filters: %d", header.CountFilters)
+	}
+
+	if header.InitialCapacity < 1 || header.InitialCapacity > 1<<62 {
+		return nil, fmt.Errorf("wrong initial capacity: %d", header.InitialCapacity)
+	}
+
+	sbf := &Filter{}
+	if err := sbf.Setup(int(header.Scale), header.Ratio, int64(header.InitialCapacity), header.ErrorRate); err != nil {
+		return nil, err
+	}
+
+	filterSizes, err := readArrayOfUint64(r, int(header.CountFilters))
+	if err != nil {
+		return nil, err
+	}
+
+	sbf.filters = make([]*bloomfilter.BloomFilter, len(filterSizes), len(filterSizes))
+	for i, size := range filterSizes {
+
+		cr := &countingReader{r: io.LimitReader(r, int64(size))}
+		if sbf.filters[i], err = bloomfilter.FromPybloom(cr); err != nil {
+			return nil, err
+		}
+
+		if cr.n != int64(size) || pybloomSize(sbf.filters[i]) != size {
+			return nil, fmt.Errorf("wrong size of filter %d: %d", i, size)
+		}
+	}
+
+	sbf.setOptions()
+
+	return sbf, nil
+}
+
+// WritePybloom writes scalable filter in python-bloomfilter format. It returns error
+// if some inner filter can not be saved by bloomfilter.BloomFilter.WritePybloom.
+// Returns number of written bytes.
+func (sbf *Filter) WritePybloom(w io.Writer) (int64, error) {
+
+	sbf.mc.RLock()
+	defer sbf.mc.RUnlock()
+
+	filterSizes := make([]uint64, len(sbf.filters), len(sbf.filters))
+	for i, filter := range sbf.filters {
+		if !filter.PybloomCompatible() {
+			return 0, fmt.Errorf("filter %d can not be saved in python-bloomfilter format", i)
+		}
+		filterSizes[i] = pybloomSize(filter)
+	}
+
+	header := bytes.NewBuffer([]byte{})
+	binary.Write(header, binary.LittleEndian, pybloomHeader{
+		Scale:           int32(sbf.scale),
+		Ratio:           sbf.ratio,
+		InitialCapacity: uint64(sbf.initialCapacity),
+		ErrorRate:       sbf.errorRate,
+		CountFilters:    int32(len(sbf.filters)),
+	})
+	binary.Write(header, binary.LittleEndian, filterSizes)
+
+	cw := &countingWriter{w: w}
+	if _, err := cw.Write(header.Bytes()); err != nil {
+		return cw.n, err
+	}
+
+	for _, filter := range sbf.filters {
+		if _, err := filter.WritePybloom(cw); err != nil {
+			return cw.n, err
+		}
+	}
+
+	return cw.n, nil
+}
+
+// pybloomSize returns length of inner filter in python-bloomfilter format.
+func pybloomSize(filter *bloomfilter.BloomFilter) uint64 {
+	return bloomfilter.PybloomHeaderLen + (uint64(filter.NumSlices())*filter.BitsPerSlice()+7)/8
+}
@@ -0,0 +1,142 @@
+package scalable
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/binary"
+
+	"github.com/iostrovok/go-bloom-filter/bloomfilter"
+	"github.com/iostrovok/go-bloom-filter/bloomfilter/fortesting"
+	. "gopkg.in/check.v1"
+)
+
+func (s *scalTestSuite) TestPybloomGolden(c *C) {
+
+	cases := fortesting.LoadPybloomCases(c)
+	c.Assert(len(cases.Scalable) > 0, Equals, true)
+
+	for _, tc := range cases.Scalable {
+		comment := Commentf("%s", tc.Name)
+		golden := fortesting.ReadPybloomFile(c, "scalable_"+tc.Name+".bin.gz")
+		keys := fortesting.PybloomKeys(tc.Name, tc.Keys)
+
+		// python file is read by the codec
+		loaded, err := FromPybloom(bytes.NewReader(golden))
+		c.Assert(err, IsNil, comment)
+		c.Assert(len(loaded.filters), Equals, tc.Filters, comment)
+		c.Assert(loaded.scale, Equals, tc.Mode, comment)
+		c.Assert(loaded.initialCapacity, Equals, int64(tc.InitialCapacity), comment)
+		c.Assert(loaded.errorRate, Equals, tc.ErrorRate, comment)
+		for _, key := range keys {
+			c.Assert(loaded.Check([]byte(key)), Equals, true, comment)
+		}
+
+		written := bytes.NewBuffer([]byte{})
+		n, err := loaded.WritePybloom(written)
+		c.Assert(err, IsNil, comment)
+		c.Assert(n, Equals, int64(len(golden)), comment)
+		c.Assert(written.Bytes(), DeepEquals, golden, comment)
+
+		// the same keys give the same file
+		filter, err := NewWithOptions(tc.InitialCapacity, tc.ErrorRate, tc.Mode, bloomfilter.WithIndexMode(bloomfilter.IndexPybloom))
+		c.Assert(err, IsNil, comment)
+		for _, key := range keys {
+			_, err := filter.Add([]byte(key))
+			c.Assert(err, IsNil, comment)
+		}
+		written.Reset()
+		_, err = filter.WritePybloom(written)
+		c.Assert(err, IsNil, comment)
+		c.Assert(written.Bytes(), DeepEquals, golden, comment)
+
+		// Go file of the codec filter is read back
+		again, err := FromReader(bufio.NewReader(bytes.NewReader(loaded.ToBytes())))
+		c.Assert(err, IsNil, comment)
+		c.Assert(Equal(loaded, again), Equals, true, comment)
+
+		// default filter is the same as python one while all positions fit into one digest
+		filter, err = New(tc.InitialCapacity, tc.ErrorRate, tc.Mode)
+		c.Assert(err, IsNil, comment)
+		for _, key := range keys {
+			_, err := filter.Add([]byte(key))
+			c.Assert(err, IsNil, comment)
+		}
+
+		compatible := true
+		for _, f := range filter.filters {
+			compatible = compatible && f.PybloomCompatible()
+		}
+
+		if tc.Name == "salts" {
+			c.Assert(compatible, Equals, false, comment)
+			_, err = filter.WritePybloom(bytes.NewBuffer([]byte{}))
+			c.Assert(err, NotNil, comment)
+		} else {
+			c.Assert(compatible, Equals, true, comment)
+			c.Assert(filter.ToBytes(), DeepEquals, golden, comment)
+
+			fromFile, err := FromReader(bufio.NewReader(bytes.NewReader(golden)))
+			c.Assert(err, IsNil, comment)
+			c.Assert(Equal(fromFile, loaded), Equals, true, comment)
+		}
+	}
+}
+
+func (s *scalTestSuite) TestPybloomHeader(c *C) {
+
+	filter, err := New(100, 0.001)
+	c.Assert(err, IsNil)
+	for _, key := range fortesting.PybloomKeys("key", 300) {
+		_, err := filter.Add([]byte(key))
+		c.Assert(err, IsNil)
+	}
+	c.Assert(len(filter.filters) > 1, Equals, true)
+
+	binBuf := bytes.NewBuffer([]byte{})
+	_, err = filter.WritePybloom(binBuf)
+	c.Assert(err, IsNil)
+	golden := binBuf.Bytes()
+
+	// empty filter has header only
+	empty, err := New(100, 0.001)
+	c.Assert(err, IsNil)
+	header := bytes.NewBuffer([]byte{})
+	n, err := empty.WritePybloom(header)
+	c.Assert(err, IsNil)
+	c.Assert(n, Equals, int64(PybloomHeaderLen))
+	loaded, err := FromPybloom(bytes.NewReader(header.Bytes()))
+	c.Assert(err, IsNil)
+	c.Assert(len(loaded.filters), Equals, 0)
+
+	broken := func(offset int, value uint64, size int) []byte {
+		out := append([]byte{}, golden...)
+		if size == 4 {
+			binary.LittleEndian.PutUint32(out[offset:], uint32(value))
+		} else {
+			binary.LittleEndian.PutUint64(out[offset:], value)
+		}
+		return out
+	}
+
+	for _, data := range [][]byte{
+		golden[:20],
+		golden[:PybloomHeaderLen+4],
+		golden[:len(golden)-1],
+		broken(0, 1, 4),
+		broken(12, 0, 8),
+		broken(28, uint64(0xffffffff), 4),
+		broken(28, 65, 4),
+		broken(PybloomHeaderLen, 10, 8),
+		broken(PybloomHeaderLen, 1<<40, 8),
+	} {
+		_, err := FromPybloom(bytes.NewReader(data))
+		c.Assert(err, NotNil)
+	}
+
+	// other formats of this package are not python-bloomfilter
+	v2, err := NewWithOptions(100, 0.001, SmallSetGrowth, bloomfilter.WithFileFormat(bloomfilter.FormatV2))
+	c.Assert(err, IsNil)
+	v2.Add([]byte("key"))
+	_, err = FromPybloom(bytes.NewReader(v2.ToBytes()))
+	c.Assert(err, NotNil)
+}
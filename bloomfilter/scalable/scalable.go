@@ -5,10 +5,13 @@ import (
 	"bytes"
 	"encoding/binary"
 	"fmt"
+	"io"
+	"math"
 	"os"
 	"sync"
 
 	"github.com/iostrovok/go-bloom-filter/bloomfilter"
+	"github.com/iostrovok/go-bloom-filter/bloomfilter/array"
 )
 
 /*
@@ -24,6 +27,8 @@ const (
 	LargeSetGrowth = 4 // faster, but takes up more memory faster
 )
 
+var mappedError = fmt.Errorf("mapped scalable bloom filter can not grow")
+
 // Filter is a structure for scalable bloom filter.
 type Filter struct {
 	mc sync.RWMutex
@@ -34,6 +39,11 @@ type Filter struct {
 	ratio           float64
 	initialCapacity int64
 	errorRate       float64
+
+	opts []bloomfilter.Option
+
+	// mapped file, see FromFileMapped
+	mapping *array.Mapping
 }
 
 // New is constructor. It checks parameters and creates new scalable bloom filter.
@@ -56,6 +66,24 @@ func New(initialCapacity int, errorRate float64, modes ...int) (*Filter, error)
 	return sbf, nil
 }
 
+// NewWithOptions is constructor with options for every inner bloom filter.
+// Error rate and capacity of inner filters are defined by scalable filter.
+func NewWithOptions(initialCapacity int, errorRate float64, mode int, opts ...bloomfilter.Option) (*Filter, error) {
+
+	sbf, err := New(initialCapacity, errorRate, mode)
+	if err != nil {
+		return nil, err
+	}
+
+	// checks options before first key is added
+	if err := bloomfilter.CheckOptions(opts...); err != nil {
+		return nil, err
+	}
+
+	sbf.opts = opts
+	return sbf, nil
+}
+
 // Setup for main parameters. We may change after filter creation.
 func (sbf *Filter) Setup(mode int, ratio float64, initialCapacity int64, errorRate float64) error {
 
@@ -113,11 +141,15 @@ func (sbf *Filter) getEmptyFilter() (*bloomfilter.BloomFilter, error) {
 
 	if len(sbf.filters) == 0 {
 
+		if sbf.mapping != nil {
+			return nil, mappedError
+		}
+
 		sbf.mc.Lock()
 		defer sbf.mc.Unlock()
 
 		if len(sbf.filters) == 0 {
-			filter, err := bloomfilter.New(sbf.initialCapacity, sbf.errorRate*(1.0-sbf.ratio))
+			filter, err := sbf.newFilter(sbf.initialCapacity, sbf.errorRate*(1.0-sbf.ratio))
 			if err != nil {
 				return nil, err
 			}
@@ -132,11 +164,15 @@ func (sbf *Filter) getEmptyFilter() (*bloomfilter.BloomFilter, error) {
 		return filter, nil
 	}
 
+	if sbf.mapping != nil {
+		return nil, mappedError
+	}
+
 	sbf.mc.Lock()
 	defer sbf.mc.Unlock()
 
 	if filter.Count() >= filter.Capacity() {
-		newFilter, err := bloomfilter.New(filter.Capacity()*int64(sbf.scale), filter.ErrorRate()*sbf.ratio)
+		newFilter, err := sbf.newFilter(filter.Capacity()*int64(sbf.scale), filter.ErrorRate()*sbf.ratio)
 		if err != nil {
 			return nil, err
 		}
@@ -146,26 +182,23 @@ func (sbf *Filter) getEmptyFilter() (*bloomfilter.BloomFilter, error) {
 	return sbf.filters[len(sbf.filters)-1], nil
 }
 
+func (sbf *Filter) newFilter(capacity int64, errorRate float64) (*bloomfilter.BloomFilter, error) {
+	// new inner filters start sparse, options may change it
+	opts := make([]bloomfilter.Option, 0, len(sbf.opts)+2)
+	opts = append(opts, bloomfilter.WithStorage(bloomfilter.StorageSparse))
+	opts = append(opts, sbf.opts...)
+	opts = append(opts, bloomfilter.WithErrorRate(errorRate))
+	return bloomfilter.NewWithOptions(capacity, opts...)
+}
+
 // Merge integrates 2 scalable bloom filters. Filters must have the same parameters
 func (sbf *Filter) Merge(sbfNew *Filter) error {
 	/*
 		You should believe that you have not added keys to target filter before merging.
 		This function goal is synchronize local read-only filter and remote one.
 	*/
-	if float32(sbf.ratio) != float32(sbfNew.ratio) {
-		return fmt.Errorf("Wrong length for scale: %f != %f", sbf.ratio, sbfNew.ratio)
-	}
-
-	if float32(sbf.errorRate) != float32(sbfNew.errorRate) {
-		return fmt.Errorf("Wrong length for errorRate: %f != %f", sbf.errorRate, sbfNew.errorRate)
-	}
-
-	if sbf.initialCapacity != sbfNew.initialCapacity {
-		return fmt.Errorf("Wrong length for initialCapacity: %d != %d", sbf.initialCapacity, sbfNew.initialCapacity)
-	}
-
-	if sbf.scale != sbfNew.scale {
-		return fmt.Errorf("Wrong length for scale: %d != %d", sbf.scale, sbfNew.scale)
+	if err := sbf.compare(sbfNew); err != nil {
+		return err
 	}
 
 	for i := 0; i < len(sbf.filters) && i < len(sbfNew.filters); i++ {
@@ -189,6 +222,27 @@ func (sbf *Filter) Merge(sbfNew *Filter) error {
 	return nil
 }
 
+func (sbf *Filter) compare(sbfNew *Filter) error {
+
+	if float32(sbf.ratio) != float32(sbfNew.ratio) {
+		return fmt.Errorf("Wrong length for scale: %f != %f", sbf.ratio, sbfNew.ratio)
+	}
+
+	if float32(sbf.errorRate) != float32(sbfNew.errorRate) {
+		return fmt.Errorf("Wrong length for errorRate: %f != %f", sbf.errorRate, sbfNew.errorRate)
+	}
+
+	if sbf.initialCapacity != sbfNew.initialCapacity {
+		return fmt.Errorf("Wrong length for initialCapacity: %d != %d", sbf.initialCapacity, sbfNew.initialCapacity)
+	}
+
+	if sbf.scale != sbfNew.scale {
+		return fmt.Errorf("Wrong length for scale: %d != %d", sbf.scale, sbfNew.scale)
+	}
+
+	return nil
+}
+
 // Capacity is a "getter". Returns full Capacity
 func (sbf *Filter) Capacity() int64 {
 	// Returns the total capacity for all filters in this SBF
@@ -209,6 +263,50 @@ func (sbf *Filter) Count() int64 {
 	return res
 }
 
+// EstimateCardinality returns number of keys which is estimated by set bits of all filters.
+// See bloomfilter.BloomFilter.EstimateCardinality.
+func (sbf *Filter) EstimateCardinality() float64 {
+	sbf.mc.RLock()
+	defer sbf.mc.RUnlock()
+
+	res := 0.0
+	for _, f := range sbf.filters {
+		res += f.EstimateCardinality()
+	}
+	return res
+}
+
+// FillRatio returns part of set bits in all filters.
+func (sbf *Filter) FillRatio() float64 {
+	sbf.mc.RLock()
+	defer sbf.mc.RUnlock()
+
+	setBits, numBits := 0.0, 0.0
+	for _, f := range sbf.filters {
+		bits := float64(f.NumSlices()) * float64(f.BitsPerSlice())
+		setBits += f.FillRatio() * bits
+		numBits += bits
+	}
+
+	if numBits == 0 {
+		return 0
+	}
+	return setBits / numBits
+}
+
+// EstimatedFPR returns false positive rate which is calculated by real set bits.
+// Unknown key is found if any filter finds it, so rate is 1 - П(1 - rate of filter).
+func (sbf *Filter) EstimatedFPR() float64 {
+	sbf.mc.RLock()
+	defer sbf.mc.RUnlock()
+
+	res := 1.0
+	for _, f := range sbf.filters {
+		res *= 1 - f.EstimatedFPR()
+	}
+	return 1 - res
+}
+
 // ToFile saves scalable bloom filter to file by file name.
 func (sbf *Filter) ToFile(fileName string) error {
 	file, err := os.Create(fileName)
@@ -217,61 +315,38 @@ func (sbf *Filter) ToFile(fileName string) error {
 		return err
 	}
 
-	_, err = file.Write(sbf.ToBytes())
-	return err
+	writer := bufio.NewWriter(file)
+	if bloomfilter.CompressionOf(sbf.opts...) != bloomfilter.CompressionNone {
+		err = sbf.writeCompressed(writer)
+	} else {
+		_, err = sbf.WriteTo(writer)
+	}
+	if err != nil {
+		return err
+	}
+
+	return writer.Flush()
 }
 
-// ToBytes returns binary image of scalable bloom filter
+// ToBytes returns binary image of scalable bloom filter.
+// Filter with bloomfilter.WithFileFormat(bloomfilter.FormatV2) option is saved in format v2.
 func (sbf *Filter) ToBytes() []byte {
-
-	sbf.mc.RLock()
-	defer sbf.mc.RUnlock()
-
 	binBuf := bytes.NewBuffer([]byte{})
+	sbf.WriteTo(binBuf)
+	return binBuf.Bytes()
+}
+
+// writeHeader writes python-bloomfilter header and sizes of inner filters.
+func (sbf *Filter) writeHeader(binBuf *bytes.Buffer, filterSizes []uint64) {
 	binary.Write(binBuf, binary.LittleEndian, uint32(sbf.scale))
 	binary.Write(binBuf, binary.LittleEndian, sbf.ratio)
 	binary.Write(binBuf, binary.LittleEndian, int64(sbf.initialCapacity))
 	binary.Write(binBuf, binary.LittleEndian, float64(sbf.errorRate))
-	binary.Write(binBuf, binary.LittleEndian, int32(len(sbf.filters)))
-
-	if len(sbf.filters) == 0 {
-		return binBuf.Bytes()
-	}
+	binary.Write(binBuf, binary.LittleEndian, int32(len(filterSizes)))
 
 	// Then each filter directly, with a header describing
 	// their lengths.
-	headerPos := binBuf.Len()
-
-	filterSizes := make([]uint64, len(sbf.filters), len(sbf.filters))
-	for _, i := range filterSizes {
-		binary.Write(binBuf, binary.LittleEndian, i)
-	}
-
-	var last, begin int
-	for i, filter := range sbf.filters {
-		begin = binBuf.Len()
-		filter.ToBytes(binBuf)
-		last = binBuf.Len()
-		filterSizes[i] = uint64(last - begin)
-	}
-
-	return saveInt64List(headerPos, binBuf, filterSizes)
-}
-
-func saveInt64List(pos int, binBuf *bytes.Buffer, mylist []uint64) []byte {
-	var tmpBinBuf bytes.Buffer
-	for _, i := range mylist {
-		binary.Write(&tmpBinBuf, binary.LittleEndian, i)
-	}
-
-	// copy to out list
-	buf := tmpBinBuf.Bytes()
-	out := binBuf.Bytes()
-	for i := range buf {
-		out[i+pos] = buf[i]
-	}
-
-	return out
+	binary.Write(binBuf, binary.LittleEndian, filterSizes)
 }
 
 // FromFile creates new scalable bloom filter from file
@@ -288,9 +363,21 @@ func FromFile(fileName string) (*Filter, error) {
 	return FromReader(reader)
 }
 
-// FromReader creates new scalable bloom filter from bufio.Reader
+// FromReader creates new scalable bloom filter from bufio.Reader.
+// It reads python-bloomfilter format, format v2 and compressed files. Checksums of format v2 are checked.
+// Python file with small error rates gives false negatives, such file must be read by FromPybloom.
 func FromReader(reader *bufio.Reader) (*Filter, error) {
 
+	if isCompressed(reader) {
+		return readCompressed(reader)
+	}
+
+	return readFilter(reader)
+}
+
+// readHeader reads header and sizes of inner filters and creates filter without inner filters.
+func readHeader(reader io.Reader) (*Filter, []uint64, error) {
+
 	var header struct {
 		Scale           int32
 		Ratio           float64
@@ -299,55 +386,65 @@ func FromReader(reader *bufio.Reader) (*Filter, error) {
 		CountFilters    int32
 	}
 
-	// const headerLen = int64(unsafe.Sizeof(header))
-	headerLen := 32
+	// struct has padding, so its size is not used
+	headerLen := PybloomHeaderLen
 	b := make([]byte, headerLen, headerLen)
-	_, err := reader.Read(b)
-	if err != nil {
-		return nil, err
+	if _, err := io.ReadFull(reader, b); err != nil {
+		return nil, nil, err
 	}
 
 	r := bytes.NewReader(b)
 	if err := binary.Read(r, binary.LittleEndian, &header); err != nil {
-		return nil, err
+		return nil, nil, err
+	}
+
+	if header.CountFilters < 0 || header.CountFilters > 64 {
+		return nil, nil, fmt.Errorf("wrong number of filters: %d", header.CountFilters)
 	}
 
 	sbf, err := New(int(header.InitialCapacity), float64(header.ErrorRate), int(header.Scale))
 	if err != nil {
-		return nil, err
+		return nil, nil, err
+	}
+
+	// saved ratio is used for new inner filters
+	if err := sbf.Setup(int(header.Scale), header.Ratio, header.InitialCapacity, header.ErrorRate); err != nil {
+		return nil, nil, err
 	}
 
 	sbf.filters = make([]*bloomfilter.BloomFilter, header.CountFilters, header.CountFilters)
 
 	if header.CountFilters == 0 {
-		return sbf, nil
+		return sbf, nil, nil
 	}
 
-	filterSizes := []uint64{}
-	filterSizes, err = readArrayOfUint64(reader, int(header.CountFilters))
+	filterSizes, err := readArrayOfUint64(reader, int(header.CountFilters))
 	if err != nil {
-		return nil, err
+		return nil, nil, err
 	}
 
-	for i, length := range filterSizes {
-
-		sbf.filters[i], err = bloomfilter.FromReader(reader, int64(length))
-
-		if err != nil {
-			return nil, err
+	for i, size := range filterSizes {
+		if size < 1 || size > math.MaxInt64 {
+			return nil, nil, fmt.Errorf("wrong size of filter %d: %d", i, size)
 		}
 	}
 
-	return sbf, nil
+	return sbf, filterSizes, nil
 }
 
-func readArrayOfUint64(reader *bufio.Reader, count int) ([]uint64, error) {
+// setOptions makes new inner filters with the same hashing as saved ones
+func (sbf *Filter) setOptions() {
+	if len(sbf.filters) > 0 {
+		sbf.opts = sbf.filters[len(sbf.filters)-1].Options()
+	}
+}
+
+func readArrayOfUint64(reader io.Reader, count int) ([]uint64, error) {
 
 	out := make([]uint64, count, count)
 	for i := 0; i < count; i++ {
 		array := []byte{0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00}
-		_, err := reader.Read(array)
-		if err != nil {
+		if _, err := io.ReadFull(reader, array); err != nil {
 			return nil, err
 		}
 
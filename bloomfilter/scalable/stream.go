@@ -0,0 +1,157 @@
+package scalable
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+
+	"github.com/iostrovok/go-bloom-filter/bloomfilter"
+)
+
+// WriteTo writes binary image of ToBytes to w. Sizes of inner filters are known before
+// they are written, so inner filters are streamed one by one without copy in memory.
+// Returns number of written bytes.
+func (sbf *Filter) WriteTo(w io.Writer) (int64, error) {
+
+	sbf.mc.RLock()
+	defer sbf.mc.RUnlock()
+
+	filterSizes := make([]uint64, len(sbf.filters), len(sbf.filters))
+	for i, filter := range sbf.filters {
+		filterSizes[i] = uint64(filter.BinarySize())
+	}
+
+	header := bytes.NewBuffer([]byte{})
+	if bloomfilter.FileFormatOf(sbf.opts...) == bloomfilter.FormatV2 {
+		sbf.writeV2Header(header, filterSizes)
+	} else {
+		sbf.writeHeader(header, filterSizes)
+	}
+
+	cw := &countingWriter{w: w}
+	if _, err := cw.Write(header.Bytes()); err != nil {
+		return cw.n, err
+	}
+
+	for _, filter := range sbf.filters {
+		if _, err := filter.WriteTo(cw); err != nil {
+			return cw.n, err
+		}
+	}
+
+	return cw.n, nil
+}
+
+// ReadFrom replaces filter by scalable filter which is read from r in any format of FromReader, compressed files too.
+// Only bytes of filter are read from r, so other data may follow it. Returns number of read bytes.
+// Filter which is opened by FromFileMapped must be closed before.
+func (sbf *Filter) ReadFrom(r io.Reader) (int64, error) {
+
+	cr := &countingReader{r: r}
+	out, err := readFilter(cr)
+	if err != nil {
+		return cr.n, err
+	}
+
+	sbf.replace(out)
+	return cr.n, nil
+}
+
+// replace sets all fields of filter from out. Filter has mutex, so it can not be copied.
+func (sbf *Filter) replace(out *Filter) {
+
+	sbf.mc.Lock()
+	defer sbf.mc.Unlock()
+
+	sbf.filters = out.filters
+	sbf.scale = out.scale
+	sbf.ratio = out.ratio
+	sbf.initialCapacity = out.initialCapacity
+	sbf.errorRate = out.errorRate
+	sbf.opts = out.opts
+	sbf.mapping = nil
+}
+
+// readFilter reads scalable filter in any format. Bytes after filter are not read.
+func readFilter(reader io.Reader) (*Filter, error) {
+
+	magic := make([]byte, len(v2Magic), len(v2Magic))
+	if _, err := io.ReadFull(reader, magic); err != nil {
+		return nil, err
+	}
+	reader = io.MultiReader(bytes.NewReader(magic), reader)
+
+	if bytes.Equal(magic, compressedMagic) {
+		return readCompressed(&byteReader{r: reader})
+	}
+
+	var sbf *Filter
+	var filterSizes []uint64
+	var err error
+
+	if bytes.Equal(magic, v2Magic) {
+		sbf, filterSizes, err = readV2Header(reader)
+	} else {
+		sbf, filterSizes, err = readHeader(reader)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	for i, size := range filterSizes {
+		filter := &bloomfilter.BloomFilter{}
+		n, err := filter.ReadFrom(io.LimitReader(reader, int64(size)))
+		if err != nil {
+			return nil, err
+		}
+
+		if n != int64(size) {
+			return nil, fmt.Errorf("wrong size of filter %d: %d", i, size)
+		}
+
+		sbf.filters[i] = filter
+	}
+
+	sbf.setOptions()
+
+	return sbf, nil
+}
+
+// countingWriter counts written bytes
+type countingWriter struct {
+	w io.Writer
+	n int64
+}
+
+func (cw *countingWriter) Write(p []byte) (int, error) {
+	n, err := cw.w.Write(p)
+	cw.n += int64(n)
+	return n, err
+}
+
+// countingReader counts read bytes
+type countingReader struct {
+	r io.Reader
+	n int64
+}
+
+func (cr *countingReader) Read(p []byte) (int, error) {
+	n, err := cr.r.Read(p)
+	cr.n += int64(n)
+	return n, err
+}
+
+// byteReader reads by one byte, so gzip does not read data after compressed filter.
+type byteReader struct {
+	r io.Reader
+	b [1]byte
+}
+
+func (br *byteReader) Read(p []byte) (int, error) {
+	return br.r.Read(p)
+}
+
+func (br *byteReader) ReadByte() (byte, error) {
+	_, err := io.ReadFull(br.r, br.b[:])
+	return br.b[0], err
+}
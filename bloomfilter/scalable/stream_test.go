@@ -0,0 +1,103 @@
+package scalable
+
+import (
+	"bytes"
+	"encoding/binary"
+	"io"
+
+	"github.com/iostrovok/go-bloom-filter/bloomfilter"
+	"github.com/iostrovok/go-bloom-filter/bloomfilter/fortesting"
+	. "gopkg.in/check.v1"
+)
+
+func (s *scalTestSuite) TestWriteToReadFrom(c *C) {
+
+	testArray := fortesting.ArrayForTesting()
+
+	for _, opts := range [][]bloomfilter.Option{
+		{},
+		{bloomfilter.WithFileFormat(bloomfilter.FormatV2)},
+		{bloomfilter.WithHashStrategy(bloomfilter.HashMurmur3), bloomfilter.WithConcurrency(bloomfilter.ConcurrencyAtomic)},
+	} {
+		filter, err := NewWithOptions(100, 0.001, SmallSetGrowth, opts...)
+		c.Assert(err, IsNil)
+
+		for _, s := range testArray {
+			_, err := filter.Add([]byte(s))
+			c.Assert(err, IsNil)
+		}
+		c.Assert(len(filter.filters) > 1, Equals, true)
+
+		data := filter.ToBytes()
+
+		written := bytes.NewBuffer([]byte{})
+		n, err := filter.WriteTo(written)
+		c.Assert(err, IsNil)
+		c.Assert(n, Equals, int64(len(data)))
+		c.Assert(written.Bytes(), DeepEquals, data)
+
+		// filter and tail in one stream
+		reader, writer := io.Pipe()
+		go func() {
+			if _, err := filter.WriteTo(writer); err != nil {
+				writer.CloseWithError(err)
+				return
+			}
+			writer.Write([]byte("tail"))
+			writer.Close()
+		}()
+
+		loaded := &Filter{}
+		n, err = loaded.ReadFrom(reader)
+		c.Assert(err, IsNil)
+		c.Assert(n, Equals, int64(len(data)))
+		c.Assert(Equal(filter, loaded), Equals, true)
+		c.Assert(loaded.Count(), Equals, filter.Count())
+		c.Assert(loaded.ToBytes(), DeepEquals, data)
+
+		tail, err := io.ReadAll(reader)
+		c.Assert(err, IsNil)
+		c.Assert(string(tail), Equals, "tail")
+
+		// loaded filter grows with the same options
+		for _, s := range testArray {
+			_, err := loaded.Add([]byte("more " + s))
+			c.Assert(err, IsNil)
+		}
+
+		_, err = (&Filter{}).ReadFrom(bytes.NewReader(data[:len(data)-3]))
+		c.Assert(err, NotNil)
+	}
+}
+
+func (s *scalTestSuite) TestReadFromWrongHeader(c *C) {
+
+	filter, err := New(100, 0.001, SmallSetGrowth)
+	c.Assert(err, IsNil)
+	_, err = filter.Add([]byte("key"))
+	c.Assert(err, IsNil)
+	image := filter.ToBytes()
+
+	broken := func(offset int, value uint64, size int) []byte {
+		out := append([]byte{}, image...)
+		if size == 4 {
+			binary.LittleEndian.PutUint32(out[offset:], uint32(value))
+		} else {
+			binary.LittleEndian.PutUint64(out[offset:], value)
+		}
+		return out
+	}
+
+	// header is checked before filters are allocated
+	for _, data := range [][]byte{
+		broken(28, uint64(0xffffffff), 4),
+		broken(28, 1<<30, 4),
+		broken(28, 65, 4),
+		broken(PybloomHeaderLen, 0, 8),
+		broken(PybloomHeaderLen, 1<<63, 8),
+		broken(PybloomHeaderLen+8, 0, 8),
+	} {
+		_, err := (&Filter{}).ReadFrom(bytes.NewReader(data))
+		c.Assert(err, NotNil)
+	}
+}
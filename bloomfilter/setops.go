@@ -0,0 +1,146 @@
+package bloomfilter
+
+import (
+	"math"
+)
+
+// Union returns new filter which finds keys of both filters.
+// Filters must have the same parameters. Count of result is estimated by set bits.
+func Union(a, b *BloomFilter) (*BloomFilter, error) {
+
+	if err := a.compare(b); err != nil {
+		return nil, err
+	}
+
+	out := a.clone()
+	if err := mergeBits(out.bitarray, b.bitarray); err != nil {
+		return nil, err
+	}
+	out.count = roundCount(out.EstimateCardinality())
+
+	return out, nil
+}
+
+// Intersect returns new filter with bits which are set in both filters.
+// It finds all common keys, false positive rate of result is not higher than rate of a or b.
+// Filters must have the same parameters. Count of result is estimated by EstimateIntersectionSize.
+func Intersect(a, b *BloomFilter) (*BloomFilter, error) {
+
+	size, err := EstimateIntersectionSize(a, b)
+	if err != nil {
+		return nil, err
+	}
+
+	out := a.clone()
+	andBits(out.bitarray, b.bitarray)
+	out.count = roundCount(size)
+
+	return out, nil
+}
+
+// Difference returns new filter with bits which are set in current filter and are not set in bfOld,
+// e.g. "new since yesterday" filter. Filters must have the same parameters.
+// Bits are removed by AndNot, so new key is lost in result if any of its bits is set in bfOld.
+// Part of lost keys is about 1 - (1 - bfOld.FillRatio())^k. Count of result is estimated as |A ∪ B| - |B|.
+func (bf *BloomFilter) Difference(bfOld *BloomFilter) (*BloomFilter, error) {
+
+	sizeUnion, err := estimateUnionSize(bf, bfOld)
+	if err != nil {
+		return nil, err
+	}
+
+	out := bf.clone()
+	andNotBits(out.bitarray, bfOld.bitarray)
+	out.count = roundCount(nonNegative(sizeUnion - bfOld.EstimateCardinality()))
+
+	return out, nil
+}
+
+// SymmetricDifferenceEstimate returns number of keys which are found in one of filters only.
+// It is estimated as 2|A ∪ B| - |A| - |B| and shows how far two replicas of the same filter have drifted.
+// Set bits of union are counted by Xor as (|a| + |b| + |a ^ b|) / 2 for every slice, no copy of bits is made.
+func (bf *BloomFilter) SymmetricDifferenceEstimate(other *BloomFilter) (float64, error) {
+
+	if err := bf.compare(other); err != nil {
+		return 0, err
+	}
+
+	popA := bf.slicesPopCount()
+	popB := other.slicesPopCount()
+	union := bf.slicesCount(xorPopCounter(bf.bitarray, other.bitarray))
+	for i := range union {
+		union[i] = (popA[i] + popB[i] + union[i]) / 2
+	}
+
+	sizeUnion := bf.estimateCardinality(union)
+	return nonNegative(2*sizeUnion - bf.estimateCardinality(popA) - other.estimateCardinality(popB)), nil
+}
+
+// EstimateIntersectionSize returns number of common keys which is estimated as |A| + |B| - |A ∪ B|.
+// Every cardinality is estimated by set bits, see EstimateCardinality.
+func EstimateIntersectionSize(a, b *BloomFilter) (float64, error) {
+
+	sizeUnion, err := estimateUnionSize(a, b)
+	if err != nil {
+		return 0, err
+	}
+
+	return intersectionSize(a.EstimateCardinality(), b.EstimateCardinality(), sizeUnion), nil
+}
+
+// EstimateJaccard returns Jaccard index |A ∩ B| / |A ∪ B| which is estimated by set bits.
+// Result is 0 for empty filters.
+func EstimateJaccard(a, b *BloomFilter) (float64, error) {
+
+	sizeUnion, err := estimateUnionSize(a, b)
+	if err != nil {
+		return 0, err
+	}
+
+	if sizeUnion == 0 || math.IsInf(sizeUnion, 1) {
+		return 0, nil
+	}
+
+	return intersectionSize(a.EstimateCardinality(), b.EstimateCardinality(), sizeUnion) / sizeUnion, nil
+}
+
+// estimateUnionSize returns |A ∪ B| which is estimated by set bits of a | b without copy of bits.
+func estimateUnionSize(a, b *BloomFilter) (float64, error) {
+
+	if err := a.compare(b); err != nil {
+		return 0, err
+	}
+
+	return a.estimateCardinality(a.unionSlicesPopCount(b)), nil
+}
+
+// intersectionSize is |A| + |B| - |A ∪ B|.
+func intersectionSize(sizeA, sizeB, sizeUnion float64) float64 {
+	return nonNegative(sizeA + sizeB - sizeUnion)
+}
+
+// nonNegative returns 0 for estimations which are a bit less than 0 or are undefined.
+func nonNegative(estimate float64) float64 {
+	if estimate < 0 || math.IsNaN(estimate) {
+		return 0
+	}
+	return estimate
+}
+
+// clone returns filter with the same parameters and copy of bits.
+func (bf *BloomFilter) clone() *BloomFilter {
+	out := *bf
+	out.bitarray = cloneBits(bf.bitarray)
+	// copy is kept in heap
+	out.mapping = nil
+	out.ownMapping = false
+	out.checksumOffset = 0
+	return &out
+}
+
+func roundCount(estimate float64) int64 {
+	if math.IsInf(estimate, 1) {
+		return math.MaxInt64
+	}
+	return int64(math.Round(estimate))
+}
@@ -0,0 +1,184 @@
+package bloomfilter
+
+import (
+	"math"
+
+	"github.com/iostrovok/go-bloom-filter/bloomfilter/fortesting"
+	. "gopkg.in/check.v1"
+)
+
+func (s *filterTestSuite) TestUnionIntersect(c *C) {
+
+	testArray := fortesting.ArrayForTesting()
+	third := len(testArray) / 3
+
+	// a has [0, 2/3), b has [1/3, 1)
+	filterA, err := New(int64(len(testArray)), 0.001)
+	c.Assert(err, IsNil)
+	filterB, err := New(int64(len(testArray)), 0.001)
+	c.Assert(err, IsNil)
+
+	for _, s := range testArray[:2*third] {
+		_, err := filterA.Add([]byte(s))
+		c.Assert(err, IsNil)
+	}
+	for _, s := range testArray[third:] {
+		_, err := filterB.Add([]byte(s))
+		c.Assert(err, IsNil)
+	}
+
+	countA := filterA.Count()
+	popA := counterOf(filterA.bitarray).PopCount()
+
+	union, err := Union(filterA, filterB)
+	c.Assert(err, IsNil)
+	c.Assert(filterA.Count(), Equals, countA)
+	c.Assert(counterOf(filterA.bitarray).PopCount(), Equals, popA)
+	for _, s := range testArray {
+		c.Assert(union.Check([]byte(s)), Equals, true)
+	}
+	total := float64(len(testArray))
+	c.Assert(math.Abs(float64(union.Count())-total) < 0.05*total, Equals, true, Commentf("%d", union.Count()))
+
+	intersect, err := Intersect(filterA, filterB)
+	c.Assert(err, IsNil)
+	c.Assert(counterOf(filterA.bitarray).PopCount(), Equals, popA)
+	for _, s := range testArray[third : 2*third] {
+		c.Assert(intersect.Check([]byte(s)), Equals, true)
+	}
+
+	countBad := 0
+	for _, s := range testArray[:third] {
+		if intersect.Check([]byte(s)) {
+			countBad++
+		}
+	}
+	c.Assert(countBad < 3, Equals, true)
+
+	size, err := EstimateIntersectionSize(filterA, filterB)
+	c.Assert(err, IsNil)
+	c.Assert(math.Abs(size-float64(third)) < 0.1*float64(third), Equals, true, Commentf("%f", size))
+	c.Assert(intersect.Count(), Equals, int64(math.Round(size)))
+
+	jaccard, err := EstimateJaccard(filterA, filterB)
+	c.Assert(err, IsNil)
+	c.Assert(math.Abs(jaccard-float64(third)/total) < 0.05, Equals, true, Commentf("%f", jaccard))
+
+	jaccard, err = EstimateJaccard(filterA, filterA)
+	c.Assert(err, IsNil)
+	c.Assert(jaccard, Equals, float64(1))
+
+	filterC, err := New(int64(len(testArray)), 0.01)
+	c.Assert(err, IsNil)
+
+	_, err = Union(filterA, filterC)
+	c.Assert(err, NotNil)
+	_, err = Intersect(filterA, filterC)
+	c.Assert(err, NotNil)
+	_, err = EstimateIntersectionSize(filterA, filterC)
+	c.Assert(err, NotNil)
+	_, err = EstimateJaccard(filterA, filterC)
+	c.Assert(err, NotNil)
+
+	empty, err := New(int64(len(testArray)), 0.001)
+	c.Assert(err, IsNil)
+	jaccard, err = EstimateJaccard(empty, empty)
+	c.Assert(err, IsNil)
+	c.Assert(jaccard, Equals, float64(0))
+}
+
+func (s *filterTestSuite) TestEstimateUnionSize(c *C) {
+
+	testArray := fortesting.ArrayForTesting()
+	half := len(testArray) / 2
+
+	// union estimate does not depend on storage of bits
+	for _, opts := range [][2][]Option{
+		{{}, {}},
+		{{WithStorage(StorageSparse)}, {}},
+		{{WithStorage(StorageSparse)}, {WithStorage(StorageSparse)}},
+		{{WithConcurrency(ConcurrencyAtomic)}, {WithConcurrency(ConcurrencyAtomic)}},
+		{{WithConcurrency(ConcurrencyAtomic)}, {}},
+	} {
+		filterA, err := NewWithOptions(int64(len(testArray)), opts[0]...)
+		c.Assert(err, IsNil)
+		filterB, err := NewWithOptions(int64(len(testArray)), opts[1]...)
+		c.Assert(err, IsNil)
+
+		for _, s := range testArray[:half] {
+			filterA.Add([]byte(s))
+		}
+		for _, s := range testArray[half/2:] {
+			filterB.Add([]byte(s))
+		}
+
+		union, err := Union(filterA, filterB)
+		c.Assert(err, IsNil)
+
+		size, err := estimateUnionSize(filterA, filterB)
+		c.Assert(err, IsNil)
+		c.Assert(size, Equals, union.EstimateCardinality(), Commentf("%v", opts))
+	}
+}
+
+func (s *filterTestSuite) TestDifference(c *C) {
+
+	testArray := fortesting.ArrayForTesting()
+	third := len(testArray) / 3
+
+	// yesterday has [0, 2/3), today has [1/3, 1)
+	yesterday, err := New(int64(len(testArray)), 0.001)
+	c.Assert(err, IsNil)
+	today, err := NewWithOptions(int64(len(testArray)), WithErrorRate(0.001), WithConcurrency(ConcurrencyAtomic))
+	c.Assert(err, IsNil)
+
+	for _, s := range testArray[:2*third] {
+		_, err := yesterday.Add([]byte(s))
+		c.Assert(err, IsNil)
+	}
+	for _, s := range testArray[third:] {
+		_, err := today.Add([]byte(s))
+		c.Assert(err, IsNil)
+	}
+
+	fresh, err := today.Difference(yesterday)
+	c.Assert(err, IsNil)
+
+	newKeys := len(testArray) - 2*third
+	lost := 0
+	for _, s := range testArray[2*third:] {
+		if !fresh.Check([]byte(s)) {
+			lost++
+		}
+	}
+	// key is lost if any of its bits is set in old filter
+	expectedLost := 1 - math.Pow(1-yesterday.FillRatio(), float64(yesterday.numSlices))
+	c.Assert(math.Abs(float64(lost)/float64(newKeys)-expectedLost) < 0.05, Equals, true, Commentf("%d", lost))
+
+	for _, s := range testArray[:2*third] {
+		c.Assert(fresh.Check([]byte(s)), Equals, false)
+	}
+	c.Assert(math.Abs(float64(fresh.Count()-int64(newKeys))) < 0.1*float64(newKeys), Equals, true, Commentf("%d", fresh.Count()))
+	c.Assert(today.Count(), Equals, int64(len(testArray)-third))
+
+	drift, err := today.SymmetricDifferenceEstimate(yesterday)
+	c.Assert(err, IsNil)
+	expected := float64(len(testArray) - third)
+	c.Assert(math.Abs(drift-expected) < 0.1*expected, Equals, true, Commentf("%f", drift))
+
+	// Xor count gives the same union as Union
+	union, err := Union(today, yesterday)
+	c.Assert(err, IsNil)
+	c.Assert(drift, Equals, 2*union.EstimateCardinality()-today.EstimateCardinality()-yesterday.EstimateCardinality())
+
+	drift, err = today.SymmetricDifferenceEstimate(today)
+	c.Assert(err, IsNil)
+	c.Assert(drift, Equals, float64(0))
+
+	other, err := New(int64(len(testArray)), 0.01)
+	c.Assert(err, IsNil)
+	_, err = today.Difference(other)
+	c.Assert(err, NotNil)
+	_, err = today.SymmetricDifferenceEstimate(other)
+	c.Assert(err, NotNil)
+}
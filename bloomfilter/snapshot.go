@@ -0,0 +1,47 @@
+package bloomfilter
+
+import (
+	"sync/atomic"
+
+	"github.com/iostrovok/go-bloom-filter/bloomfilter/array"
+)
+
+// Snapshot returns read-only filter with keys which are added before this moment.
+// Bits are not copied at once: every page of bits is copied once when current filter changes it.
+// So snapshot may be saved by ToBytes or checked while keys are added without stall of Add.
+// Close must be called when snapshot is not needed, otherwise changed pages are copied into it.
+func (bf *BloomFilter) Snapshot() *BloomFilter {
+
+	out := &BloomFilter{
+		errorRate:     bf.errorRate,
+		numSlices:     bf.numSlices,
+		bitsPerSlice:  bf.bitsPerSlice,
+		capacity:      bf.capacity,
+		numBits:       bf.numBits,
+		count:         atomic.LoadInt64(&bf.count),
+		chunkSize:     bf.chunkSize,
+		hasher:        bf.hasher,
+		saltFunctions: bf.saltFunctions,
+		opts:          bf.opts,
+	}
+
+	switch a := bf.bitarray.(type) {
+	case *array.Array:
+		out.bitarray = a.Snapshot()
+	case *array.Atomic:
+		out.bitarray = a.Snapshot()
+	default:
+		// snapshots and other stores are copied
+		copied := array.New(bf.numBits)
+		copied.MergeBytes(arrayBytes(bf.bitarray))
+		out.bitarray = copied.Snapshot()
+	}
+
+	return out
+}
+
+// isSnapshot returns true if filter is created by Snapshot.
+func (bf *BloomFilter) isSnapshot() bool {
+	_, ok := bf.bitarray.(*array.Snapshot)
+	return ok
+}
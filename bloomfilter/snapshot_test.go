@@ -0,0 +1,71 @@
+package bloomfilter
+
+import (
+	"bytes"
+	"sync"
+
+	"github.com/iostrovok/go-bloom-filter/bloomfilter/fortesting"
+	. "gopkg.in/check.v1"
+)
+
+func (s *filterTestSuite) TestSnapshot(c *C) {
+
+	testArray := fortesting.ArrayForTesting()
+	half := len(testArray) / 2
+
+	for _, mode := range []ConcurrencyMode{ConcurrencyLocked, ConcurrencyStriped, ConcurrencyAtomic} {
+
+		filter, err := NewWithOptions(int64(len(testArray)), WithConcurrency(mode))
+		c.Assert(err, IsNil)
+
+		for _, s := range testArray[:half] {
+			_, err := filter.Add([]byte(s))
+			c.Assert(err, IsNil)
+		}
+
+		expected := bytes.NewBuffer([]byte{})
+		c.Assert(filter.ToBytes(expected), IsNil)
+
+		snapshot := filter.Snapshot()
+		c.Assert(snapshot.Count(), Equals, filter.Count())
+
+		// keys are added while snapshot is saved
+		var wg sync.WaitGroup
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for _, s := range testArray[half:] {
+				filter.Add([]byte(s))
+			}
+		}()
+
+		for i := 0; i < 3; i++ {
+			binBuf := bytes.NewBuffer([]byte{})
+			c.Check(snapshot.ToBytes(binBuf), IsNil)
+			c.Check(binBuf.Bytes(), DeepEquals, expected.Bytes())
+		}
+		wg.Wait()
+
+		for _, s := range testArray[:half] {
+			c.Assert(snapshot.Check([]byte(s)), Equals, true)
+		}
+		for _, s := range testArray {
+			c.Assert(filter.Check([]byte(s)), Equals, true)
+		}
+		c.Assert(snapshot.Count(), Equals, int64(half))
+
+		_, err = snapshot.Add([]byte("new key"))
+		c.Assert(err, NotNil)
+		c.Assert(snapshot.Merge(filter), NotNil)
+		c.Assert(snapshot.Clear(), NotNil)
+		c.Assert(snapshot.SetLocations([]uint64{0}, 1), Equals, readOnlyError)
+
+		// copies are writable
+		union, err := Union(snapshot, filter)
+		c.Assert(err, IsNil)
+		c.Assert(union.Merge(snapshot), IsNil)
+
+		c.Assert(snapshot.Snapshot().Check([]byte(testArray[0])), Equals, true)
+		c.Assert(snapshot.Close(), IsNil)
+	}
+}
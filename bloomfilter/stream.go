@@ -0,0 +1,181 @@
+package bloomfilter
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/binary"
+	"hash/crc32"
+	"io"
+)
+
+// WriteTo writes binary image of ToBytes to w. Bits are written directly from bit array,
+// so there is no second copy of filter in memory. Returns number of written bytes.
+func (bf *BloomFilter) WriteTo(w io.Writer) (int64, error) {
+
+	cw := &countingWriter{w: w}
+	header := bytes.NewBuffer([]byte{})
+
+	if bf.opts.format == FormatV2 {
+		writeV2Header(header, bf)
+
+		crc := crc32.New(castagnoli)
+		out := io.MultiWriter(cw, crc)
+		if _, err := out.Write(header.Bytes()); err != nil {
+			return cw.n, err
+		}
+		if err := writeBits(out, bf.bitarray); err != nil {
+			return cw.n, err
+		}
+
+		err := binary.Write(cw, binary.LittleEndian, crc.Sum32())
+		return cw.n, err
+	}
+
+	writeHeader(header, bf)
+	if _, err := cw.Write(header.Bytes()); err != nil {
+		return cw.n, err
+	}
+
+	err := writeBits(cw, bf.bitarray)
+	return cw.n, err
+}
+
+// BinarySize is a "getter". Returns number of bytes which WriteTo and ToBytes write.
+func (bf *BloomFilter) BinarySize() int64 {
+
+	header := bytes.NewBuffer([]byte{})
+	size := int64((bf.numBits + 7) / 8)
+
+	if bf.opts.format == FormatV2 {
+		writeV2Header(header, bf)
+		size += 4
+	} else {
+		writeHeader(header, bf)
+	}
+
+	return int64(header.Len()) + size
+}
+
+// ReadFrom replaces filter by filter which is read from r in any format of FromReader, compressed files too.
+// Only bytes of filter are read from r, so other data may follow it. Returns number of read bytes.
+// Filter which is opened by FromFileMapped must be closed before.
+func (bf *BloomFilter) ReadFrom(r io.Reader) (int64, error) {
+
+	cr := &countingReader{r: r}
+
+	magic := make([]byte, len(v2Magic), len(v2Magic))
+	if _, err := io.ReadFull(cr, magic); err != nil {
+		return cr.n, err
+	}
+	reader := io.MultiReader(bytes.NewReader(magic), cr)
+
+	var out *BloomFilter
+	var err error
+	if bytes.Equal(magic, v2Magic) {
+		out, err = readV2(reader)
+	} else if bytes.Equal(magic, compressedMagic) {
+		out, err = readCompressed(&byteReader{r: reader})
+	} else {
+		out, err = readLegacy(reader)
+	}
+	if err != nil {
+		return cr.n, err
+	}
+
+	*bf = *out
+	return cr.n, nil
+}
+
+// readLegacy reads filter in python-bloomfilter format or extended one. Bytes after filter are not read.
+func readLegacy(reader io.Reader) (*BloomFilter, error) {
+
+	bf, _, err := readHeader(reader)
+	if err != nil {
+		return nil, err
+	}
+
+	if bf.bitarray, err = newBitArray(bf.opts, bf.numBits); err != nil {
+		return nil, err
+	}
+
+	if err := readBits(bf, reader); err != nil {
+		return nil, err
+	}
+
+	return bf, nil
+}
+
+// readBits reads exactly bytes of bit array from reader.
+func readBits(bf *BloomFilter, reader io.Reader) error {
+
+	size := int64((bf.numBits + 7) / 8)
+	limited := &io.LimitedReader{R: reader, N: size}
+	buffered := bufio.NewReader(limited)
+
+	if err := bf.bitarray.Read(buffered, size); err != nil {
+		return err
+	}
+
+	if limited.N > 0 || buffered.Buffered() > 0 {
+		return io.ErrUnexpectedEOF
+	}
+
+	return nil
+}
+
+// writeBits writes bytes of bit array to w. Arrays which can not write themselves
+// are written via buffer.
+func writeBits(w io.Writer, bits BitStore) error {
+
+	if wt, ok := bits.(io.WriterTo); ok {
+		_, err := wt.WriteTo(w)
+		return err
+	}
+
+	binBuf := bytes.NewBuffer([]byte{})
+	if err := bits.ToBytes(binBuf); err != nil {
+		return err
+	}
+
+	_, err := w.Write(binBuf.Bytes())
+	return err
+}
+
+// countingWriter counts written bytes
+type countingWriter struct {
+	w io.Writer
+	n int64
+}
+
+func (cw *countingWriter) Write(p []byte) (int, error) {
+	n, err := cw.w.Write(p)
+	cw.n += int64(n)
+	return n, err
+}
+
+// countingReader counts read bytes
+type countingReader struct {
+	r io.Reader
+	n int64
+}
+
+func (cr *countingReader) Read(p []byte) (int, error) {
+	n, err := cr.r.Read(p)
+	cr.n += int64(n)
+	return n, err
+}
+
+// byteReader reads by one byte, so gzip does not read data after compressed filter.
+type byteReader struct {
+	r io.Reader
+	b [1]byte
+}
+
+func (br *byteReader) Read(p []byte) (int, error) {
+	return br.r.Read(p)
+}
+
+func (br *byteReader) ReadByte() (byte, error) {
+	_, err := io.ReadFull(br.r, br.b[:])
+	return br.b[0], err
+}
@@ -0,0 +1,129 @@
+package bloomfilter
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/binary"
+	"io"
+	"math"
+
+	"github.com/iostrovok/go-bloom-filter/bloomfilter/fortesting"
+	. "gopkg.in/check.v1"
+)
+
+func (s *filterTestSuite) TestWriteToReadFrom(c *C) {
+
+	testArray := fortesting.ArrayForTesting()
+
+	for _, opts := range [][]Option{
+		{},
+		{WithHashStrategy(HashXXHash64), WithIndexMode(IndexDoubleHashing), WithSeed(7)},
+		{WithConcurrency(ConcurrencyAtomic)},
+		{WithFileFormat(FormatV2)},
+		{WithConcurrency(ConcurrencyAtomic), WithFileFormat(FormatV2)},
+		{WithStore(newMapStore)},
+	} {
+		filter, err := NewWithOptions(int64(len(testArray)), opts...)
+		c.Assert(err, IsNil)
+
+		for _, s := range testArray {
+			_, err := filter.Add([]byte(s))
+			c.Assert(err, IsNil)
+		}
+
+		binBuf := bytes.NewBuffer([]byte{})
+		c.Assert(filter.ToBytes(binBuf), IsNil)
+		c.Assert(filter.BinarySize(), Equals, int64(binBuf.Len()))
+
+		// two filters and tail in one stream
+		reader, writer := io.Pipe()
+		go func() {
+			for i := 0; i < 2; i++ {
+				n, err := filter.WriteTo(writer)
+				if err != nil || n != int64(binBuf.Len()) {
+					writer.CloseWithError(io.ErrShortWrite)
+					return
+				}
+			}
+			writer.Write([]byte("tail"))
+			writer.Close()
+		}()
+
+		for i := 0; i < 2; i++ {
+			loaded := &BloomFilter{}
+			n, err := loaded.ReadFrom(reader)
+			c.Assert(err, IsNil)
+			c.Assert(n, Equals, int64(binBuf.Len()))
+			c.Assert(Equal(filter, loaded), Equals, true)
+			c.Assert(loaded.Count(), Equals, filter.Count())
+			c.Assert(loaded.FileFormat(), Equals, filter.FileFormat())
+		}
+
+		tail, err := io.ReadAll(reader)
+		c.Assert(err, IsNil)
+		c.Assert(string(tail), Equals, "tail")
+
+		// FromReader reads the same image
+		loaded, err := FromReader(bufio.NewReader(bytes.NewReader(binBuf.Bytes())), 0)
+		c.Assert(err, IsNil)
+		c.Assert(Equal(filter, loaded), Equals, true)
+
+		// truncated image
+		_, err = (&BloomFilter{}).ReadFrom(bytes.NewReader(binBuf.Bytes()[:binBuf.Len()-3]))
+		c.Assert(err, NotNil)
+	}
+}
+
+func (s *filterTestSuite) TestWriteToSnapshot(c *C) {
+
+	filter, err := New(10000, 0.001)
+	c.Assert(err, IsNil)
+	for _, s := range fortesting.ArrayForTesting() {
+		filter.Add([]byte(s))
+	}
+
+	binBuf := bytes.NewBuffer([]byte{})
+	c.Assert(filter.ToBytes(binBuf), IsNil)
+
+	snapshot := filter.Snapshot()
+	defer snapshot.Close()
+	filter.Add([]byte("added after snapshot"))
+
+	written := bytes.NewBuffer([]byte{})
+	n, err := snapshot.WriteTo(written)
+	c.Assert(err, IsNil)
+	c.Assert(n, Equals, int64(binBuf.Len()))
+	c.Assert(written.Bytes(), DeepEquals, binBuf.Bytes())
+}
+
+func (s *filterTestSuite) TestReadFromWrongHeader(c *C) {
+
+	filter, err := New(100, 0.001)
+	c.Assert(err, IsNil)
+	binBuf := bytes.NewBuffer([]byte{})
+	c.Assert(filter.ToBytes(binBuf), IsNil)
+	image := binBuf.Bytes()
+
+	broken := func(offset int, value uint64) []byte {
+		out := append([]byte{}, image...)
+		binary.LittleEndian.PutUint64(out[offset:], value)
+		return out
+	}
+
+	// header is checked before bits are allocated
+	for _, data := range [][]byte{
+		broken(0, 0),
+		broken(0, math.Float64bits(1)),
+		broken(0, math.Float64bits(-0.1)),
+		broken(8, 0),
+		broken(8, 1<<62),
+		broken(16, 0),
+		broken(16, 1<<62),
+		broken(24, 1<<63),
+	} {
+		_, err := (&BloomFilter{}).ReadFrom(bytes.NewReader(data))
+		c.Assert(err, NotNil)
+		_, err = FromReader(bufio.NewReader(bytes.NewReader(data)), 0)
+		c.Assert(err, NotNil)
+	}
+}
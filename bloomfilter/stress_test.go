@@ -0,0 +1,63 @@
+package bloomfilter
+
+import (
+	"bytes"
+	"sync"
+
+	"github.com/iostrovok/go-bloom-filter/bloomfilter/fortesting"
+	. "gopkg.in/check.v1"
+)
+
+// TestConcurrentStress runs Add, Check, Merge and ToBytes together. It is useful with -race.
+func (s *filterTestSuite) TestConcurrentStress(c *C) {
+
+	testArray := fortesting.ArrayForTesting()
+
+	for _, mode := range []ConcurrencyMode{ConcurrencyLocked, ConcurrencyStriped, ConcurrencyAtomic} {
+
+		filter, err := NewWithOptions(int64(len(testArray)), WithConcurrency(mode), WithStripes(8))
+		c.Assert(err, IsNil)
+
+		source, err := New(int64(len(testArray)))
+		c.Assert(err, IsNil)
+		for _, s := range testArray[:100] {
+			_, err := source.Add([]byte(s + "-merged"))
+			c.Assert(err, IsNil)
+		}
+
+		var wg sync.WaitGroup
+		for g := 0; g < 4; g++ {
+			wg.Add(1)
+			go func(g int) {
+				defer wg.Done()
+				for i := g; i < len(testArray); i += 4 {
+					filter.Add([]byte(testArray[i]))
+					filter.Check([]byte(testArray[i]))
+				}
+			}(g)
+		}
+
+		wg.Add(2)
+		go func() {
+			defer wg.Done()
+			for i := 0; i < 5; i++ {
+				c.Check(filter.Merge(source), IsNil)
+			}
+		}()
+		go func() {
+			defer wg.Done()
+			for i := 0; i < 5; i++ {
+				c.Check(filter.ToBytes(bytes.NewBuffer([]byte{})), IsNil)
+				filter.FillRatio()
+			}
+		}()
+		wg.Wait()
+
+		for _, s := range testArray {
+			c.Assert(filter.Check([]byte(s)), Equals, true)
+		}
+		for _, s := range testArray[:100] {
+			c.Assert(filter.Check([]byte(s+"-merged")), Equals, true)
+		}
+	}
+}
@@ -0,0 +1,90 @@
+package typed
+
+import (
+	"encoding/binary"
+	"net/netip"
+)
+
+// Integer is a set of types which are encoded by Int.
+type Integer interface {
+	~int | ~int8 | ~int16 | ~int32 | ~int64 |
+		~uint | ~uint8 | ~uint16 | ~uint32 | ~uint64 | ~uintptr
+}
+
+// String appends bytes of string.
+func String(dst []byte, v string) []byte {
+	return append(dst, v...)
+}
+
+// Bytes appends bytes as is.
+func Bytes(dst []byte, v []byte) []byte {
+	return append(dst, v...)
+}
+
+// Int appends value as 8 bytes in little-endian order.
+// It is the same key as BloomFilter.AddUint64(uint64(v)) uses.
+func Int[T Integer](dst []byte, v T) []byte {
+	return binary.LittleEndian.AppendUint64(dst, uint64(v))
+}
+
+// Addr appends 4 bytes for IPv4 address and 16 bytes with zone for IPv6 address.
+// Zero address is encoded as empty key.
+func Addr(dst []byte, v netip.Addr) []byte {
+	if v.Is4() {
+		b := v.As4()
+		return append(dst, b[:]...)
+	}
+	if v.Is6() {
+		b := v.As16()
+		return append(append(dst, b[:]...), v.Zone()...)
+	}
+	return dst
+}
+
+// UUID appends 16 bytes of UUID. Any type with [16]byte underlying type
+// (for example github.com/google/uuid.UUID) can be used.
+func UUID[T ~[16]byte](dst []byte, v T) []byte {
+	return append(dst, v[:]...)
+}
+
+// Strings appends every string with length prefix, so ["ab", "c"] and ["a", "bc"]
+// are different keys.
+func Strings(dst []byte, v []string) []byte {
+	for _, s := range v {
+		dst = binary.LittleEndian.AppendUint32(dst, uint32(len(s)))
+		dst = append(dst, s...)
+	}
+	return dst
+}
+
+// Tuple appends every element with length prefix.
+func Tuple(dst []byte, v [][]byte) []byte {
+	for _, b := range v {
+		dst = binary.LittleEndian.AppendUint32(dst, uint32(len(b)))
+		dst = append(dst, b...)
+	}
+	return dst
+}
+
+// Pair is a key of two values.
+type Pair[A, B any] struct {
+	First  A
+	Second B
+}
+
+// PairOf returns encoder for Pair. Every value is encoded with length prefix.
+func PairOf[A, B any](encodeA Encoder[A], encodeB Encoder[B]) Encoder[Pair[A, B]] {
+	return func(dst []byte, v Pair[A, B]) []byte {
+		dst = appendPrefixed(dst, v.First, encodeA)
+		return appendPrefixed(dst, v.Second, encodeB)
+	}
+}
+
+// appendPrefixed encodes value after 4 bytes place and writes length of encoded value there.
+func appendPrefixed[T any](dst []byte, v T, encode Encoder[T]) []byte {
+	start := len(dst)
+	dst = append(dst, 0, 0, 0, 0)
+	dst = encode(dst, v)
+	binary.LittleEndian.PutUint32(dst[start:], uint32(len(dst)-start-4))
+	return dst
+}
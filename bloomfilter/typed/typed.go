@@ -0,0 +1,67 @@
+package typed
+
+import (
+	"sync"
+)
+
+/*
+	Typed wrapper over bloom filters. Values are converted to keys by encoder:
+
+	filter := typed.New(bf, typed.String)
+	filter.Add("key")
+*/
+
+// Backend is a filter which keeps encoded keys.
+// Both *bloomfilter.BloomFilter and *scalable.Filter implement it.
+type Backend interface {
+	Add(key []byte, skipChecks ...bool) (bool, error)
+	Check(key []byte) bool
+}
+
+// Encoder appends encoded value to dst and returns the resulting slice.
+// Different values must be encoded to different bytes.
+type Encoder[T any] func(dst []byte, v T) []byte
+
+// Filter is a bloom filter for values of type T.
+type Filter[T any] struct {
+	backend Backend
+	encode  Encoder[T]
+	pool    sync.Pool
+}
+
+// New is constructor. It creates typed filter over backend.
+func New[T any](backend Backend, encode Encoder[T]) *Filter[T] {
+	return &Filter[T]{
+		backend: backend,
+		encode:  encode,
+		pool: sync.Pool{
+			New: func() interface{} {
+				b := make([]byte, 0, 64)
+				return &b
+			},
+		},
+	}
+}
+
+// Backend is a "getter". Returns filter which keeps keys.
+func (f *Filter[T]) Backend() Backend {
+	return f.backend
+}
+
+// Add new value. Returns true/false for value and error.
+func (f *Filter[T]) Add(v T) (bool, error) {
+	buf := f.pool.Get().(*[]byte)
+	defer f.pool.Put(buf)
+
+	*buf = f.encode((*buf)[:0], v)
+	return f.backend.Add(*buf)
+}
+
+// Check value. Returns true/false
+func (f *Filter[T]) Check(v T) bool {
+	buf := f.pool.Get().(*[]byte)
+	defer f.pool.Put(buf)
+
+	*buf = f.encode((*buf)[:0], v)
+	return f.backend.Check(*buf)
+}
@@ -0,0 +1,139 @@
+package typed
+
+import (
+	"net/netip"
+	"testing"
+
+	"github.com/iostrovok/go-bloom-filter/bloomfilter"
+	"github.com/iostrovok/go-bloom-filter/bloomfilter/fortesting"
+	"github.com/iostrovok/go-bloom-filter/bloomfilter/scalable"
+	. "gopkg.in/check.v1"
+)
+
+func Test(t *testing.T) { TestingT(t) }
+
+type typedTestSuite struct{}
+
+var _ = Suite(&typedTestSuite{})
+
+// Backend interface is implemented by both filters.
+var (
+	_ Backend = &bloomfilter.BloomFilter{}
+	_ Backend = &scalable.Filter{}
+)
+
+func (s *typedTestSuite) backends(c *C) []Backend {
+	bf, err := bloomfilter.New(10000, 0.0001)
+	c.Assert(err, IsNil)
+
+	sbf, err := scalable.New(1000, 0.0001, scalable.SmallSetGrowth)
+	c.Assert(err, IsNil)
+
+	return []Backend{bf, sbf}
+}
+
+func (s *typedTestSuite) TestString(c *C) {
+
+	for _, backend := range s.backends(c) {
+		filter := New(backend, String)
+		c.Assert(filter.Backend(), Equals, backend)
+
+		for _, v := range fortesting.ArrayForTesting() {
+			res, err := filter.Add(v)
+			c.Assert(err, IsNil)
+			c.Assert(res, Equals, false)
+		}
+
+		for _, v := range fortesting.ArrayForTesting() {
+			c.Assert(filter.Check(v), Equals, true)
+			c.Assert(backend.Check([]byte(v)), Equals, true)
+		}
+	}
+}
+
+func (s *typedTestSuite) TestInt(c *C) {
+
+	bf, err := bloomfilter.New(10000, 0.0001)
+	c.Assert(err, IsNil)
+
+	filter := New(bf, Int[int32])
+	for i := int32(-500); i < 500; i++ {
+		_, err := filter.Add(i)
+		c.Assert(err, IsNil)
+	}
+
+	for i := int32(-500); i < 500; i++ {
+		c.Assert(filter.Check(i), Equals, true)
+		c.Assert(bf.CheckUint64(uint64(i)), Equals, true)
+	}
+
+	countBad := 0
+	for i := int32(1000); i < 2000; i++ {
+		if filter.Check(i) {
+			countBad++
+		}
+	}
+	c.Assert(countBad < 3, Equals, true)
+}
+
+func (s *typedTestSuite) TestAddr(c *C) {
+
+	c.Assert(Addr(nil, netip.MustParseAddr("10.0.0.1")), DeepEquals, []byte{10, 0, 0, 1})
+	c.Assert(len(Addr(nil, netip.MustParseAddr("::ffff:10.0.0.1"))), Equals, 16)
+	c.Assert(Addr(nil, netip.MustParseAddr("fe80::1%eth0"))[16:], DeepEquals, []byte("eth0"))
+
+	for _, backend := range s.backends(c) {
+		filter := New(backend, Addr)
+
+		_, err := filter.Add(netip.MustParseAddr("192.168.1.1"))
+		c.Assert(err, IsNil)
+		_, err = filter.Add(netip.MustParseAddr("2001:db8::1"))
+		c.Assert(err, IsNil)
+
+		c.Assert(filter.Check(netip.MustParseAddr("192.168.1.1")), Equals, true)
+		c.Assert(filter.Check(netip.MustParseAddr("2001:db8::1")), Equals, true)
+		c.Assert(filter.Check(netip.MustParseAddr("::ffff:192.168.1.1")), Equals, false)
+		c.Assert(filter.Check(netip.MustParseAddr("2001:db8::2")), Equals, false)
+	}
+}
+
+type testUUID [16]byte
+
+func (s *typedTestSuite) TestUUID(c *C) {
+
+	bf, err := bloomfilter.New(1000, 0.0001)
+	c.Assert(err, IsNil)
+
+	filter := New(bf, UUID[testUUID])
+
+	id := testUUID{1, 2, 3, 4, 5, 6, 7, 8, 9, 10, 11, 12, 13, 14, 15, 16}
+	_, err = filter.Add(id)
+	c.Assert(err, IsNil)
+
+	c.Assert(filter.Check(id), Equals, true)
+	c.Assert(filter.Check(testUUID{}), Equals, false)
+}
+
+func (s *typedTestSuite) TestTuples(c *C) {
+
+	c.Assert(Strings(nil, []string{"ab", "c"}), Not(DeepEquals), Strings(nil, []string{"a", "bc"}))
+	c.Assert(Tuple(nil, [][]byte{[]byte("ab"), []byte("c")}), DeepEquals, Strings(nil, []string{"ab", "c"}))
+
+	encode := PairOf(String, Int[uint16])
+	c.Assert(encode(nil, Pair[string, uint16]{"a", 1}), DeepEquals,
+		[]byte{1, 0, 0, 0, 'a', 8, 0, 0, 0, 1, 0, 0, 0, 0, 0, 0, 0})
+
+	for _, backend := range s.backends(c) {
+		strings := New(backend, Strings)
+		_, err := strings.Add([]string{"ab", "c"})
+		c.Assert(err, IsNil)
+		c.Assert(strings.Check([]string{"ab", "c"}), Equals, true)
+		c.Assert(strings.Check([]string{"a", "bc"}), Equals, false)
+
+		pairs := New(backend, PairOf(String, String))
+		_, err = pairs.Add(Pair[string, string]{"user", "42"})
+		c.Assert(err, IsNil)
+		c.Assert(pairs.Check(Pair[string, string]{"user", "42"}), Equals, true)
+		c.Assert(pairs.Check(Pair[string, string]{"user4", "2"}), Equals, false)
+	}
+}
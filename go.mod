@@ -1,8 +1,10 @@
 module github.com/iostrovok/go-bloom-filter
 
+go 1.21
+
+require gopkg.in/check.v1 v1.0.0-20180628173108-788fd7840127
+
 require (
 	github.com/kr/pretty v0.1.0 // indirect
-	golang.org/x/lint v0.0.0-20190301231843-5614ed5bae6f // indirect
-	golang.org/x/tools v0.0.0-20190312151545-0bb0c0a6e846 // indirect
-	gopkg.in/check.v1 v1.0.0-20180628173108-788fd7840127
+	github.com/kr/text v0.1.0 // indirect
 )
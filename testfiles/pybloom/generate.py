@@ -0,0 +1,328 @@
+#!/usr/bin/env python3
+"""
+Generates golden files of python-bloomfilter for compatibility tests of go-bloom-filter.
+
+    python3 generate.py
+
+If module pybloom (https://github.com/jaybaird/python-bloomfilter) or its fork pybloom_live
+(https://github.com/joseph-fox/python-bloomfilter) is installed, its BloomFilter, ScalableBloomFilter
+and make_hashfuncs write the files, and the name and version of the package are saved as "source"
+and "version" of cases.json. Otherwise the classes below are used. They repeat pybloom 2.0 code
+line by line, only bitarray is replaced by bytearray with the same little endian bit order.
+The checked-in files were written by these classes: the build machine had no access to PyPI,
+so pybloom and pybloom_live could not be installed. Run the script again where one of them
+is installed to check the files against the real library. The classes write ../test_simple.bin
+and ../test_scal.bin of real pybloom byte by byte for the keys of bloomfilter/fortesting.
+
+Files:
+    plain_<name>.bin.gz     BloomFilter.tofile, gzip compressed
+    scalable_<name>.bin.gz  ScalableBloomFilter.tofile, gzip compressed
+    indexes.json            positions of keys for configurations which are too big for files
+    cases.json              parameters and keys of all files
+"""
+
+import gzip
+import hashlib
+import io
+import json
+import math
+import os
+from struct import calcsize, pack, unpack
+
+HERE = os.path.dirname(os.path.abspath(__file__))
+
+
+def make_hashfuncs(num_slices, num_bits):
+    if num_bits >= (1 << 31):
+        fmt_code, chunk_size = 'Q', 8
+    elif num_bits >= (1 << 15):
+        fmt_code, chunk_size = 'I', 4
+    else:
+        fmt_code, chunk_size = 'H', 2
+    total_hash_bits = 8 * num_slices * chunk_size
+    if total_hash_bits > 384:
+        hashfn = hashlib.sha512
+    elif total_hash_bits > 256:
+        hashfn = hashlib.sha384
+    elif total_hash_bits > 160:
+        hashfn = hashlib.sha256
+    elif total_hash_bits > 128:
+        hashfn = hashlib.sha1
+    else:
+        hashfn = hashlib.md5
+    fmt = fmt_code * (hashfn().digest_size // chunk_size)
+    num_salts, extra = divmod(num_slices, len(fmt))
+    if extra:
+        num_salts += 1
+    salts = tuple(hashfn(hashfn(pack('I', i)).digest()) for i in range(num_salts))
+
+    def _make_hashfuncs(key):
+        if isinstance(key, str):
+            key = key.encode('utf-8')
+        else:
+            key = str(key).encode('utf-8')
+        i = 0
+        for salt in salts:
+            h = salt.copy()
+            h.update(key)
+            for uint in unpack(fmt, h.digest()):
+                yield uint % num_bits
+                i += 1
+                if i >= num_slices:
+                    return
+
+    return _make_hashfuncs, hashfn
+
+
+class _Bits(object):
+    """Replaces bitarray(num_bits, endian='little')."""
+
+    def __init__(self, num_bits):
+        self.data = bytearray((num_bits + 7) // 8)
+
+    def __getitem__(self, i):
+        return bool(self.data[i >> 3] & (1 << (i & 7)))
+
+    def __setitem__(self, i, value):
+        self.data[i >> 3] |= 1 << (i & 7)
+
+    def tofile(self, f):
+        f.write(bytes(self.data))
+
+
+class MirrorBloomFilter(object):
+    FILE_FMT = b'<dQQQQ'
+
+    def __init__(self, capacity, error_rate=0.001):
+        if not (0 < error_rate < 1):
+            raise ValueError("Error_Rate must be between 0 and 1.")
+        if not capacity > 0:
+            raise ValueError("Capacity must be > 0")
+        num_slices = int(math.ceil(math.log(1.0 / error_rate, 2)))
+        bits_per_slice = int(math.ceil(
+            (capacity * abs(math.log(error_rate))) /
+            (num_slices * (math.log(2) ** 2))))
+        self._setup(error_rate, num_slices, bits_per_slice, capacity, 0)
+        self.bitarray = _Bits(self.num_bits)
+
+    def _setup(self, error_rate, num_slices, bits_per_slice, capacity, count):
+        self.error_rate = error_rate
+        self.num_slices = num_slices
+        self.bits_per_slice = bits_per_slice
+        self.capacity = capacity
+        self.num_bits = num_slices * bits_per_slice
+        self.count = count
+        self.make_hashes, self.hashfn = make_hashfuncs(self.num_slices, self.bits_per_slice)
+
+    def __contains__(self, key):
+        bits_per_slice = self.bits_per_slice
+        bitarray = self.bitarray
+        hashes = self.make_hashes(key)
+        offset = 0
+        for k in hashes:
+            if not bitarray[offset + k]:
+                return False
+            offset += bits_per_slice
+        return True
+
+    def add(self, key, skip_check=False):
+        bitarray = self.bitarray
+        bits_per_slice = self.bits_per_slice
+        hashes = self.make_hashes(key)
+        found_all_bits = True
+        if self.count > self.capacity:
+            raise IndexError("BloomFilter is at capacity")
+        offset = 0
+        for k in hashes:
+            if not skip_check and found_all_bits and not bitarray[offset + k]:
+                found_all_bits = False
+            self.bitarray[offset + k] = True
+            offset += bits_per_slice
+
+        if skip_check:
+            self.count += 1
+            return False
+        elif not found_all_bits:
+            self.count += 1
+            return False
+        else:
+            return True
+
+    def tofile(self, f):
+        f.write(pack(self.FILE_FMT, self.error_rate, self.num_slices,
+                     self.bits_per_slice, self.capacity, self.count))
+        self.bitarray.tofile(f)
+
+
+class MirrorScalableBloomFilter(object):
+    SMALL_SET_GROWTH = 2
+    LARGE_SET_GROWTH = 4
+    FILE_FMT = '<idQd'
+
+    def __init__(self, initial_capacity=100, error_rate=0.001, mode=SMALL_SET_GROWTH):
+        if not error_rate or error_rate < 0:
+            raise ValueError("Error_Rate must be a decimal less than 0.")
+        self._setup(mode, 0.9, initial_capacity, error_rate)
+        self.filters = []
+
+    def _setup(self, mode, ratio, initial_capacity, error_rate):
+        self.scale = mode
+        self.ratio = ratio
+        self.initial_capacity = initial_capacity
+        self.error_rate = error_rate
+
+    def __contains__(self, key):
+        for f in reversed(self.filters):
+            if key in f:
+                return True
+        return False
+
+    def add(self, key):
+        if key in self:
+            return True
+        if not self.filters:
+            filter = MirrorBloomFilter(
+                capacity=self.initial_capacity,
+                error_rate=self.error_rate * (1.0 - self.ratio))
+            self.filters.append(filter)
+        else:
+            filter = self.filters[-1]
+            if filter.count >= filter.capacity:
+                filter = MirrorBloomFilter(
+                    capacity=filter.capacity * self.scale,
+                    error_rate=filter.error_rate * self.ratio)
+                self.filters.append(filter)
+        filter.add(key, skip_check=True)
+        return False
+
+    def tofile(self, f):
+        f.write(pack(self.FILE_FMT, self.scale, self.ratio,
+                     self.initial_capacity, self.error_rate))
+        f.write(pack(b'<l', len(self.filters)))
+        if len(self.filters) > 0:
+            headerpos = f.tell()
+            headerfmt = b'<' + b'Q' * (len(self.filters))
+            f.write(b'.' * calcsize(headerfmt))
+            filter_sizes = []
+            for filter in self.filters:
+                begin = f.tell()
+                filter.tofile(f)
+                filter_sizes.append(f.tell() - begin)
+            f.seek(headerpos)
+            f.write(pack(headerfmt, *filter_sizes))
+
+
+def package_version(name):
+    try:
+        from importlib.metadata import version
+        return version(name)
+    except Exception:
+        module = __import__(name.replace('-', '_'))
+        return getattr(module, '__version__', 'unknown')
+
+
+try:
+    from pybloom import BloomFilter, ScalableBloomFilter
+    from pybloom.pybloom import make_hashfuncs
+    SOURCE, VERSION = 'pybloom', package_version('pybloom')
+except ImportError:
+    try:
+        from pybloom_live import BloomFilter, ScalableBloomFilter
+        from pybloom_live.pybloom import make_hashfuncs
+        SOURCE, VERSION = 'pybloom_live', package_version('pybloom-live')
+    except ImportError:
+        BloomFilter, ScalableBloomFilter = MirrorBloomFilter, MirrorScalableBloomFilter
+        SOURCE, VERSION = 'mirror of pybloom 2.0', '2.0 (mirror, real pybloom not installed)'
+
+
+def keys(prefix, n):
+    return ['%s-%d' % (prefix, i) for i in range(n)]
+
+
+# name, capacity, error rate, number of keys: every chunk size and hash function of make_hashfuncs
+PLAIN = [
+    ('c2_md5', 1000, 0.01, 700),
+    ('c2_sha1', 1000, 0.001, 700),
+    ('c2_sha256', 1000, 0.0001, 700),
+    ('c2_sha384', 1000, 0.000001, 700),
+    ('c2_sha512', 1000, 0.00000001, 700),
+    ('c2_sha512_salts', 1000, 0.0000000001, 700),
+    ('c4_md5', 30000, 0.1, 500),
+    ('c4_sha1', 30000, 0.05, 500),
+    ('c4_sha256', 25000, 0.01, 500),
+    ('c4_sha384', 25000, 0.001, 500),
+    ('c4_sha512', 25000, 0.0001, 500),
+    ('c4_sha512_salts', 25000, 0.000001, 500),
+    ('pow2', 1000, 2.0 ** -29, 300),
+]
+
+# name, initial capacity, error rate, mode, number of keys
+SCALABLE = [
+    ('small', 100, 0.001, 2, 1000),
+    ('large', 100, 0.01, 4, 1000),
+    ('salts', 100, 0.000000001, 2, 700),
+    ('c4', 25000, 0.01, 2, 26000),
+]
+
+# num slices, bits per slice: chunks of 8 bytes need more than 1 << 31 bits per slice
+INDEXES = [
+    (7, 1000), (34, 1000), (20, 40000),
+    (1, 1 << 31), (2, 3000000000), (3, 1 << 32), (5, 5000000000),
+    (7, (1 << 33) + 7), (9, 1 << 31), (20, 12345678901),
+]
+
+
+def write_gz(name, data):
+    with open(os.path.join(HERE, name), 'wb') as f:
+        with gzip.GzipFile(filename='', mode='wb', fileobj=f, mtime=0) as gz:
+            gz.write(data)
+
+
+def main():
+    cases = {'source': SOURCE, 'version': VERSION, 'plain': [], 'scalable': []}
+
+    for name, capacity, error_rate, n in PLAIN:
+        f = BloomFilter(capacity=capacity, error_rate=error_rate)
+        for key in keys(name, n):
+            f.add(key)
+        out = io.BytesIO()
+        f.tofile(out)
+        write_gz('plain_%s.bin.gz' % name, out.getvalue())
+        cases['plain'].append({
+            'name': name, 'capacity': capacity, 'error_rate': error_rate, 'keys': n,
+            'num_slices': f.num_slices, 'bits_per_slice': f.bits_per_slice,
+            'hash': f.hashfn().name, 'count': f.count,
+        })
+
+    for name, capacity, error_rate, mode, n in SCALABLE:
+        f = ScalableBloomFilter(initial_capacity=capacity, error_rate=error_rate, mode=mode)
+        for key in keys(name, n):
+            f.add(key)
+        out = io.BytesIO()
+        f.tofile(out)
+        write_gz('scalable_%s.bin.gz' % name, out.getvalue())
+        cases['scalable'].append({
+            'name': name, 'initial_capacity': capacity, 'error_rate': error_rate, 'mode': mode, 'keys': n,
+            'filters': len(f.filters), 'hashes': [x.hashfn().name for x in f.filters],
+        })
+
+    indexes = []
+    for num_slices, bits_per_slice in INDEXES:
+        make_hashes, hashfn = make_hashfuncs(num_slices, bits_per_slice)
+        names = keys('index', 20)
+        indexes.append({
+            'num_slices': num_slices, 'bits_per_slice': bits_per_slice, 'hash': hashfn().name,
+            'keys': names, 'indexes': [list(make_hashes(key)) for key in names],
+        })
+
+    with open(os.path.join(HERE, 'cases.json'), 'w') as f:
+        json.dump(cases, f, indent=1, sort_keys=True)
+        f.write('\n')
+
+    with open(os.path.join(HERE, 'indexes.json'), 'w') as f:
+        json.dump(indexes, f, indent=1, sort_keys=True)
+        f.write('\n')
+
+
+if __name__ == '__main__':
+    main()